@@ -0,0 +1,612 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/zesbe/zesbe-go/internal/config"
+	"github.com/zesbe/zesbe-go/internal/credentials"
+	"github.com/zesbe/zesbe-go/internal/doctor"
+	"github.com/zesbe/zesbe-go/internal/session"
+
+	"golang.org/x/term"
+)
+
+// sessionsUsage describes the sessions subcommands
+const sessionsUsage = `Usage:
+  zesbe-go sessions list [--limit N]
+  zesbe-go sessions show <id>
+  zesbe-go sessions export <id> [--output file]
+  zesbe-go sessions delete <id>
+
+<id> may be the first characters of a session ID, as shown by list.`
+
+// runSessions handles `zesbe-go sessions`, which manages saved chats
+func runSessions(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, sessionsUsage)
+		return 2
+	}
+	command, args := args[0], args[1:]
+
+	fs := flag.NewFlagSet("sessions "+command, flag.ContinueOnError)
+	fs.Usage = func() { fmt.Fprintln(os.Stderr, sessionsUsage) }
+	limit := 20
+	output := ""
+	switch command {
+	case "list":
+		fs.IntVar(&limit, "limit", limit, "number of sessions to list; 0 lists all")
+	case "export":
+		fs.StringVar(&output, "output", "", "write the export to this file instead of stdout")
+	case "show", "delete":
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown sessions command: %s\n\n%s\n", command, sessionsUsage)
+		return 2
+	}
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 2
+	}
+	want := 1
+	if command == "list" {
+		want = 0
+	}
+	if len(positional) != want {
+		fs.Usage()
+		return 2
+	}
+
+	store, err := session.NewStore("")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	defer store.Close()
+
+	if command == "list" {
+		sessions, err := store.ListSessions(limit)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		if len(sessions) == 0 {
+			fmt.Println("No saved sessions")
+			return 0
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tUPDATED\tMESSAGES\tMODEL\tTITLE")
+		for _, s := range sessions {
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n",
+				s.ID[:8], s.UpdatedAt.Format("2006-01-02 15:04"), s.MessageCount, s.Model, s.Title)
+		}
+		w.Flush()
+		return 0
+	}
+
+	s, err := store.FindSession(positional[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	switch command {
+	case "show":
+		messages, err := store.GetMessages(s.ID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Printf("%s\n%s · %s/%s · %d messages · %s\n",
+			s.Title, s.ID, s.Provider, s.Model, len(messages), s.WorkingDir)
+		for _, m := range messages {
+			fmt.Printf("\n── %s · %s ──\n%s\n", m.Role, m.Timestamp.Format(time.DateTime), strings.TrimSpace(m.Content))
+		}
+
+	case "export":
+		data, err := store.ExportSession(s.ID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		data = append(data, '\n')
+		if output == "" {
+			os.Stdout.Write(data)
+		} else if err := os.WriteFile(output, data, 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		} else {
+			fmt.Fprintf(os.Stderr, "Exported session %s to %s\n", s.ID[:8], output)
+		}
+
+	case "delete":
+		if err := store.DeleteSession(s.ID); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Deleted session %s (%s)\n", s.ID[:8], s.Title)
+	}
+	return 0
+}
+
+// configUsage describes the config subcommands
+const configUsage = `Usage:
+  zesbe-go config get [name]
+  zesbe-go config set [--project] <name> <value>
+  zesbe-go config validate [file...]
+  zesbe-go config schema
+  zesbe-go config trust|untrust [dir]
+
+Without a name, get lists every effective setting and where it came from.
+Names may reach into object settings, e.g. tool_policies.run_command.timeout.
+set writes the user config, or the project's .zesbe/config.json with
+--project. The value is parsed as JSON when it is valid JSON and taken as a
+string otherwise; null removes the setting. validate checks the user and
+project configs and the ZESBE_* variables, or the given files, against the
+schema that schema prints.
+
+A project config only starts servers, runs hooks and custom tools, or
+changes tool_access and the git guards once you trust the project. trust
+and untrust record that decision for the project holding the current
+directory, or dir.`
+
+// runConfig handles `zesbe-go config`, which reads and changes settings
+func runConfig(cfg *config.Config, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, configUsage)
+		return 2
+	}
+	command, args := args[0], args[1:]
+
+	switch command {
+	case "get":
+		if len(args) > 1 {
+			fmt.Fprintln(os.Stderr, configUsage)
+			return 2
+		}
+		if len(args) == 0 {
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "SETTING\tVALUE\tSOURCE")
+			for _, s := range cfg.Settings() {
+				fmt.Fprintf(w, "%s\t%s\t%s\n", s.Name, s.Value, s.Origin)
+			}
+			w.Flush()
+			for _, warning := range cfg.Problems() {
+				fmt.Fprintf(os.Stderr, "Ignored: %s\n", warning)
+			}
+			return 0
+		}
+		v, origin, ok := cfg.Get(args[0])
+		if !ok {
+			fmt.Fprintf(os.Stderr, "Error: %s is not set\n", args[0])
+			return 1
+		}
+		if s, isString := v.(string); isString {
+			fmt.Println(s)
+		} else {
+			data, _ := json.MarshalIndent(v, "", "  ")
+			fmt.Println(string(data))
+		}
+		fmt.Fprintf(os.Stderr, "from %s\n", origin)
+		return 0
+
+	case "set":
+		fs := flag.NewFlagSet("config set", flag.ContinueOnError)
+		fs.Usage = func() { fmt.Fprintln(os.Stderr, configUsage) }
+		project := fs.Bool("project", false, "write the project config instead of the user config")
+		positional, err := parseInterspersed(fs, args)
+		if err != nil {
+			return 2
+		}
+		if len(positional) != 2 {
+			fs.Usage()
+			return 2
+		}
+		key, value := positional[0], positional[1]
+
+		path := config.GetConfigPath()
+		if *project {
+			if err := cfg.CheckProjectSetting(key); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return 1
+			}
+			path = config.GetProjectConfigPath()
+		}
+		if err := config.SetFileValue(path, key, value); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Set %s in %s\n", key, path)
+		return 0
+
+	case "validate":
+		if len(args) > 0 {
+			return validateFiles(args)
+		}
+		return validateConfig(cfg)
+
+	case "schema":
+		if len(args) != 0 {
+			fmt.Fprintln(os.Stderr, configUsage)
+			return 2
+		}
+		os.Stdout.Write(config.Schema())
+		return 0
+
+	case "trust", "untrust":
+		if len(args) > 1 {
+			fmt.Fprintln(os.Stderr, configUsage)
+			return 2
+		}
+		dir := config.ProjectRoot(config.GetProjectConfigPath())
+		if len(args) == 1 {
+			dir = args[0]
+		}
+		if err := config.SetProjectTrust(dir, command == "trust"); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		if command == "trust" {
+			fmt.Printf("Trusted %s\n", dir)
+		} else {
+			fmt.Printf("No longer trusting %s\n", dir)
+		}
+		return 0
+
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown config command: %s\n\n%s\n", command, configUsage)
+		return 2
+	}
+}
+
+// validateConfig reports what was wrong with the config files and
+// variables Load read, and fails when anything was dropped
+func validateConfig(cfg *config.Config) int {
+	failed := false
+	checked := 0
+	for _, f := range cfg.Files {
+		if !f.Found {
+			continue
+		}
+		checked++
+		if f.Err != nil {
+			fmt.Println(f.Err)
+			failed = true
+			continue
+		}
+		ok := true
+		for _, issue := range cfg.Issues {
+			if issue.Origin.Location == f.Path {
+				fmt.Println(issue)
+				ok = false
+			}
+		}
+		if ok {
+			fmt.Printf("%s: ok\n", f.Path)
+		}
+		failed = failed || !ok
+	}
+	for _, issue := range cfg.Issues {
+		if issue.Origin.Source == config.SourceEnv || issue.Origin.Source == config.SourceFlag {
+			fmt.Println(issue)
+			failed = true
+		}
+	}
+	for _, w := range cfg.Warnings {
+		fmt.Printf("warning: %s\n", w)
+	}
+	if checked == 0 {
+		fmt.Println("No config files found; the defaults apply")
+	}
+	if failed {
+		return 1
+	}
+	return 0
+}
+
+// validateFiles checks config files against the schema
+func validateFiles(paths []string) int {
+	status := 0
+	for _, path := range paths {
+		issues, err := config.ValidateFile(path)
+		if err != nil {
+			fmt.Println(err)
+			status = 1
+			continue
+		}
+		for _, issue := range issues {
+			fmt.Println(issue)
+			status = 1
+		}
+		if len(issues) == 0 {
+			fmt.Printf("%s: ok\n", path)
+		}
+	}
+	return status
+}
+
+// authUsage describes the auth subcommands
+const authUsage = `Usage:
+  zesbe-go auth login [provider]
+  zesbe-go auth logout [provider]
+  zesbe-go auth list
+
+login reads the API key from standard input, without echoing it when that
+is a terminal, and stores it in the system keyring. Without a keyring, or
+with credential_store set to "file", keys go in ~/.zesbe-go/credentials.enc,
+encrypted with a passphrase taken from ZESBE_PASSPHRASE or asked for.
+The provider defaults to the current one.`
+
+// runAuth handles `zesbe-go auth`, which manages the stored API keys
+func runAuth(cfg *config.Config, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, authUsage)
+		return 2
+	}
+	command, args := args[0], args[1:]
+
+	store := config.Credentials()
+	if store == nil {
+		_, err := credentials.Open(cfg.CredentialStore, config.GetConfigDir())
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	switch command {
+	case "list":
+		if len(args) != 0 {
+			fmt.Fprintln(os.Stderr, authUsage)
+			return 2
+		}
+		// A wrong passphrase would fail every lookup
+		if file, ok := store.(*credentials.FileStore); ok {
+			if err := file.Unlock(); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return 1
+			}
+		}
+		names := cfg.ListProviders()
+		sort.Strings(names)
+		fmt.Printf("Credential store: %s\n\n", store.Name())
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "PROVIDER\tSTORED\tKEY IN USE")
+		for _, name := range names {
+			stored := "no"
+			if _, err := store.Get(name); err == nil {
+				stored = "yes"
+			} else if !errors.Is(err, credentials.ErrNotFound) {
+				stored = "unknown: " + err.Error()
+			}
+			inUse := "none"
+			if key, origin := cfg.ProviderKey(name); key != "" {
+				inUse = origin.String()
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", name, stored, inUse)
+		}
+		w.Flush()
+		return 0
+
+	case "login", "logout":
+		if len(args) > 1 {
+			fmt.Fprintln(os.Stderr, authUsage)
+			return 2
+		}
+		provider := cfg.Provider
+		if len(args) == 1 {
+			provider = args[0]
+		}
+		if _, ok := cfg.Providers[provider]; !ok {
+			fmt.Fprintf(os.Stderr, "Error: unknown provider %q\n", provider)
+			return 1
+		}
+
+		if command == "login" {
+			key, err := readAPIKey(provider)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return 1
+			}
+			if err := store.Set(provider, key); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return 1
+			}
+			fmt.Printf("Stored the %s API key in the %s\n", provider, store.Name())
+		} else {
+			err := store.Delete(provider)
+			if errors.Is(err, credentials.ErrNotFound) {
+				fmt.Fprintf(os.Stderr, "Error: the %s holds no %s API key\n", store.Name(), provider)
+				return 1
+			} else if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return 1
+			}
+			fmt.Printf("Removed the %s API key from the %s\n", provider, store.Name())
+		}
+
+		// Point out keys that win over the store or outlive it
+		if envVar := config.GetAPIKeyEnvVar(provider); os.Getenv(envVar) != "" {
+			fmt.Fprintf(os.Stderr, "Note: %s is set and takes precedence over the stored key\n", envVar)
+		}
+		if keyPath := config.GetAPIKeyPath(provider); fileExists(keyPath) {
+			fmt.Fprintf(os.Stderr, "Note: %s still holds a plain-text key; delete it once the stored key works\n", keyPath)
+		}
+		return 0
+
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown auth command: %s\n\n%s\n", command, authUsage)
+		return 2
+	}
+}
+
+// readAPIKey reads an API key from standard input, asking for it without
+// echo on a terminal
+func readAPIKey(provider string) (string, error) {
+	var data []byte
+	var err error
+	if fd := int(os.Stdin.Fd()); term.IsTerminal(fd) {
+		fmt.Fprintf(os.Stderr, "API key for %s: ", provider)
+		data, err = term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+	} else {
+		data, err = io.ReadAll(io.LimitReader(os.Stdin, 64*1024))
+	}
+	if err != nil {
+		return "", err
+	}
+	key := strings.TrimSpace(string(data))
+	if key == "" {
+		return "", errors.New("no API key given")
+	}
+	if strings.ContainsAny(key, " \t\r\n") {
+		return "", errors.New("the API key contains whitespace")
+	}
+	return key, nil
+}
+
+// promptPassphrase asks for the credentials file's passphrase on the
+// terminal, twice when the file is being created
+func promptPassphrase(confirm bool) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", credentials.ErrLocked
+	}
+	ask := func(prompt string) (string, error) {
+		fmt.Fprint(os.Stderr, prompt)
+		data, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		return string(data), err
+	}
+	if !confirm {
+		return ask("Passphrase for the credentials file: ")
+	}
+	pass, err := ask("New passphrase for the credentials file: ")
+	if err != nil {
+		return "", err
+	}
+	again, err := ask("Repeat the passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if pass != again {
+		return "", errors.New("the passphrases don't match")
+	}
+	return pass, nil
+}
+
+// fileExists reports whether path exists
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// runProviders handles `zesbe-go providers`, which lists the providers and
+// whether each has an API key
+func runProviders(cfg *config.Config) int {
+	names := cfg.ListProviders()
+	sort.Strings(names)
+
+	// Without ZESBE_PASSPHRASE the file's keys can't be seen, and this
+	// doesn't ask for the passphrase
+	locked := false
+	if store, ok := config.Credentials().(*credentials.FileStore); ok && store.Exists() {
+		locked = errors.Is(store.Unlock(), credentials.ErrLocked)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "\tPROVIDER\tMODEL\tBASE URL\tAPI KEY")
+	for _, name := range names {
+		p := cfg.Providers[name]
+		current := ""
+		if name == cfg.Provider {
+			current = "*"
+		}
+		model := p.Model
+		if name == cfg.Provider {
+			model = cfg.Model
+		}
+		key := "missing"
+		if k, origin := cfg.ProviderKey(name); k != "" {
+			key = "✓ " + origin.String()
+		} else if locked {
+			key = "unknown (credentials file locked)"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", current, name, model, p.BaseURL, key)
+	}
+	w.Flush()
+	return 0
+}
+
+// runDoctor handles `zesbe-go doctor`, which checks the installation and
+// prints or saves a report
+func runDoctor(cfg *config.Config, args []string) int {
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: zesbe-go doctor [--format text|json] [--output file] [--offline] [--timeout 5s]")
+		fs.PrintDefaults()
+	}
+	format := fs.String("format", "text", "report format: text or json")
+	output := fs.String("output", "", "write the report to this file instead of stdout")
+	offline := fs.Bool("offline", false, "skip the provider reachability checks")
+	timeout := fs.Duration("timeout", doctor.DefaultTimeout, "limit for each network request and the database lock")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 0 || (*format != "text" && *format != "json") {
+		fs.Usage()
+		return 2
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	report := doctor.Run(ctx, cfg, doctor.Options{Version: Version, Offline: *offline, Timeout: *timeout})
+
+	data := []byte(report.Text())
+	if *format == "json" {
+		var err error
+		if data, err = report.JSON(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		data = append(data, '\n')
+	}
+	if *output == "" {
+		os.Stdout.Write(data)
+	} else {
+		if err := os.WriteFile(*output, data, 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(os.Stderr, "Wrote the report to %s\n", *output)
+	}
+
+	if report.Failed() {
+		return 1
+	}
+	return 0
+}
+
+// parseInterspersed parses flags that may come before, between or after
+// the positional arguments, which it returns
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
@@ -8,9 +8,12 @@ require (
 	github.com/charmbracelet/glamour v0.8.0
 	github.com/charmbracelet/lipgloss v1.1.0
 	github.com/google/uuid v1.6.0
+	github.com/liushuangls/go-anthropic/v2 v2.17.0
 	github.com/rs/zerolog v1.33.0
 	github.com/sethvargo/go-retry v0.3.0
 	go.etcd.io/bbolt v1.3.11
+	golang.org/x/crypto v0.39.0
+	golang.org/x/term v0.32.0
 	golang.org/x/time v0.8.0
 )
 
@@ -27,7 +30,6 @@ require (
 	github.com/dlclark/regexp2 v1.11.0 // indirect
 	github.com/erikgeiser/coninput v0.0.0-20211004153227-1c3628e74d0f // indirect
 	github.com/gorilla/css v1.0.1 // indirect
-	github.com/liushuangls/go-anthropic/v2 v2.17.0 // indirect
 	github.com/lucasb-eyer/go-colorful v1.2.0 // indirect
 	github.com/mattn/go-colorable v0.1.13 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
@@ -48,6 +50,5 @@ require (
 	github.com/yuin/goldmark-emoji v1.0.3 // indirect
 	golang.org/x/net v0.41.0 // indirect
 	golang.org/x/sys v0.36.0 // indirect
-	golang.org/x/term v0.32.0 // indirect
 	golang.org/x/text v0.27.0 // indirect
 )
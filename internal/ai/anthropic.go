@@ -4,8 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/zesbe/zesbe-go/internal/hooks"
 	"github.com/zesbe/zesbe-go/internal/logger"
 	"github.com/zesbe/zesbe-go/internal/tools"
 
@@ -20,6 +22,7 @@ type AnthropicClient struct {
 	temperature float32
 	history     []anthropic.Message
 	systemMsg   string
+	hooks       *hooks.Runner
 }
 
 // NewAnthropicClient creates a new Anthropic client using the SDK
@@ -32,69 +35,27 @@ func NewAnthropicClient(apiKey, model string, maxTokens int, temperature float64
 		maxTokens:   maxTokens,
 		temperature: float32(temperature),
 		history:     []anthropic.Message{},
-		systemMsg:   getAnthropicSystemPrompt(),
 	}
 }
 
-// getAnthropicSystemPrompt returns the system prompt for Anthropic
-func getAnthropicSystemPrompt() string {
-	return `You are Zesbe, an enterprise-grade AI coding assistant with direct access to the filesystem and shell.
-
-You are powerful, precise, and proactive. You can read, write, and edit files, run commands, and help with complex coding tasks.
-
-## Core Principles
-1. **Be Direct** - Execute actions immediately using tools, don't just describe them
-2. **Be Thorough** - Read files before modifying, understand context before acting
-3. **Be Safe** - Validate operations, handle errors gracefully
-4. **Be Efficient** - Use the right tools for the job, minimize unnecessary operations
-
-## Guidelines
-- Use markdown for code blocks with language specifier
-- Always respond in the same language the user uses
-- Use tools proactively when needed
-- After using a tool, explain what you found or did clearly
-- For complex tasks, break them down into steps
-
-Use the available tools directly when needed.`
-}
-
 // getAnthropicTools returns tool definitions in Anthropic SDK format
 func getAnthropicTools() []anthropic.ToolDefinition {
 	toolDefs := tools.GetToolDefinitions()
 	result := make([]anthropic.ToolDefinition, 0, len(toolDefs))
 
 	for _, td := range toolDefs {
-		// Build properties as map for JSON schema
-		properties := make(map[string]map[string]string)
-		required := []string{}
-
-		for _, param := range td.Parameters {
-			properties[param] = map[string]string{
-				"type":        "string",
-				"description": fmt.Sprintf("The %s parameter", param),
-			}
-			required = append(required, param)
-		}
-
-		// InputSchema accepts any type that serializes to proper JSON schema
-		tool := anthropic.ToolDefinition{
+		result = append(result, anthropic.ToolDefinition{
 			Name:        td.Name,
 			Description: td.Description,
-			InputSchema: map[string]interface{}{
-				"type":       "object",
-				"properties": properties,
-				"required":   required,
-			},
-		}
-
-		result = append(result, tool)
+			InputSchema: td.InputSchema(),
+		})
 	}
 
 	return result
 }
 
 // ChatStream sends a message and returns streaming channels
-func (c *AnthropicClient) ChatStream(userMessage string) (<-chan string, <-chan error) {
+func (c *AnthropicClient) ChatStream(ctx context.Context, userMessage string) (<-chan string, <-chan error) {
 	tokenChan := make(chan string, 100)
 	errChan := make(chan error, 1)
 
@@ -102,23 +63,29 @@ func (c *AnthropicClient) ChatStream(userMessage string) (<-chan string, <-chan
 		defer close(tokenChan)
 		defer close(errChan)
 
+		prompt, err := c.hooks.SubmitPrompt(ctx, userMessage)
+		if err != nil {
+			errChan <- err
+			return
+		}
+
 		// Add user message to history
 		c.history = append(c.history, anthropic.Message{
 			Role: anthropic.RoleUser,
 			Content: []anthropic.MessageContent{
-				anthropic.NewTextMessageContent(userMessage),
+				anthropic.NewTextMessageContent(prompt),
 			},
 		})
 
 		// Start the agentic loop for tool use
-		c.runAgentLoop(tokenChan, errChan)
+		c.runAgentLoop(ctx, tokenChan, errChan)
 	}()
 
 	return tokenChan, errChan
 }
 
 // runAgentLoop handles the agentic tool use loop
-func (c *AnthropicClient) runAgentLoop(tokenChan chan<- string, errChan chan<- error) {
+func (c *AnthropicClient) runAgentLoop(ctx context.Context, tokenChan chan<- string, errChan chan<- error) {
 	maxIterations := 10
 	iteration := 0
 	availableTools := getAnthropicTools()
@@ -127,10 +94,10 @@ func (c *AnthropicClient) runAgentLoop(tokenChan chan<- string, errChan chan<- e
 		iteration++
 		logger.Infof("Anthropic agent loop iteration %d", iteration)
 
-		ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
+		reqCtx, cancel := context.WithTimeout(ctx, 120*time.Second)
 
 		// Create request (non-streaming for simplicity with tools)
-		resp, err := c.client.CreateMessages(ctx, anthropic.MessagesRequest{
+		resp, err := c.client.CreateMessages(reqCtx, anthropic.MessagesRequest{
 			Model:     c.model,
 			MaxTokens: c.maxTokens,
 			System:    c.systemMsg,
@@ -188,6 +155,7 @@ func (c *AnthropicClient) runAgentLoop(tokenChan chan<- string, errChan chan<- e
 				Role:    anthropic.RoleAssistant,
 				Content: resp.Content,
 			})
+			c.hooks.Stop(ctx, textContent)
 			return
 		}
 
@@ -204,17 +172,9 @@ func (c *AnthropicClient) runAgentLoop(tokenChan chan<- string, errChan chan<- e
 			tokenChan <- fmt.Sprintf("\n\n🔧 **Executing:** `%s`\n", toolUse.Name)
 
 			// Parse input to map[string]string
-			params := make(map[string]string)
-			var inputMap map[string]interface{}
-			if err := json.Unmarshal(toolUse.Input, &inputMap); err == nil {
-				for k, v := range inputMap {
-					if str, ok := v.(string); ok {
-						params[k] = str
-					} else {
-						params[k] = fmt.Sprintf("%v", v)
-					}
-				}
-			}
+			var inputMap map[string]json.RawMessage
+			json.Unmarshal(toolUse.Input, &inputMap)
+			params := tools.StringParams(inputMap)
 
 			// Execute the tool
 			call := tools.ToolCall{
@@ -223,20 +183,14 @@ func (c *AnthropicClient) runAgentLoop(tokenChan chan<- string, errChan chan<- e
 			}
 
 			start := time.Now()
-			result := tools.ExecuteTool(call)
+			_, result := c.hooks.ExecuteTool(ctx, call)
 			duration := time.Since(start)
 
 			// Format result message
 			var resultContent string
 			if result.Success {
 				resultContent = result.Output
-				if len(resultContent) > 4000 {
-					resultContent = resultContent[:4000] + "\n... (truncated)"
-				}
-				displayOutput := resultContent
-				if len(displayOutput) > 500 {
-					displayOutput = displayOutput[:500] + "..."
-				}
+				displayOutput := tools.DisplayOutput(toolUse.Name, resultContent)
 				tokenChan <- fmt.Sprintf("✅ **Success** (%s)\n```\n%s\n```\n\n",
 					duration.Round(time.Millisecond), displayOutput)
 			} else {
@@ -263,6 +217,31 @@ func (c *AnthropicClient) runAgentLoop(tokenChan chan<- string, errChan chan<- e
 
 	logger.Warn("Anthropic agent loop reached max iterations")
 	tokenChan <- "\n\n⚠️ Reached maximum tool iterations. Stopping.\n"
+	c.hooks.Stop(ctx, "")
+}
+
+// Complete sends a single prompt without history or tools
+func (c *AnthropicClient) Complete(ctx context.Context, system, prompt string) (string, error) {
+	resp, err := c.client.CreateMessages(ctx, anthropic.MessagesRequest{
+		Model:     c.model,
+		MaxTokens: c.maxTokens,
+		System:    system,
+		Messages: []anthropic.Message{{
+			Role:    anthropic.RoleUser,
+			Content: []anthropic.MessageContent{anthropic.NewTextMessageContent(prompt)},
+		}},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var text strings.Builder
+	for _, block := range resp.Content {
+		if block.Type == anthropic.MessagesContentTypeText && block.Text != nil {
+			text.WriteString(*block.Text)
+		}
+	}
+	return text.String(), nil
 }
 
 // ClearHistory clears the conversation history
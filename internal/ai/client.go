@@ -17,7 +17,10 @@ import (
 	"golang.org/x/time/rate"
 
 	"github.com/zesbe/zesbe-go/internal/config"
+	"github.com/zesbe/zesbe-go/internal/hooks"
+	"github.com/zesbe/zesbe-go/internal/instructions"
 	"github.com/zesbe/zesbe-go/internal/logger"
+	"github.com/zesbe/zesbe-go/internal/prompt"
 	"github.com/zesbe/zesbe-go/internal/tools"
 )
 
@@ -91,6 +94,8 @@ type Client struct {
 	retryConfig     RetryConfig
 	mu              sync.RWMutex
 	anthropicClient *AnthropicClient // Native Anthropic SDK client
+	hooks           *hooks.Runner
+	prompt          *prompt.Builder
 }
 
 // RetryConfig holds retry configuration
@@ -113,9 +118,9 @@ func DefaultRetryConfig() RetryConfig {
 
 // NewClient creates a new AI client with enterprise features
 func NewClient(cfg *config.Config) *Client {
-	systemPrompt := cfg.SystemPrompt
-	if systemPrompt == "" {
-		systemPrompt = DefaultSystemPrompt()
+	builder, err := prompt.New(cfg)
+	if err != nil {
+		logger.Warnf("Invalid prompt settings: %v", err)
 	}
 
 	// Configure rate limiter based on provider
@@ -125,8 +130,7 @@ func NewClient(cfg *config.Config) *Client {
 		config: cfg,
 		messages: []Message{
 			{
-				Role:    "system",
-				Content: systemPrompt,
+				Role: "system", // Rendered by RefreshSystemPrompt
 			},
 		},
 		httpClient: &http.Client{
@@ -141,8 +145,15 @@ func NewClient(cfg *config.Config) *Client {
 		rateLimiter:  rate.NewLimiter(rate.Limit(rps), rps*2),
 		stats:        &ClientStats{},
 		retryConfig:  DefaultRetryConfig(),
+		prompt:       builder,
 	}
 
+	runner, err := hooks.New(cfg.AllHooks())
+	if err != nil {
+		logger.Warnf("Invalid hooks: %v", err)
+	}
+	client.hooks = runner
+
 	// Initialize Anthropic SDK client for native tool calling
 	if cfg.Provider == "anthropic" && cfg.APIKey != "" {
 		client.anthropicClient = NewAnthropicClient(
@@ -151,9 +162,12 @@ func NewClient(cfg *config.Config) *Client {
 			4096,  // maxTokens
 			0.7,   // temperature
 		)
+		client.anthropicClient.hooks = runner
 		logger.Info("Initialized Anthropic SDK with native tool calling")
 	}
 
+	client.RefreshSystemPrompt()
+
 	return client
 }
 
@@ -179,30 +193,6 @@ func getRateLimitForProvider(provider string) int {
 	}
 }
 
-// DefaultSystemPrompt returns the default system prompt with tools
-func DefaultSystemPrompt() string {
-	basePrompt := `You are Zesbe, an enterprise-grade AI coding assistant with direct access to the filesystem and shell.
-
-You are powerful, precise, and proactive. You can read, write, and edit files, run commands, and help with complex coding tasks.
-
-## Core Principles
-1. **Be Direct** - Execute actions immediately, don't just describe them
-2. **Be Thorough** - Read files before modifying, understand context before acting
-3. **Be Safe** - Validate operations, handle errors gracefully
-4. **Be Efficient** - Use the right tools for the job, minimize unnecessary operations
-
-## Guidelines
-- Use markdown for code blocks with language specifier
-- Always respond in the same language the user uses
-- Use tools proactively when needed - don't ask for permission for basic file operations
-- After using a tool, explain what you found or did clearly
-- If a tool fails, explain the error and try alternatives
-- For complex tasks, break them down into steps
-- Always verify your changes work as expected`
-
-	return basePrompt + tools.GetToolsPrompt()
-}
-
 // ChatResult contains the final response and any tool executions
 type ChatResult struct {
 	Response     string
@@ -218,8 +208,11 @@ type DisplayPart struct {
 	Content string
 }
 
-// Chat sends a message and returns streaming response channels
-func (c *Client) Chat(userMessage string) (<-chan string, <-chan error) {
+// Chat sends a message and returns streaming response channels. Cancelling
+// ctx stops the turn, including any tool that is running.
+func (c *Client) Chat(ctx context.Context, userMessage string) (<-chan string, <-chan error) {
+	c.RefreshSystemPrompt()
+
 	// Use Anthropic SDK for native tool calling when available
 	if c.anthropicClient != nil {
 		logger.Info("Using Anthropic SDK with native tool calling")
@@ -227,7 +220,7 @@ func (c *Client) Chat(userMessage string) (<-chan string, <-chan error) {
 		c.stats.TotalRequests++
 		c.stats.LastRequestTime = time.Now()
 		c.stats.mu.Unlock()
-		return c.anthropicClient.ChatStream(userMessage)
+		return c.anthropicClient.ChatStream(ctx, userMessage)
 	}
 
 	tokenChan := make(chan string, 100)
@@ -237,18 +230,23 @@ func (c *Client) Chat(userMessage string) (<-chan string, <-chan error) {
 		defer close(tokenChan)
 		defer close(errChan)
 
+		prompt, err := c.hooks.SubmitPrompt(ctx, userMessage)
+		if err != nil {
+			errChan <- err
+			return
+		}
+
 		// Add user message to history
 		c.mu.Lock()
 		c.messages = append(c.messages, Message{
 			Role:    "user",
-			Content: userMessage,
+			Content: prompt,
 		})
 		c.mu.Unlock()
 
 		// Tool execution loop
 		for loop := 0; loop < c.maxToolLoops; loop++ {
 			// Wait for rate limiter
-			ctx := context.Background()
 			if err := c.rateLimiter.Wait(ctx); err != nil {
 				errChan <- fmt.Errorf("rate limit error: %w", err)
 				return
@@ -256,7 +254,7 @@ func (c *Client) Chat(userMessage string) (<-chan string, <-chan error) {
 
 			// Get AI response with retry
 			startTime := time.Now()
-			response, err := c.callAPIWithRetry(ctx)
+			response, err := c.callAPIWithRetry(ctx, c.GetHistory())
 			duration := time.Since(startTime)
 
 			if err != nil {
@@ -292,6 +290,7 @@ func (c *Client) Chat(userMessage string) (<-chan string, <-chan error) {
 					Content: response,
 				})
 				c.mu.Unlock()
+				c.hooks.Stop(ctx, cleanResponse)
 				return
 			}
 
@@ -312,7 +311,7 @@ func (c *Client) Chat(userMessage string) (<-chan string, <-chan error) {
 
 				// Execute tool with timing
 				toolStart := time.Now()
-				result := tools.ExecuteTool(call)
+				call, result := c.hooks.ExecuteTool(ctx, call)
 				toolDuration := time.Since(toolStart)
 
 				logger.ToolExecution(call.Name, result.Success, toolDuration)
@@ -328,11 +327,7 @@ func (c *Client) Chat(userMessage string) (<-chan string, <-chan error) {
 				}
 
 				if result.Success {
-					output := result.Output
-					maxLen := 800
-					if len(output) > maxLen {
-						output = output[:maxLen] + "\n... (truncated)"
-					}
+					output := tools.DisplayOutput(call.Name, result.Output)
 					if output != "" {
 						// Determine code block type based on tool
 						codeType := ""
@@ -372,13 +367,43 @@ func (c *Client) Chat(userMessage string) (<-chan string, <-chan error) {
 
 		// Max loops reached
 		tokenChan <- "\n⚠️ Maximum tool iterations reached."
+		c.hooks.Stop(ctx, "")
 	}()
 
 	return tokenChan, errChan
 }
 
+// Complete sends a one-off prompt outside the conversation and returns the
+// reply. The chat history is left untouched and no tools are offered.
+func (c *Client) Complete(ctx context.Context, system, prompt string) (string, error) {
+	if c.anthropicClient != nil {
+		return c.anthropicClient.Complete(ctx, system, prompt)
+	}
+
+	if err := c.rateLimiter.Wait(ctx); err != nil {
+		return "", fmt.Errorf("rate limit error: %w", err)
+	}
+	response, err := c.callAPIWithRetry(ctx, []Message{
+		{Role: "system", Content: system},
+		{Role: "user", Content: prompt},
+	})
+
+	c.stats.mu.Lock()
+	c.stats.TotalRequests++
+	c.stats.LastRequestTime = time.Now()
+	if err != nil {
+		c.stats.TotalErrors++
+	}
+	c.stats.mu.Unlock()
+
+	if err != nil {
+		return "", err
+	}
+	return cleanThinkBlocks(response), nil
+}
+
 // callAPIWithRetry makes an API call with retry logic
-func (c *Client) callAPIWithRetry(ctx context.Context) (string, error) {
+func (c *Client) callAPIWithRetry(ctx context.Context, messages []Message) (string, error) {
 	backoff := retry.NewExponential(c.retryConfig.InitialWait)
 	backoff = retry.WithMaxRetries(uint64(c.retryConfig.MaxRetries), backoff)
 	backoff = retry.WithCappedDuration(c.retryConfig.MaxWait, backoff)
@@ -386,7 +411,7 @@ func (c *Client) callAPIWithRetry(ctx context.Context) (string, error) {
 	var response string
 	err := retry.Do(ctx, backoff, func(ctx context.Context) error {
 		var err error
-		response, err = c.callAPI()
+		response, err = c.callAPI(ctx, messages)
 		if err != nil {
 			// Check if error is retryable
 			if isRetryableError(err) {
@@ -415,14 +440,12 @@ func isRetryableError(err error) bool {
 }
 
 // callAPI makes a single API call and returns the response
-func (c *Client) callAPI() (string, error) {
-	c.mu.RLock()
+func (c *Client) callAPI(ctx context.Context, messages []Message) (string, error) {
 	reqBody := ChatRequest{
 		Model:    c.config.Model,
-		Messages: c.messages,
+		Messages: messages,
 		Stream:   true,
 	}
-	c.mu.RUnlock()
 
 	jsonData, err := json.Marshal(reqBody)
 	if err != nil {
@@ -433,7 +456,7 @@ func (c *Client) callAPI() (string, error) {
 
 	logger.APIRequest(c.config.Provider, c.config.Model, url)
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return "", fmt.Errorf("failed to create request: %w", err)
 	}
@@ -520,7 +543,7 @@ func (c *Client) ClearHistory() {
 func (c *Client) GetHistory() []Message {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
-	return c.messages
+	return append([]Message(nil), c.messages...)
 }
 
 // SetSystemPrompt updates the system prompt
@@ -534,11 +557,35 @@ func (c *Client) SetSystemPrompt(prompt string) {
 	}
 }
 
+// RefreshSystemPrompt re-renders the system prompt for the current
+// directory, git state and ZESBE.md files. The Anthropic client gets tools through the
+// API, so its prompt leaves out the text tool protocol. It returns the
+// instruction files loaded.
+func (c *Client) RefreshSystemPrompt() []instructions.File {
+	native := c.anthropicClient != nil
+	text, files := c.prompt.Build(c.config.Provider, c.config.Model, native)
+	if native {
+		c.anthropicClient.systemMsg = text
+	} else {
+		c.SetSystemPrompt(text)
+	}
+	if len(files) > 0 {
+		logger.Debugf("Loaded %d instruction file(s)", len(files))
+	}
+	return files
+}
+
 // GetStats returns client statistics
 func (c *Client) GetStats() ClientStats {
 	c.stats.mu.RLock()
 	defer c.stats.mu.RUnlock()
-	return *c.stats
+	return ClientStats{
+		TotalRequests:   c.stats.TotalRequests,
+		TotalTokens:     c.stats.TotalTokens,
+		TotalErrors:     c.stats.TotalErrors,
+		AverageLatency:  c.stats.AverageLatency,
+		LastRequestTime: c.stats.LastRequestTime,
+	}
 }
 
 // SetRetryConfig updates retry configuration
@@ -580,7 +627,7 @@ func truncateString(s string, maxLen int) string {
 	if len(s) <= maxLen {
 		return s
 	}
-	return s[:maxLen] + "..."
+	return tools.TruncateRunes(s, maxLen) + "..."
 }
 
 // detectLanguage detects the programming language from file extension
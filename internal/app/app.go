@@ -1,6 +1,7 @@
 package app
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"regexp"
@@ -84,6 +85,9 @@ var (
 
 	sessionStyle = lipgloss.NewStyle().
 			Foreground(lipgloss.Color("#00D4AA"))
+
+	jobsStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#FFD93D"))
 )
 
 // Message types
@@ -95,6 +99,10 @@ type sessionLoadedMsg struct{}
 type tipRotateMsg struct{}
 type focusCheckMsg struct{}
 
+// jobsTickMsg redraws the jobs panel, so runtimes advance and finished jobs
+// drop off without waiting for a keystroke
+type jobsTickMsg struct{}
+
 // Quick action definition
 type QuickAction struct {
 	Key         string
@@ -149,6 +157,7 @@ type Model struct {
 	streamingText strings.Builder
 	streamChan    <-chan string
 	errChan       <-chan error
+	cancelTurn    context.CancelFunc // Stops the running chat turn and its tools
 	width         int
 	height        int
 	ready         bool
@@ -158,6 +167,9 @@ type Model struct {
 	// New enterprise features
 	showQuickActions bool
 	multiLineMode    bool
+	pendingCommit    *pendingCommit // Drafted commit awaiting approval
+	review           *reviewView    // Last /review result
+	pendingInit      bool           // /init is waiting for the agent to write ZESBE.md
 	currentTip       int
 	tokensUsed       int
 	lastContext      string // Current working context (file/dir)
@@ -196,12 +208,19 @@ func New(cfg *config.Config) *Model {
 
 	// Create new session
 	if store != nil {
-		_, err = store.NewSession(cfg.Provider, cfg.Model)
+		s, err := store.NewSession(cfg.Provider, cfg.Model)
 		if err != nil {
 			logger.Error("Failed to create session", err)
+		} else {
+			tools.SetArtifactSession(s.ID)
 		}
 	}
 
+	if err := tools.Configure(cfg); err != nil {
+		logger.Warnf("Invalid tool settings: %v", err)
+	}
+	tools.StartMCPServers(cfg.MCPServers)
+
 	// Get current working directory for context
 	cwd, _ := os.Getwd()
 
@@ -234,9 +253,17 @@ func (m *Model) Init() tea.Cmd {
 		tea.Tick(2*time.Second, func(t time.Time) tea.Msg {
 			return focusCheckMsg{}
 		}),
+		jobsTick(),
 	)
 }
 
+// jobsTick schedules the next jobsTickMsg
+func jobsTick() tea.Cmd {
+	return tea.Tick(time.Second, func(t time.Time) tea.Msg {
+		return jobsTickMsg{}
+	})
+}
+
 // Update handles messages and updates the model
 func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmds []tea.Cmd
@@ -246,11 +273,21 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if m.streaming {
 			// Only allow Ctrl+C during streaming
 			if msg.String() == "ctrl+c" {
+				m.cleanup()
 				return m, tea.Quit
 			}
 			return m, nil
 		}
 
+		if m.pendingCommit != nil {
+			return m.handleCommitKey(msg)
+		}
+		if m.review != nil && m.review.open {
+			if model, cmd, handled := m.handleReviewKey(msg); handled {
+				return model, cmd
+			}
+		}
+
 		switch msg.String() {
 		case "ctrl+c":
 			m.cleanup()
@@ -318,9 +355,8 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			// New conversation/session
 			m.messages = []ChatMessage{}
 			m.client.ClearHistory()
-			if m.sessionStore != nil {
-				m.sessionStore.NewSession(m.config.Provider, m.config.Model)
-			}
+			tools.ResetShellSession()
+			m.newSession()
 			m.textarea.Reset()
 			m.updateViewport()
 			m.addSystemMessage("Started new conversation")
@@ -475,6 +511,18 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return focusCheckMsg{}
 		})
 
+	case jobsTickMsg:
+		return m, jobsTick()
+
+	case commitMessageMsg:
+		return m.handleCommitMessage(msg)
+
+	case commitDoneMsg:
+		return m.handleCommitDone(msg)
+
+	case reviewDoneMsg:
+		return m.handleReviewDone(msg)
+
 	case spinner.TickMsg:
 		if m.streaming {
 			var cmd tea.Cmd
@@ -501,6 +549,7 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.streaming = false
 				m.streamingText.Reset()
 				m.statusText = "Error"
+				m.pendingInit = false
 				m.updateViewport()
 				// Re-focus textarea after error
 				m.textarea.Focus()
@@ -532,6 +581,9 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.streaming = false
 					m.streamingText.Reset()
 					m.statusText = "Ready"
+					if m.pendingInit {
+						m.finishInit()
+					}
 					m.updateViewport()
 					// Re-focus textarea after streaming
 					m.textarea.Focus()
@@ -632,6 +684,8 @@ func (m *Model) View() string {
 			))
 		}
 		quickActionsView = qaBuilder.String()
+	} else {
+		quickActionsView = m.renderReviewPanel()
 	}
 
 	// Status bar with stats
@@ -657,8 +711,13 @@ func (m *Model) View() string {
 		)) + tapHint
 	}
 
+	// Background jobs panel
+	if jobsView := m.renderJobsPanel(); jobsView != "" {
+		statusBar = jobsView + "\n" + statusBar
+	}
+
 	// Build the view
-	if m.showQuickActions {
+	if quickActionsView != "" {
 		return fmt.Sprintf("%s\n%s\n%s\n%s\n\n%s",
 			title,
 			chatView,
@@ -675,6 +734,21 @@ func (m *Model) View() string {
 	)
 }
 
+// renderJobsPanel renders a one-line summary of the background jobs the
+// agent started that are still running, or "" when there are none
+func (m *Model) renderJobsPanel() string {
+	var parts []string
+	for _, job := range tools.Processes.List() {
+		if job.Running {
+			parts = append(parts, fmt.Sprintf("%s ▶ %s (%s)", job.ID, job.Runtime.Round(time.Second), truncateLog(job.Command, 24)))
+		}
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return jobsStyle.Render("  ⚙️  Jobs: " + strings.Join(parts, " │ "))
+}
+
 // handleCommand processes slash commands
 func (m *Model) handleCommand(cmd string) (*Model, tea.Cmd) {
 	parts := strings.Fields(cmd)
@@ -715,12 +789,21 @@ func (m *Model) handleCommand(cmd string) (*Model, tea.Cmd) {
 | /ls [path] | List directory contents |
 | /cat [file] | Read file contents |
 | /pwd | Show current directory |
-| /cd [path] | Change directory |
+| /cd [path] | Change directory and reload ZESBE.md instructions |
+| /init [force] | Have the agent write a starter ZESBE.md for the project |
 | /git status | Show git status |
 | /git log | Show recent commits |
 | /git diff | Show git diff |
 | /git branch | Show branches |
+| /commit [--amend] | Commit staged changes with an AI-written message |
+| /review [range] | Review uncommitted changes or a range like main..HEAD |
+| /review show | Reopen the last review's findings |
+| /review export <json\|sarif> [file] | Save the last review's findings |
 | /run [command] | Execute shell command |
+| /jobs [kill <id>|clear] | List, stop or clear finished background jobs |
+| /mcp | Show MCP server status |
+| /lsp | Show language server status |
+| /config [name] | Show effective settings and where each came from |
 | /quit | Exit application |
 
 ## Keyboard Shortcuts
@@ -746,9 +829,8 @@ func (m *Model) handleCommand(cmd string) (*Model, tea.Cmd) {
 	case "/new":
 		m.messages = []ChatMessage{}
 		m.client.ClearHistory()
-		if m.sessionStore != nil {
-			m.sessionStore.NewSession(m.config.Provider, m.config.Model)
-		}
+		tools.ResetShellSession()
+		m.newSession()
 		m.addSystemMessage("Started new conversation")
 
 	case "/model":
@@ -783,9 +865,7 @@ func (m *Model) handleCommand(cmd string) (*Model, tea.Cmd) {
 		} else {
 			if m.config.SwitchProvider(args[0]) {
 				m.client = ai.NewClient(m.config)
-				if m.sessionStore != nil {
-					m.sessionStore.NewSession(m.config.Provider, m.config.Model)
-				}
+				m.newSession()
 				m.addSystemMessage(fmt.Sprintf("✓ Switched to provider: `%s` (model: `%s`)", m.config.Provider, m.config.Model))
 			} else {
 				m.addErrorMessage(fmt.Sprintf("Unknown provider: %s", args[0]))
@@ -894,6 +974,9 @@ func (m *Model) handleCommand(cmd string) (*Model, tea.Cmd) {
 			result := tools.ChangeDirectory(args[0])
 			if result.Success {
 				m.addSystemMessage(result.Output)
+				if files := m.client.RefreshSystemPrompt(); len(files) > 0 {
+					m.addSystemMessage(describeInstructions(files))
+				}
 			} else {
 				m.addErrorMessage(result.Error)
 			}
@@ -906,15 +989,14 @@ func (m *Model) handleCommand(cmd string) (*Model, tea.Cmd) {
 			var result tools.ToolResult
 			switch args[0] {
 			case "status":
-				result = tools.GitStatus(".")
+				result = tools.GitStatus(context.Background(), ".")
 			case "log":
-				count := 10
-				result = tools.GitLog(".", count)
+				result = tools.GitLog(context.Background(), ".", tools.GitLogOptions{Count: 10})
 			case "diff":
 				staged := len(args) > 1 && args[1] == "--staged"
-				result = tools.GitDiff(".", staged)
+				result = tools.GitDiff(context.Background(), ".", staged)
 			case "branch":
-				result = tools.GitBranch(".")
+				result = tools.GitBranch(context.Background(), ".")
 			default:
 				m.addErrorMessage(fmt.Sprintf("Unknown git command: %s", args[0]))
 				m.textarea.Reset()
@@ -931,12 +1013,24 @@ func (m *Model) handleCommand(cmd string) (*Model, tea.Cmd) {
 			}
 		}
 
+	case "/commit":
+		m.textarea.Reset()
+		return m.startCommit(args)
+
+	case "/init":
+		m.textarea.Reset()
+		return m.handleInitCommand(args)
+
+	case "/review":
+		m.textarea.Reset()
+		return m.handleReviewCommand(args)
+
 	case "/run":
 		if len(args) == 0 {
 			m.addErrorMessage("Usage: /run <command>")
 		} else {
 			command := strings.Join(args, " ")
-			result := tools.ExecuteCommand(command, 0)
+			result := tools.ExecuteCommand(context.Background(), command, 0)
 			if result.Success {
 				m.addSystemMessage(fmt.Sprintf("```\n%s\n```", result.Output))
 			} else {
@@ -944,6 +1038,30 @@ func (m *Model) handleCommand(cmd string) (*Model, tea.Cmd) {
 			}
 		}
 
+	case "/jobs":
+		if len(args) >= 2 && args[0] == "kill" {
+			result := tools.KillProcess(args[1])
+			if result.Success {
+				m.addSystemMessage(result.Output)
+			} else {
+				m.addErrorMessage(result.Error)
+			}
+		} else if len(args) == 1 && args[0] == "clear" {
+			m.addSystemMessage(fmt.Sprintf("Removed %d finished job(s)", tools.Processes.Prune()))
+		} else {
+			result := tools.ListProcesses()
+			m.addSystemMessage(fmt.Sprintf("```\n%s\n```", result.Output))
+		}
+
+	case "/mcp":
+		m.addSystemMessage(m.renderMCPStatus())
+
+	case "/lsp":
+		m.addSystemMessage(m.renderLSPStatus())
+
+	case "/config":
+		m.addSystemMessage(m.renderConfig(args))
+
 	case "/quit", "/exit":
 		m.cleanup()
 		return m, tea.Quit
@@ -957,6 +1075,109 @@ func (m *Model) handleCommand(cmd string) (*Model, tea.Cmd) {
 	return m, nil
 }
 
+// renderMCPStatus describes each configured MCP server
+func (m *Model) renderMCPStatus() string {
+	servers := tools.MCPStatus()
+	if len(servers) == 0 {
+		return "No MCP servers configured. Add them under `mcp_servers` in `~/.zesbe-go/config.json`."
+	}
+
+	var sb strings.Builder
+	sb.WriteString("**MCP Servers**\n\n")
+	sb.WriteString("| Server | Transport | Status | Tools | Resources | Prompts |\n")
+	sb.WriteString("|--------|-----------|--------|-------|-----------|---------|\n")
+	for _, s := range servers {
+		status := "✓ connected"
+		switch {
+		case s.Disabled:
+			status = "disabled"
+		case s.Connecting:
+			status = "connecting…"
+		case !s.Connected:
+			status = "✗ " + s.Error
+		case s.Server.Name != "":
+			status = fmt.Sprintf("✓ %s %s", s.Server.Name, s.Server.Version)
+		}
+		sb.WriteString(fmt.Sprintf("| %s | %s | %s | %d | %d | %d |\n",
+			s.Name, s.Transport, status, s.Tools, s.Resources, s.Prompts))
+	}
+	sb.WriteString("\nTools are available to the model as `mcp__<server>__<tool>`.")
+	return sb.String()
+}
+
+// renderLSPStatus describes each configured language server
+func (m *Model) renderLSPStatus() string {
+	servers := tools.LSPStatus()
+	if len(servers) == 0 {
+		return "No language servers found. Install gopls, pyright or typescript-language-server, or add them under `lsp_servers` in `~/.zesbe-go/config.json`."
+	}
+
+	var sb strings.Builder
+	sb.WriteString("**Language Servers**\n\n")
+	sb.WriteString("| Server | Command | Extensions | Status |\n")
+	sb.WriteString("|--------|---------|------------|--------|\n")
+	for _, s := range servers {
+		status := "idle"
+		switch {
+		case s.Disabled:
+			status = "disabled"
+		case s.Error != "":
+			status = "✗ " + s.Error
+		case s.Running:
+			status = "✓ running"
+		}
+		sb.WriteString(fmt.Sprintf("| %s | %s | %s | %s |\n",
+			s.Name, s.Command, strings.Join(s.Extensions, " "), status))
+	}
+	sb.WriteString("\nServers start the first time a matching file is edited.")
+	return sb.String()
+}
+
+// renderConfig lists the effective settings and their sources, optionally
+// only those whose names start with a prefix such as tool_policies
+func (m *Model) renderConfig(args []string) string {
+	prefix := ""
+	if len(args) > 0 {
+		prefix = args[0]
+	}
+
+	var sb strings.Builder
+	sb.WriteString("**Configuration**\n\n")
+	sb.WriteString("| Setting | Value | Source |\n")
+	sb.WriteString("|---------|-------|--------|\n")
+	shown := 0
+	for _, s := range m.config.Settings() {
+		if !strings.HasPrefix(s.Name, prefix) {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("| %s | `%s` | %s |\n",
+			s.Name, escapeTableCell(s.Value), escapeTableCell(s.Origin.String())))
+		shown++
+	}
+	if shown == 0 {
+		return fmt.Sprintf("No settings match `%s`.", prefix)
+	}
+
+	project := m.config.ProjectFile
+	if project == "" {
+		project = "none"
+	}
+	sb.WriteString(fmt.Sprintf("\nUser config: `%s`\nProject config: `%s`\n", config.GetConfigPath(), project))
+	sb.WriteString("\nLater layers win: default, user, project, env, flag, session.")
+	if len(m.config.Problems()) > 0 {
+		sb.WriteString("\n\n**Ignored**\n")
+		for _, w := range m.config.Problems() {
+			sb.WriteString("\n- " + w)
+		}
+	}
+	return sb.String()
+}
+
+// escapeTableCell keeps a value from breaking a Markdown table row
+func escapeTableCell(s string) string {
+	return strings.ReplaceAll(s, "|", "\\|")
+}
+
 // showStats shows session statistics
 func (m *Model) showStats() (*Model, tea.Cmd) {
 	var sb strings.Builder
@@ -988,8 +1209,25 @@ func (m *Model) showStats() (*Model, tea.Cmd) {
 	return m, nil
 }
 
+// newSession starts a new stored session and its tool output directory
+func (m *Model) newSession() {
+	if m.sessionStore == nil {
+		return
+	}
+	s, err := m.sessionStore.NewSession(m.config.Provider, m.config.Model)
+	if err != nil {
+		logger.Error("Failed to create session", err)
+		return
+	}
+	tools.SetArtifactSession(s.ID)
+}
+
 // cleanup performs cleanup before exit
 func (m *Model) cleanup() {
+	if m.cancelTurn != nil {
+		m.cancelTurn()
+	}
+	tools.Shutdown()
 	if m.sessionStore != nil {
 		m.sessionStore.Close()
 	}
@@ -1016,8 +1254,14 @@ func (m *Model) addErrorMessage(content string) {
 
 // sendMessage starts streaming from the AI
 func (m *Model) sendMessage(input string) tea.Cmd {
+	if m.cancelTurn != nil {
+		m.cancelTurn()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancelTurn = cancel
+
 	// Get channels from AI client
-	tokenChan, errChan := m.client.Chat(input)
+	tokenChan, errChan := m.client.Chat(ctx, input)
 
 	// Store channels for polling
 	m.streamChan = tokenChan
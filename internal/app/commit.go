@@ -0,0 +1,206 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/zesbe/zesbe-go/internal/logger"
+	"github.com/zesbe/zesbe-go/internal/tools"
+)
+
+// maxCommitDiffBytes caps the diff sent to the model for a commit message
+const maxCommitDiffBytes = 24000
+
+// commitMessageSystemPrompt instructs the model when drafting a message
+const commitMessageSystemPrompt = `You write git commit messages. Use the Conventional Commits format: "type(scope): summary" with type one of feat, fix, docs, style, refactor, perf, test, build, ci or chore, and the scope optional. Keep the summary in the imperative mood and at most 72 characters. If the change needs explaining, add a blank line and a short body wrapped at 72 columns saying what changed and why. When the recent commits follow a different convention, follow theirs instead. Reply with the commit message only, without code fences or commentary.`
+
+// commitMessageMsg carries a drafted commit message back to the TUI
+type commitMessageMsg struct {
+	message string
+	amend   bool
+	err     error
+}
+
+// commitDoneMsg carries the result of git commit back to the TUI
+type commitDoneMsg struct {
+	result tools.ToolResult
+}
+
+// pendingCommit is a drafted commit waiting for the user to approve it
+type pendingCommit struct {
+	amend bool
+}
+
+// codeFence matches a fenced block wrapped around a whole reply
+var codeFence = regexp.MustCompile("(?s)^```[a-z]*\n(.*?)\n?```$")
+
+// startCommit drafts a commit message for the staged changes
+func (m *Model) startCommit(args []string) (*Model, tea.Cmd) {
+	amend := false
+	for _, arg := range args {
+		if arg != "--amend" {
+			m.addErrorMessage("Usage: /commit [--amend]")
+			m.updateViewport()
+			return m, nil
+		}
+		amend = true
+	}
+
+	diff, err := tools.GitCommitDiff(context.Background(), ".", amend)
+	if err != nil {
+		m.addErrorMessage(fmt.Sprintf("Failed to read the staged diff: %v", err))
+		m.updateViewport()
+		return m, nil
+	}
+	if strings.TrimSpace(diff) == "" {
+		m.addErrorMessage("Nothing is staged. Stage changes with `git add` first.")
+		m.updateViewport()
+		return m, nil
+	}
+
+	m.streaming = true
+	m.statusText = "Writing commit message..."
+	m.updateViewport()
+	return m, tea.Batch(m.generateCommitMessage(diff, amend), m.spinner.Tick)
+}
+
+// generateCommitMessage asks the current provider for a commit message
+func (m *Model) generateCommitMessage(diff string, amend bool) tea.Cmd {
+	client := m.client
+	return func() tea.Msg {
+		var prompt strings.Builder
+		if subjects, err := tools.GitRecentSubjects(context.Background(), ".", 10); err == nil && len(subjects) > 0 {
+			prompt.WriteString("Recent commit subjects in this repository:\n")
+			for _, s := range subjects {
+				prompt.WriteString("- " + s + "\n")
+			}
+			prompt.WriteString("\n")
+		}
+		if amend {
+			prompt.WriteString("This message replaces the last commit's. ")
+		}
+		prompt.WriteString("Write the commit message for this diff:\n\n")
+		prompt.WriteString(tools.TruncateOutput(diff, maxCommitDiffBytes, tools.TruncateHead))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		defer cancel()
+		reply, err := client.Complete(ctx, commitMessageSystemPrompt, prompt.String())
+		if err != nil {
+			return commitMessageMsg{err: err}
+		}
+		return commitMessageMsg{message: cleanCommitMessage(reply), amend: amend}
+	}
+}
+
+// cleanCommitMessage strips fences and quotes models sometimes add
+func cleanCommitMessage(reply string) string {
+	reply = strings.TrimSpace(reply)
+	if match := codeFence.FindStringSubmatch(reply); match != nil {
+		reply = strings.TrimSpace(match[1])
+	}
+	return strings.Trim(reply, "\"'`")
+}
+
+// handleCommitMessage puts a drafted message in the input for review
+func (m *Model) handleCommitMessage(msg commitMessageMsg) (*Model, tea.Cmd) {
+	m.streaming = false
+	m.statusText = "Ready"
+	if msg.err != nil {
+		logger.Error("Commit message generation failed", msg.err)
+		m.addErrorMessage(fmt.Sprintf("Failed to write a commit message: %v", msg.err))
+		m.updateViewport()
+		return m, nil
+	}
+
+	m.pendingCommit = &pendingCommit{amend: msg.amend}
+	m.textarea.KeyMap.InsertNewline.SetEnabled(true)
+	m.textarea.SetHeight(6)
+	m.textarea.SetValue(msg.message)
+	m.textarea.Focus()
+
+	action := "commit"
+	if msg.amend {
+		action = "amend the last commit"
+	}
+	m.addSystemMessage(fmt.Sprintf("📝 Proposed commit message is in the input. Edit it if needed, then press **Ctrl+S** or **Alt+Enter** to %s, or **Esc** to cancel.", action))
+	m.updateViewport()
+	return m, nil
+}
+
+// handleCommitKey handles keys while a commit message is under review.
+// Enter inserts a newline so the body can be edited.
+func (m *Model) handleCommitKey(msg tea.KeyMsg) (*Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		m.cleanup()
+		return m, tea.Quit
+
+	case "esc":
+		m.endCommitReview()
+		m.addSystemMessage("Commit cancelled")
+		m.updateViewport()
+		return m, nil
+
+	// Terminals don't send Ctrl+Enter as a key of its own, so it never arrives
+	case "ctrl+s", "alt+enter":
+		message := strings.TrimSpace(m.textarea.Value())
+		m.streaming = true
+		m.statusText = "Committing..."
+		m.updateViewport()
+		return m, tea.Batch(runCommit(message, m.pendingCommit.amend), m.spinner.Tick)
+	}
+
+	var cmd tea.Cmd
+	m.textarea, cmd = m.textarea.Update(msg)
+	return m, cmd
+}
+
+// runCommit runs git commit off the UI goroutine, since hooks such as
+// linters can take a while
+func runCommit(message string, amend bool) tea.Cmd {
+	return func() tea.Msg {
+		result := tools.GitCommitWith(context.Background(), ".", message, tools.GitCommitOptions{Amend: amend})
+		return commitDoneMsg{result: result}
+	}
+}
+
+// handleCommitDone reports a finished commit
+func (m *Model) handleCommitDone(msg commitDoneMsg) (*Model, tea.Cmd) {
+	m.streaming = false
+	m.statusText = "Ready"
+	if !msg.result.Success {
+		// Keep the review open so the message can be fixed
+		m.addErrorMessage(msg.result.Error)
+		m.updateViewport()
+		return m, nil
+	}
+
+	var info struct {
+		Commit  string `json:"commit"`
+		Branch  string `json:"branch"`
+		Subject string `json:"subject"`
+	}
+	json.Unmarshal([]byte(msg.result.Output), &info)
+	m.endCommitReview()
+	m.addSystemMessage(fmt.Sprintf("✓ Committed `%s` on `%s`: %s", info.Commit, info.Branch, info.Subject))
+	m.updateViewport()
+	return m, nil
+}
+
+// endCommitReview restores the input to its normal mode
+func (m *Model) endCommitReview() {
+	m.pendingCommit = nil
+	m.textarea.Reset()
+	m.textarea.KeyMap.InsertNewline.SetEnabled(m.multiLineMode)
+	if m.multiLineMode {
+		m.textarea.SetHeight(6)
+	} else {
+		m.textarea.SetHeight(3)
+	}
+}
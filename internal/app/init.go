@@ -0,0 +1,106 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/zesbe/zesbe-go/internal/instructions"
+	"github.com/zesbe/zesbe-go/internal/tools"
+)
+
+// maxInitContextBytes caps the project tree and line counts sent for /init
+const maxInitContextBytes = 8000
+
+// initPrompt asks the agent to write a starter instructions file
+const initPrompt = `Write a starter %s for this project at %s using write_file. It is loaded into your system prompt in every future session here, so it should tell a new contributor, human or AI, what they can't quickly see from the code.
+
+Before writing, read the README, the build manifest (go.mod, package.json, pyproject.toml or similar), CI config and a few representative source files. Then cover, briefly:
+- What the project is and how it is laid out
+- How to build, run, lint and test it, with exact commands
+- Code conventions: naming, error handling, tests, comments, dependencies
+- Anything to avoid or that needs care
+
+Keep it under 80 lines of Markdown. Don't invent commands or conventions you haven't seen evidence for.%s
+
+Project tree:
+` + "```" + `
+%s
+` + "```" + `
+
+Lines of code:
+` + "```" + `
+%s
+` + "```"
+
+// handleInitCommand has the agent analyse the project and write ZESBE.md at
+// its root
+func (m *Model) handleInitCommand(args []string) (*Model, tea.Cmd) {
+	force := len(args) > 0 && args[0] == "force"
+	if len(args) > 1 || (len(args) == 1 && !force) {
+		m.addErrorMessage("Usage: /init [force]")
+		m.updateViewport()
+		return m, nil
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		m.addErrorMessage(err.Error())
+		m.updateViewport()
+		return m, nil
+	}
+	path := filepath.Join(instructions.Root(cwd), instructions.FileName)
+	existing := ""
+	if _, err := os.Stat(path); err == nil {
+		if !force {
+			m.addErrorMessage(fmt.Sprintf("%s already exists. Use `/init force` to have it rewritten.", path))
+			m.updateViewport()
+			return m, nil
+		}
+		existing = " The file already exists: read it first and keep whatever is still accurate."
+	}
+
+	tree := tools.ProjectTree(filepath.Dir(path), 3)
+	lines := tools.CountLines(filepath.Dir(path))
+	prompt := fmt.Sprintf(initPrompt, instructions.FileName, path, existing,
+		tools.TruncateLines(strings.TrimSpace(tree.Output+tree.Error), maxInitContextBytes),
+		tools.TruncateLines(strings.TrimSpace(lines.Output+lines.Error), maxInitContextBytes))
+
+	m.messages = append(m.messages, ChatMessage{
+		Role:      "user",
+		Content:   fmt.Sprintf("/init: analyse this project and write %s", path),
+		Timestamp: time.Now(),
+	})
+	m.pendingInit = true
+	m.streaming = true
+	m.streamingText.Reset()
+	m.statusText = "Analysing project..."
+	m.updateViewport()
+	return m, tea.Batch(m.sendMessage(prompt), m.spinner.Tick)
+}
+
+// finishInit loads the instructions /init wrote once the agent is done
+func (m *Model) finishInit() {
+	m.pendingInit = false
+	m.addSystemMessage(describeInstructions(m.client.RefreshSystemPrompt()))
+}
+
+// describeInstructions lists the loaded instruction files for the user
+func describeInstructions(files []instructions.File) string {
+	if len(files) == 0 {
+		return fmt.Sprintf("No %s instructions found. Run `/init` to create one.", instructions.FileName)
+	}
+	var sb strings.Builder
+	sb.WriteString("Loaded instructions from:")
+	for _, f := range files {
+		sb.WriteString("\n- `" + f.Path + "`")
+		if f.Truncated {
+			sb.WriteString(" (truncated)")
+		}
+	}
+	return sb.String()
+}
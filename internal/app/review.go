@@ -0,0 +1,206 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/zesbe/zesbe-go/internal/logger"
+	"github.com/zesbe/zesbe-go/internal/review"
+)
+
+// reviewPanelRows is how many findings the review panel shows at once
+const reviewPanelRows = 8
+
+// reviewDoneMsg carries a finished review back to the TUI
+type reviewDoneMsg struct {
+	report *review.Report
+	err    error
+}
+
+// reviewView is the last review and the state of its findings panel
+type reviewView struct {
+	report *review.Report
+	cursor int
+	open   bool
+}
+
+// severityIcons marks findings by severity
+var severityIcons = map[string]string{
+	review.SeverityError:   "❌",
+	review.SeverityWarning: "⚠️",
+	review.SeverityInfo:    "💡",
+}
+
+// handleReviewCommand handles /review [range], /review show and
+// /review export <json|sarif> [file]
+func (m *Model) handleReviewCommand(args []string) (*Model, tea.Cmd) {
+	if len(args) > 0 {
+		switch args[0] {
+		case "show":
+			if m.review == nil || len(m.review.report.Findings) == 0 {
+				m.addErrorMessage("No review findings to show. Run /review first.")
+			} else {
+				m.review.open = true
+			}
+			m.updateViewport()
+			return m, nil
+		case "export":
+			m.exportReview(args[1:])
+			m.updateViewport()
+			return m, nil
+		}
+	}
+	if len(args) > 1 {
+		m.addErrorMessage("Usage: /review [range] | /review show | /review export <json|sarif> [file]")
+		m.updateViewport()
+		return m, nil
+	}
+
+	opts := review.Options{}
+	if len(args) == 1 {
+		opts.Range = args[0]
+	}
+	client := m.client
+	m.streaming = true
+	m.statusText = "Reviewing changes..."
+	m.updateViewport()
+	return m, tea.Batch(func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+		defer cancel()
+		report, err := review.Run(ctx, client, opts)
+		return reviewDoneMsg{report: report, err: err}
+	}, m.spinner.Tick)
+}
+
+// handleReviewDone shows the findings of a finished review
+func (m *Model) handleReviewDone(msg reviewDoneMsg) (*Model, tea.Cmd) {
+	m.streaming = false
+	m.statusText = "Ready"
+	if msg.err != nil {
+		logger.Error("Review failed", msg.err)
+		m.addErrorMessage(fmt.Sprintf("Review failed: %v", msg.err))
+		m.updateViewport()
+		return m, nil
+	}
+
+	report := msg.report
+	m.review = &reviewView{report: report, open: len(report.Findings) > 0}
+	if len(report.Files) == 0 {
+		m.addSystemMessage(fmt.Sprintf("🔍 No changes to review in %s", report.Range))
+	} else if len(report.Findings) == 0 {
+		m.addSystemMessage(fmt.Sprintf("🔍 Review of %s: %s", report.Range, report.Summary()))
+	} else {
+		m.addSystemMessage(fmt.Sprintf("🔍 Review of %s: %s\n\nUse ↑/↓ to browse, Enter for details, Esc to close. `/review export sarif` saves the findings.",
+			report.Range, report.Summary()))
+	}
+	m.updateViewport()
+	return m, nil
+}
+
+// handleReviewKey handles navigation while the review panel is open. It
+// reports false for keys the panel does not use.
+func (m *Model) handleReviewKey(msg tea.KeyMsg) (*Model, tea.Cmd, bool) {
+	findings := m.review.report.Findings
+	switch msg.String() {
+	case "up":
+		if m.review.cursor > 0 {
+			m.review.cursor--
+		}
+		return m, nil, true
+	case "down":
+		if m.review.cursor < len(findings)-1 {
+			m.review.cursor++
+		}
+		return m, nil, true
+	case "enter":
+		// Only when not typing, so messages can still be sent
+		if strings.TrimSpace(m.textarea.Value()) != "" {
+			return m, nil, false
+		}
+		m.addSystemMessage(formatFinding(findings[m.review.cursor]))
+		m.updateViewport()
+		return m, nil, true
+	case "esc":
+		m.review.open = false
+		return m, nil, true
+	}
+	return m, nil, false
+}
+
+// renderReviewPanel renders the findings list, or "" when it is closed
+func (m *Model) renderReviewPanel() string {
+	if m.review == nil || !m.review.open {
+		return ""
+	}
+	findings := m.review.report.Findings
+
+	// Keep the cursor inside the visible window
+	start := 0
+	if m.review.cursor >= reviewPanelRows {
+		start = m.review.cursor - reviewPanelRows + 1
+	}
+	end := start + reviewPanelRows
+	if end > len(findings) {
+		end = len(findings)
+	}
+
+	var b strings.Builder
+	b.WriteString("\n" + statusStyle.Render(fmt.Sprintf("  🔍 Review findings (%d/%d)", m.review.cursor+1, len(findings))) +
+		" (↑/↓ to move, Enter for details, Esc to close)\n\n")
+	for i := start; i < end; i++ {
+		f := findings[i]
+		line := fmt.Sprintf("%s %s  %s", severityIcons[f.Severity], truncateLog(f.Location(), 40), truncateLog(f.Title, 60))
+		if i == m.review.cursor {
+			b.WriteString(successStyle.Render("  ▸ "+line) + "\n")
+		} else {
+			b.WriteString("    " + line + "\n")
+		}
+	}
+	return b.String()
+}
+
+// formatFinding renders one finding in full
+func formatFinding(f review.Finding) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s **%s** `%s`\n\n**%s**", severityIcons[f.Severity], f.Severity, f.Location(), f.Title)
+	if f.Message != "" {
+		b.WriteString("\n\n" + f.Message)
+	}
+	if f.Suggestion != "" {
+		b.WriteString("\n\n**Suggestion:** " + f.Suggestion)
+	}
+	return b.String()
+}
+
+// exportReview writes the last review as JSON or SARIF
+func (m *Model) exportReview(args []string) {
+	if m.review == nil {
+		m.addErrorMessage("No review to export. Run /review first.")
+		return
+	}
+	if len(args) == 0 || len(args) > 2 {
+		m.addErrorMessage("Usage: /review export <json|sarif> [file]")
+		return
+	}
+
+	format := strings.ToLower(args[0])
+	file := "zesbe-review." + format
+	if len(args) == 2 {
+		file = args[1]
+	}
+	data, err := m.review.report.Export(format, AppVersion)
+	if err != nil {
+		m.addErrorMessage(err.Error())
+		return
+	}
+	if err := os.WriteFile(file, data, 0644); err != nil {
+		m.addErrorMessage(fmt.Sprintf("Failed to write %s: %v", file, err))
+		return
+	}
+	m.addSystemMessage(fmt.Sprintf("✓ Saved %d finding(s) to %s", len(m.review.report.Findings), file))
+}
@@ -2,9 +2,14 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
+
+	"github.com/zesbe/zesbe-go/internal/credentials"
 )
 
 // Provider represents an AI provider configuration
@@ -70,6 +75,181 @@ type Config struct {
 	WordWrap    int                 `json:"word_wrap"`
 	Providers   map[string]Provider `json:"providers,omitempty"`
 	SystemPrompt string             `json:"system_prompt,omitempty"`
+	// PromptSections replace or extend named sections of the system prompt:
+	// identity, principles, guidelines, environment, instructions and tools
+	PromptSections map[string]PromptSection `json:"prompt_sections,omitempty"`
+	// PersistentShell runs every run_command in one long-lived shell so cd
+	// and export carry over between commands
+	PersistentShell bool `json:"persistent_shell,omitempty"`
+	// CredentialStore is where `zesbe-go auth login` keeps API keys:
+	// "auto" (the default), "keyring" or "file"
+	CredentialStore string `json:"credential_store,omitempty"`
+	// ToolAccess limits the tools offered to the model: "all" (the
+	// default), "read_only" or "none"
+	ToolAccess string `json:"tool_access,omitempty"`
+	// ToolPolicies overrides timeouts and output limits per tool name; the
+	// "*" entry applies to every tool without its own entry
+	ToolPolicies map[string]ToolPolicy `json:"tool_policies,omitempty"`
+	// MCPServers are Model Context Protocol servers whose tools are offered
+	// to the model as mcp__<server>__<tool>
+	MCPServers map[string]MCPServer `json:"mcp_servers,omitempty"`
+	// LSPServers are language servers started on demand to report
+	// diagnostics for files the tools change. Entries replace the built-in
+	// gopls, pyright and typescript-language-server settings of the same name.
+	LSPServers map[string]LSPServer `json:"lsp_servers,omitempty"`
+	// FileHooks are formatters and linters run after write_file and
+	// edit_file, keyed by file extension such as ".go"
+	FileHooks map[string][]FileHook `json:"file_hooks,omitempty"`
+	// Git limits what the git tools may do to shared branches
+	Git GitSettings `json:"git,omitempty"`
+	// CustomTools are team scripts offered to the model as tools
+	CustomTools []CustomTool `json:"custom_tools,omitempty"`
+	// ProjectTools are the custom tools declared in the project config file.
+	// They are never written back to the user config.
+	ProjectTools []CustomTool `json:"-"`
+	// Hooks run commands at points in the agent loop, e.g. to veto tool
+	// calls
+	Hooks Hooks `json:"hooks,omitempty"`
+	// ProjectHooks are the hooks declared in the project config file. They
+	// run after the user's hooks and are never written back.
+	ProjectHooks Hooks `json:"-"`
+	// TrustedProjects records, by project directory, whether the user lets
+	// its .zesbe/config.json run commands and change the git guards. A
+	// false entry means they declined and aren't asked again.
+	TrustedProjects map[string]bool `json:"trusted_projects,omitempty"`
+
+	// Sources records which layer set each setting, keyed by setting name
+	// or, for object settings such as tool_policies, "name.entry"
+	Sources map[string]Origin `json:"-"`
+	// ProjectFile is the project config file in effect, if any
+	ProjectFile string `json:"-"`
+	// Untrusted lists the settings the project config sets that were left
+	// out because the user hasn't trusted the project
+	Untrusted []string `json:"-"`
+	// Files are the config files Load looked for
+	Files []ConfigFile `json:"-"`
+	// Issues are the values that broke the schema and were dropped
+	Issues []Issue `json:"-"`
+	// Warnings describes settings that were ignored
+	Warnings []string `json:"-"`
+	// userValues is the user config file as read, so Save can keep
+	// settings from other layers out of it
+	userValues map[string]interface{}
+}
+
+// ConfigFile is a config file Load looked for
+type ConfigFile struct {
+	Source string // SourceUser or SourceProject
+	Path   string
+	Found  bool
+	Err    error // Why the whole file was ignored, with the line and column
+}
+
+// Tool access modes
+const (
+	ToolAccessAll      = "all"
+	ToolAccessReadOnly = "read_only"
+	ToolAccessNone     = "none"
+)
+
+// GitSettings guards pushes made by the git tools and shapes their commits.
+// By default main and master are protected and force-pushes are refused.
+type GitSettings struct {
+	ProtectedBranches  []string `json:"protected_branches,omitempty"`   // Branch names or globs such as "release/*"
+	AllowProtectedPush bool     `json:"allow_protected_push,omitempty"` // Let git_push update protected branches
+	AllowForcePush     bool     `json:"allow_force_push,omitempty"`     // Let git_push use --force-with-lease
+	CoAuthor           string   `json:"co_author,omitempty"`            // "Name <email>" added as a Co-authored-by trailer
+}
+
+// CustomTool declares a script-backed tool. Command is an argv template:
+// each element may contain {{param}} placeholders that are replaced with
+// the argument value, and the result runs without a shell. An element whose
+// placeholders are all empty is dropped, so optional flags can be written
+// as "--env={{env}}".
+type CustomTool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"` // JSON schema of the arguments
+	Command     []string        `json:"command"`
+	Dir         string          `json:"dir,omitempty"`     // Working directory; defaults to the current one
+	Timeout     string          `json:"timeout,omitempty"` // e.g. "5m"; defaults to the tool policy
+	ReadOnly    bool            `json:"read_only,omitempty"`
+}
+
+// MCPServer describes how to reach an MCP server. Command launches a stdio
+// server; URL connects to a streamable-HTTP server instead. Values in Env and
+// Headers may reference environment variables as ${VAR}.
+type MCPServer struct {
+	Command  string            `json:"command,omitempty"`
+	Args     []string          `json:"args,omitempty"`
+	Env      map[string]string `json:"env,omitempty"`
+	URL      string            `json:"url,omitempty"`
+	Headers  map[string]string `json:"headers,omitempty"`
+	Disabled bool              `json:"disabled,omitempty"`
+	// ReadOnlyTools names the server's tools that change nothing, so
+	// --read-only still offers them; "*" covers every tool. The server's
+	// own read-only hints aren't trusted.
+	ReadOnlyTools []string `json:"read_only_tools,omitempty"`
+}
+
+// LSPServer describes a language server launched over stdio for files with
+// the given extensions. Values in Env may reference environment variables as
+// ${VAR}.
+type LSPServer struct {
+	Command               string            `json:"command,omitempty"`
+	Args                  []string          `json:"args,omitempty"`
+	Env                   map[string]string `json:"env,omitempty"`
+	Extensions            []string          `json:"extensions,omitempty"`  // e.g. [".go"]
+	LanguageID            string            `json:"language_id,omitempty"` // Defaults from the file extension
+	InitializationOptions json.RawMessage   `json:"initialization_options,omitempty"`
+	Disabled              bool              `json:"disabled,omitempty"`
+}
+
+// PromptSection changes one section of the system prompt. Both fields are
+// Go templates over the same data as the built-in sections, e.g. {{.Cwd}}.
+type PromptSection struct {
+	Replace *string `json:"replace,omitempty"` // New section text; "" removes the section
+	Append  string  `json:"append,omitempty"`  // Text added after the section
+}
+
+// Hooks lists the commands run at each agent lifecycle event
+type Hooks struct {
+	PreToolUse       []Hook `json:"pre_tool_use,omitempty"`       // Before a tool runs; may block it or change its arguments
+	PostToolUse      []Hook `json:"post_tool_use,omitempty"`      // After a tool runs; may add a note to its result
+	UserPromptSubmit []Hook `json:"user_prompt_submit,omitempty"` // When a prompt is sent; may block or rewrite it
+	Stop             []Hook `json:"stop,omitempty"`               // When the agent finishes its turn
+}
+
+// Hook is a command run at a lifecycle event. It receives the event as JSON
+// on stdin and may answer with JSON on stdout.
+type Hook struct {
+	Matcher  string   `json:"matcher,omitempty"` // Regexp of tool names for tool events; empty matches every tool
+	Command  []string `json:"command"`
+	Dir      string   `json:"dir,omitempty"`     // Working directory; defaults to the current one
+	Timeout  string   `json:"timeout,omitempty"` // e.g. "10s"; defaults to 30s
+	Disabled bool     `json:"disabled,omitempty"`
+}
+
+// FileHook is a command run on a file after the tools write it. Command is
+// an argv template where {{file}} expands to the file's absolute path; the
+// path is appended when the template doesn't mention it. A hook that changes
+// the file counts as a formatter, and one that exits non-zero is reported as
+// failing.
+type FileHook struct {
+	Name     string   `json:"name,omitempty"` // Shown in reports; defaults to the program name
+	Command  []string `json:"command"`
+	Timeout  string   `json:"timeout,omitempty"` // e.g. "10s"; defaults to 30s
+	Disabled bool     `json:"disabled,omitempty"`
+}
+
+// ToolPolicy limits how long a tool may run and how much output it returns.
+// Zero values fall back to the built-in defaults for the tool.
+type ToolPolicy struct {
+	Timeout         string `json:"timeout,omitempty"`           // Default timeout, e.g. "30s" or "2m"
+	MaxTimeout      string `json:"max_timeout,omitempty"`       // Ceiling for timeouts requested by the model
+	MaxOutputBytes  int    `json:"max_output_bytes,omitempty"`  // Output size sent back to the model
+	MaxDisplayBytes int    `json:"max_display_bytes,omitempty"` // Output size shown in the chat
+	Truncate        string `json:"truncate,omitempty"`          // "head", "tail" or "head_tail"
 }
 
 // GetConfigDir returns the configuration directory path
@@ -81,11 +261,36 @@ func GetConfigDir() string {
 	return filepath.Join(home, ".zesbe-go")
 }
 
+// configPath replaces the user config file when set with SetConfigPath
+var configPath string
+
+// SetConfigPath makes Load and Save use another user config file
+func SetConfigPath(path string) {
+	configPath = path
+}
+
 // GetConfigPath returns the configuration file path
 func GetConfigPath() string {
+	if configPath != "" {
+		return configPath
+	}
 	return filepath.Join(GetConfigDir(), "config.json")
 }
 
+// GetProjectConfigPath returns the project config file that applies in the
+// current directory: the nearest .zesbe/config.json at or above it, or
+// .zesbe/config.json in the directory itself when there is none
+func GetProjectConfigPath() string {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return filepath.Join(".zesbe", "config.json")
+	}
+	if path := FindProjectConfig(cwd); path != "" {
+		return path
+	}
+	return filepath.Join(cwd, ".zesbe", "config.json")
+}
+
 // GetAPIKeyPath returns the API key file path for a provider
 func GetAPIKeyPath(provider string) string {
 	home, _ := os.UserHomeDir()
@@ -97,74 +302,202 @@ func GetAPIKeyEnvVar(provider string) string {
 	return strings.ToUpper(provider) + "_API_KEY"
 }
 
-// Load loads the configuration from file and environment
+// Load builds the configuration from layers, each overriding the one
+// before: built-in defaults, the user config, the project config,
+// environment variables and flags. Sources records where each setting came
+// from.
 func Load() *Config {
-	cfg := &Config{
-		Provider:  "minimax",
-		Model:     "MiniMax-M2",
-		BaseURL:   "https://api.minimax.io/v1",
-		Yolo:      true,
-		Theme:     "dark",
-		WordWrap:  100,
-		Providers: make(map[string]Provider),
-	}
+	return LoadWithFlags(nil)
+}
+
+// LoadWithFlags is Load with command-line overrides as the last layer
+func LoadWithFlags(overrides []Override) *Config {
+	var warnings []string
+	var files []ConfigFile
+	layers := []layer{defaultLayer()}
 
-	// Copy default providers
-	for k, v := range DefaultProviders {
-		cfg.Providers[k] = v
+	user, err := fileLayer(GetConfigPath(), SourceUser)
+	files = append(files, ConfigFile{Source: SourceUser, Path: GetConfigPath(), Found: user != nil || err != nil, Err: err})
+	if user != nil {
+		layers = append(layers, *user)
 	}
 
-	// Try to load from config file
-	configPath := GetConfigPath()
-	if data, err := os.ReadFile(configPath); err == nil {
-		if err := json.Unmarshal(data, cfg); err == nil {
-			// Merge with default providers
-			for k, v := range DefaultProviders {
-				if _, exists := cfg.Providers[k]; !exists {
-					cfg.Providers[k] = v
+	projectPath := GetProjectConfigPath()
+	project, err := fileLayer(projectPath, SourceProject)
+	files = append(files, ConfigFile{Source: SourceProject, Path: projectPath, Found: project != nil || err != nil, Err: err})
+	trusted := userTrusts(user, ProjectRoot(projectPath))
+	var untrusted []string
+	if project != nil {
+		layered := *project
+		layered.values = make(map[string]interface{}, len(project.values))
+		for k, v := range project.values {
+			if reason, denied := projectDenied[k]; denied {
+				warnings = append(warnings, fmt.Sprintf("%s: ignoring %s, %s", projectPath, k, reason))
+				continue
+			}
+			if projectGated[k] {
+				if !trusted {
+					untrusted = append(untrusted, k)
+				}
+				if !trusted || k == "custom_tools" || k == "hooks" {
+					continue
 				}
 			}
+			layered.values[k] = v
+		}
+		layers = append(layers, layered)
+	}
+	if len(untrusted) > 0 {
+		sort.Strings(untrusted)
+		warnings = append(warnings, fmt.Sprintf("%s: ignoring %s until the project is trusted (zesbe-go config trust)",
+			projectPath, strings.Join(untrusted, ", ")))
+	}
+
+	var issues []Issue
+	for _, l := range []*layer{user, project} {
+		if l != nil {
+			issues = append(issues, l.issues...)
 		}
 	}
+	for _, l := range append(envLayer(), flagLayer(overrides)...) {
+		issues = append(issues, validateLayer(&l, nil)...)
+		layers = append(layers, l)
+	}
 
-	// Load API key from various sources
-	cfg.APIKey = loadAPIKey(cfg.Provider, cfg.APIKey)
+	merged, sources := mergeLayers(layers)
+	cfg := &Config{}
+	if err := decodeStrict(merged, cfg); err != nil {
+		warnings = append(warnings, fmt.Sprintf("settings: %v", err))
+	}
+	cfg.Sources = sources
+	cfg.Files = files
+	cfg.Issues = issues
+	cfg.Warnings = warnings
+	if user != nil {
+		cfg.userValues = user.values
+	}
+	if project != nil {
+		cfg.ProjectFile = projectPath
+		cfg.Untrusted = untrusted
+		if trusted {
+			loadProjectConfig(cfg, projectPath)
+		}
+	}
+	if user != nil && hasSecret(user.values) {
+		cfg.Warnings = append(cfg.Warnings, fmt.Sprintf("%s holds an API key in plain text; move it with `zesbe-go auth login`", GetConfigPath()))
+	}
+
+	store, err := credentials.Open(cfg.CredentialStore, GetConfigDir())
+	if err != nil {
+		cfg.Warnings = append(cfg.Warnings, fmt.Sprintf("credential_store: %v", err))
+	}
+	setCredentials(store)
 
 	// Update BaseURL and Model from provider if not explicitly set
 	if provider, exists := cfg.Providers[cfg.Provider]; exists {
+		derived := Origin{Source: SourceDefault, Location: "provider " + cfg.Provider}
 		if cfg.BaseURL == "" {
 			cfg.BaseURL = provider.BaseURL
+			cfg.Sources["base_url"] = derived
 		}
 		if cfg.Model == "" {
 			cfg.Model = provider.Model
+			cfg.Sources["model"] = derived
 		}
 	}
 
 	return cfg
 }
 
-// loadAPIKey attempts to load API key from file or environment
-func loadAPIKey(provider, existingKey string) string {
-	if existingKey != "" {
-		return existingKey
+// loadProjectConfig reads custom tools and hooks from the project config
+// file. Their commands run from the project directory, the one holding
+// .zesbe, unless they set their own.
+func loadProjectConfig(cfg *Config, path string) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
 	}
+	var project struct {
+		CustomTools []CustomTool `json:"custom_tools"`
+		Hooks       Hooks        `json:"hooks"`
+	}
+	if err := json.Unmarshal(data, &project); err != nil {
+		cfg.Warnings = append(cfg.Warnings, fmt.Sprintf("%s: %v", path, err))
+		return
+	}
+	root := ProjectRoot(path)
+	resolve := func(dir string) string {
+		if dir == "" {
+			return root
+		} else if !filepath.IsAbs(dir) {
+			return filepath.Join(root, dir)
+		}
+		return dir
+	}
+	for _, t := range project.CustomTools {
+		t.Dir = resolve(t.Dir)
+		cfg.ProjectTools = append(cfg.ProjectTools, t)
+	}
+	for _, list := range []*[]Hook{
+		&project.Hooks.PreToolUse, &project.Hooks.PostToolUse,
+		&project.Hooks.UserPromptSubmit, &project.Hooks.Stop,
+	} {
+		for i := range *list {
+			(*list)[i].Dir = resolve((*list)[i].Dir)
+		}
+	}
+	cfg.ProjectHooks = project.Hooks
+}
+
+// keyStore is the credential store opened by the last Load
+var (
+	keyStore   credentials.Store
+	keyStoreMu sync.Mutex
+)
+
+// setCredentials replaces the credential store
+func setCredentials(store credentials.Store) {
+	keyStoreMu.Lock()
+	defer keyStoreMu.Unlock()
+	keyStore = store
+}
+
+// Credentials returns the credential store chosen by credential_store, or
+// nil when the setting names one that can't be used
+func Credentials() credentials.Store {
+	keyStoreMu.Lock()
+	defer keyStoreMu.Unlock()
+	return keyStore
+}
 
+// loadAPIKey looks up a provider's API key in the environment, then in the
+// credential store, then in the provider's plain-text key file
+func loadAPIKey(provider string) (string, Origin) {
 	// Try environment variable first
 	envVar := GetAPIKeyEnvVar(provider)
 	if key := os.Getenv(envVar); key != "" {
-		return strings.TrimSpace(key)
+		return strings.TrimSpace(key), Origin{Source: SourceEnv, Location: envVar}
+	}
+
+	// A locked or missing store falls through to the key file
+	if store := Credentials(); store != nil {
+		if key, err := store.Get(provider); err == nil {
+			return key, Origin{Source: SourceUser, Location: store.Name()}
+		}
 	}
 
 	// Try provider-specific file
 	keyPath := GetAPIKeyPath(provider)
 	if data, err := os.ReadFile(keyPath); err == nil {
-		return strings.TrimSpace(string(data))
+		return strings.TrimSpace(string(data)), Origin{Source: SourceUser, Location: keyPath}
 	}
 
-	return ""
+	return "", Origin{Source: SourceDefault}
 }
 
-// Save saves the configuration to file
+// Save writes the settings to the user config file, readable only by the
+// user. Values that came from the project config, the environment or flags
+// are left as the user config had them, and API keys are never written.
 func (c *Config) Save() error {
 	configPath := GetConfigPath()
 
@@ -173,16 +506,80 @@ func (c *Config) Save() error {
 		return err
 	}
 
-	// Don't save the API key to file for security
-	configToSave := *c
-	configToSave.APIKey = ""
+	values, err := toMap(c)
+	if err != nil {
+		return err
+	}
+	for path, o := range c.Sources {
+		switch o.Source {
+		case SourceProject, SourceEnv, SourceFlag:
+			restoreValue(values, c.userValues, path)
+		}
+	}
+	stripSecrets(values)
 
-	data, err := json.MarshalIndent(configToSave, "", "  ")
+	data, err := json.MarshalIndent(values, "", "  ")
 	if err != nil {
 		return err
 	}
 
-	return os.WriteFile(configPath, data, 0644)
+	return writeUserFile(configPath, data)
+}
+
+// writeUserFile writes a file only the user may read, tightening the mode
+// of an existing one
+func writeUserFile(path string, data []byte) error {
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return err
+	}
+	return os.Chmod(path, 0600)
+}
+
+// Problems lists the config files and settings that were ignored and why
+func (c *Config) Problems() []string {
+	return append(c.Errors(), c.Warnings...)
+}
+
+// Errors lists the config files that couldn't be read and the values that
+// broke the schema, with their line and column. Unlike warnings, these
+// mean the configuration isn't what the user wrote.
+func (c *Config) Errors() []string {
+	var errs []string
+	for _, f := range c.Files {
+		if f.Err != nil {
+			errs = append(errs, fmt.Sprintf("Ignoring %s config: %v", f.Source, f.Err))
+		}
+	}
+	for _, issue := range c.Issues {
+		errs = append(errs, issue.String())
+	}
+	return errs
+}
+
+// LoadAPIKey fills in the current provider's API key from the environment,
+// the credential store or the key file when the config doesn't set one.
+// Load leaves this out so commands that never call a provider don't unlock
+// the credential store.
+func (c *Config) LoadAPIKey() {
+	if c.APIKey != "" {
+		return
+	}
+	if c.Sources == nil {
+		c.Sources = make(map[string]Origin)
+	}
+	c.APIKey, c.Sources["api_key"] = loadAPIKey(c.Provider)
+}
+
+// ProviderKey returns the API key for a provider and where it came from,
+// or "" when there is none
+func (c *Config) ProviderKey(name string) (string, Origin) {
+	if name == c.Provider && c.APIKey != "" {
+		return c.APIKey, c.origin("api_key")
+	}
+	if p, ok := c.Providers[name]; ok && p.APIKey != "" {
+		return p.APIKey, c.origin("providers." + name)
+	}
+	return loadAPIKey(name)
 }
 
 // SwitchProvider switches to a different provider
@@ -195,7 +592,20 @@ func (c *Config) SwitchProvider(name string) bool {
 	c.Provider = name
 	c.BaseURL = provider.BaseURL
 	c.Model = provider.Model
-	c.APIKey = loadAPIKey(name, provider.APIKey)
+	keyOrigin := Origin{Source: SourceUser, Location: "providers." + name}
+	c.APIKey = provider.APIKey
+	if c.APIKey == "" {
+		c.APIKey, keyOrigin = loadAPIKey(name)
+	}
+
+	if c.Sources == nil {
+		c.Sources = make(map[string]Origin)
+	}
+	session := Origin{Source: SourceSession, Location: "/provider " + name}
+	c.Sources["provider"] = session
+	c.Sources["model"] = session
+	c.Sources["base_url"] = session
+	c.Sources["api_key"] = keyOrigin
 
 	return true
 }
@@ -212,6 +622,37 @@ func (c *Config) GetCurrentProvider() Provider {
 	}
 }
 
+// ProjectTrust reports whether the user trusts the project config in
+// effect, and whether they have decided either way
+func (c *Config) ProjectTrust() (trusted, decided bool) {
+	if c.ProjectFile == "" {
+		return false, false
+	}
+	trusted, decided = c.TrustedProjects[ProjectRoot(c.ProjectFile)]
+	return trusted, decided
+}
+
+// AllCustomTools returns the user's custom tools followed by the project's,
+// so project declarations override user ones of the same name
+func (c *Config) AllCustomTools() []CustomTool {
+	all := make([]CustomTool, 0, len(c.CustomTools)+len(c.ProjectTools))
+	all = append(all, c.CustomTools...)
+	return append(all, c.ProjectTools...)
+}
+
+// AllHooks returns the user's hooks followed by the project's
+func (c *Config) AllHooks() Hooks {
+	join := func(user, project []Hook) []Hook {
+		return append(append([]Hook(nil), user...), project...)
+	}
+	return Hooks{
+		PreToolUse:       join(c.Hooks.PreToolUse, c.ProjectHooks.PreToolUse),
+		PostToolUse:      join(c.Hooks.PostToolUse, c.ProjectHooks.PostToolUse),
+		UserPromptSubmit: join(c.Hooks.UserPromptSubmit, c.ProjectHooks.UserPromptSubmit),
+		Stop:             join(c.Hooks.Stop, c.ProjectHooks.Stop),
+	}
+}
+
 // ListProviders returns all available provider names
 func (c *Config) ListProviders() []string {
 	providers := make([]string, 0, len(c.Providers))
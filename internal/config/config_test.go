@@ -0,0 +1,46 @@
+package config
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/zesbe/zesbe-go/internal/credentials"
+)
+
+func TestLoadLeavesCredentialsLocked(t *testing.T) {
+	cfg := loadIn(t, `{"credential_store": "file"}`, "")
+	t.Setenv(GetAPIKeyEnvVar(cfg.Provider), "")
+	t.Setenv(credentials.PassphraseEnv, "secret")
+	path := filepath.Join(GetConfigDir(), credentials.FileName)
+	if err := credentials.NewFileStore(path).Set(cfg.Provider, "sk-stored"); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(credentials.PassphraseEnv, "")
+
+	asked := 0
+	credentials.SetPrompt(func(bool) (string, error) {
+		asked++
+		return "", errors.New("no terminal")
+	})
+	defer credentials.SetPrompt(nil)
+
+	// Loading the config, as every command does, must not ask
+	cfg = LoadWithFlags(nil)
+	if asked != 0 || cfg.APIKey != "" {
+		t.Fatalf("Load asked %d times and found key %q, want neither", asked, cfg.APIKey)
+	}
+
+	// Only a command that calls the provider unlocks the store
+	credentials.SetPrompt(func(bool) (string, error) {
+		asked++
+		return "secret", nil
+	})
+	cfg.LoadAPIKey()
+	if asked != 1 || cfg.APIKey != "sk-stored" {
+		t.Errorf("LoadAPIKey asked %d times and found key %q, want once and sk-stored", asked, cfg.APIKey)
+	}
+	if origin := cfg.origin("api_key"); origin.Location != Credentials().Name() {
+		t.Errorf("api_key from %s, want the credential store", origin)
+	}
+}
@@ -0,0 +1,202 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// settingNames lists the top-level setting names from Config's JSON tags
+func settingNames() map[string]bool {
+	names := make(map[string]bool)
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			names[name] = true
+		}
+	}
+	return names
+}
+
+// Get returns the effective value of a setting and where it came from. key
+// may reach into object settings, as in "tool_policies.run_command" or
+// "git.co_author". API keys are masked.
+func (c *Config) Get(key string) (interface{}, Origin, bool) {
+	values, err := toMap(c)
+	if err != nil {
+		return nil, Origin{}, false
+	}
+	maskSecrets(values)
+
+	parts := strings.Split(key, ".")
+	var v interface{} = values
+	for _, part := range parts {
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, Origin{}, false
+		}
+		if v, ok = obj[part]; !ok {
+			return nil, Origin{}, false
+		}
+	}
+	if len(parts) > 2 {
+		parts = parts[:2]
+	}
+	return v, c.origin(strings.Join(parts, ".")), true
+}
+
+// SetFileValue sets one setting in a config file, creating the file when
+// needed. value is parsed as JSON when it is valid JSON and taken as a
+// string otherwise; null removes the setting. key may reach into object
+// settings as with Get. Values that break the schema or hold an API key are
+// refused, and the user config is kept readable only by the user.
+func SetFileValue(path, key, value string) error {
+	parts := strings.Split(key, ".")
+	if !settingNames()[parts[0]] {
+		if guess := closest(parts[0], loadSchema().Properties); guess != "" {
+			return fmt.Errorf("unknown setting %q (did you mean %q?)", parts[0], guess)
+		}
+		return fmt.Errorf("unknown setting %q", parts[0])
+	}
+	for _, part := range parts {
+		if part == "" {
+			return fmt.Errorf("invalid setting name %q", key)
+		}
+	}
+
+	values := make(map[string]interface{})
+	data, err := os.ReadFile(path)
+	if err == nil {
+		if values, err = decodeObject(data); err != nil {
+			return fileError(path, data, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	var v interface{} = value
+	if decoded, err := decodeValue(value); err == nil {
+		v = decoded
+	}
+
+	if v != nil {
+		// Refuse API keys before touching the file
+		var nested interface{} = v
+		for i := len(parts) - 1; i > 0; i-- {
+			nested = map[string]interface{}{parts[i]: nested}
+		}
+		setting := map[string]interface{}{parts[0]: nested}
+		if hasSecret(setting) {
+			return fmt.Errorf("%s: API keys aren't kept in config files; use `zesbe-go auth login`", key)
+		}
+	}
+
+	obj := values
+	for _, part := range parts[:len(parts)-1] {
+		next, ok := obj[part].(map[string]interface{})
+		if !ok {
+			if v == nil {
+				return nil // Nothing to remove
+			}
+			next = make(map[string]interface{})
+			obj[part] = next
+		}
+		obj = next
+	}
+	last := parts[len(parts)-1]
+	if v == nil {
+		delete(obj, last)
+	} else {
+		obj[last] = v
+	}
+
+	// Check the changed setting, or entry of an object setting, as the file
+	// now has it; problems elsewhere in the file are left alone
+	for _, e := range validateValues(values) {
+		if len(e.path) > 0 && e.path[0] == parts[0] && (len(parts) == 1 || len(e.path) == 1 || e.path[1] == parts[1]) {
+			return fmt.Errorf("%s %s", formatKey(e.path), e.message)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	out, err := json.MarshalIndent(values, "", "  ")
+	if err != nil {
+		return err
+	}
+	if path == GetConfigPath() {
+		return writeUserFile(path, append(out, '\n'))
+	}
+	return os.WriteFile(path, append(out, '\n'), 0644)
+}
+
+// CheckProjectSetting reports whether the project config may set key. The
+// settings that run commands or loosen the git guards need the project to
+// be trusted first, as Load only applies them then.
+func (c *Config) CheckProjectSetting(key string) error {
+	name, _, _ := strings.Cut(key, ".")
+	if reason := projectDenied[name]; reason != "" {
+		return fmt.Errorf("a project config can't set %s: %s", name, reason)
+	}
+	if projectGated[name] {
+		root := ProjectRoot(GetProjectConfigPath())
+		if !c.TrustedProjects[root] {
+			return fmt.Errorf("a project config can only set %s once you trust %s: run `zesbe-go config trust`", name, root)
+		}
+	}
+	return nil
+}
+
+// SetProjectTrust records in the user config whether the project in dir may
+// run commands from its .zesbe/config.json
+func SetProjectTrust(dir string, trusted bool) error {
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	path := GetConfigPath()
+	values := make(map[string]interface{})
+	data, err := os.ReadFile(path)
+	if err == nil {
+		if values, err = decodeObject(data); err != nil {
+			return fileError(path, data, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	list, ok := values["trusted_projects"].(map[string]interface{})
+	if !ok {
+		list = make(map[string]interface{})
+		values["trusted_projects"] = list
+	}
+	list[root] = trusted
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	out, err := json.MarshalIndent(values, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeUserFile(path, append(out, '\n'))
+}
+
+// decodeValue decodes a single JSON value keeping numbers exact
+func decodeValue(s string) (interface{}, error) {
+	var v interface{}
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("trailing data")
+	}
+	return v, nil
+}
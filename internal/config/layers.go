@@ -0,0 +1,496 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Setting sources, from lowest to highest precedence
+const (
+	SourceDefault = "default"
+	SourceUser    = "user"
+	SourceProject = "project"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+	SourceSession = "session" // Changed while the app runs, e.g. by /provider
+)
+
+// Origin records which layer set a value
+type Origin struct {
+	Source   string // One of the Source constants
+	Location string // Config file, environment variable, flag or command
+}
+
+// String returns e.g. "project (/repo/.zesbe/config.json)"
+func (o Origin) String() string {
+	if o.Location == "" {
+		return o.Source
+	}
+	return fmt.Sprintf("%s (%s)", o.Source, o.Location)
+}
+
+// envSettings maps environment variables to the settings they override
+var envSettings = []struct {
+	name string
+	key  string
+}{
+	{"ZESBE_PROVIDER", "provider"},
+	{"ZESBE_MODEL", "model"},
+	{"ZESBE_BASE_URL", "base_url"},
+	{"ZESBE_THEME", "theme"},
+}
+
+// projectDenied are settings a project config may not change: a cloned
+// repository shouldn't be able to send the user's API key elsewhere or
+// trust itself
+var projectDenied = map[string]string{
+	"api_key":          "credentials come from the user config",
+	"credential_store": "credentials come from the user config",
+	"base_url":         "endpoints come from the user config",
+	"providers":        "endpoints come from the user config",
+	"trusted_projects": "trust is given in the user config",
+}
+
+// projectGated are settings that run commands or loosen the git guards. A
+// project config only applies them once the user trusts the project.
+// custom_tools and hooks are then added to the user's rather than layered.
+var projectGated = map[string]bool{
+	"custom_tools": true,
+	"file_hooks":   true,
+	"git":          true,
+	"hooks":        true,
+	"lsp_servers":  true,
+	"mcp_servers":  true,
+	"tool_access":  true,
+}
+
+// ProjectRoot returns the directory holding a project config file's .zesbe
+func ProjectRoot(path string) string {
+	return filepath.Dir(filepath.Dir(path))
+}
+
+// userTrusts reports whether the user config trusts a project directory
+func userTrusts(user *layer, root string) bool {
+	if user == nil {
+		return false
+	}
+	list, _ := user.values["trusted_projects"].(map[string]interface{})
+	trusted, _ := list[root].(bool)
+	return trusted
+}
+
+// layer is one source of settings as decoded JSON
+type layer struct {
+	origin Origin
+	values map[string]interface{}
+	issues []Issue // Values dropped for breaking the schema
+}
+
+// defaultLayer returns the built-in settings
+func defaultLayer() layer {
+	defaults := &Config{
+		Provider:  "minimax",
+		Yolo:      true,
+		Theme:     "dark",
+		WordWrap:  100,
+		Providers: DefaultProviders,
+	}
+	values, _ := toMap(defaults)
+	return layer{origin: Origin{Source: SourceDefault}, values: values}
+}
+
+// fileLayer reads a config file. A missing file gives no layer.
+func fileLayer(path, source string) (*layer, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	values, err := decodeObject(data)
+	if err != nil {
+		return nil, fileError(path, data, err)
+	}
+	l := &layer{origin: Origin{Source: source, Location: path}, values: values}
+	l.issues = validateLayer(l, data)
+	return l, nil
+}
+
+// envLayer collects the settings given in the environment
+func envLayer() []layer {
+	var layers []layer
+	for _, e := range envSettings {
+		if v := strings.TrimSpace(os.Getenv(e.name)); v != "" {
+			layers = append(layers, layer{
+				origin: Origin{Source: SourceEnv, Location: e.name},
+				values: map[string]interface{}{e.key: v},
+			})
+		}
+	}
+	return layers
+}
+
+// Override is a setting given on the command line
+type Override struct {
+	Flag  string      // e.g. "--model"
+	Key   string      // Setting name, e.g. "model"
+	Value interface{} // JSON-compatible value
+}
+
+// flagLayer turns command-line overrides into a layer per flag
+func flagLayer(overrides []Override) []layer {
+	var layers []layer
+	for _, o := range overrides {
+		layers = append(layers, layer{
+			origin: Origin{Source: SourceFlag, Location: o.Flag},
+			values: map[string]interface{}{o.Key: o.Value},
+		})
+	}
+	return layers
+}
+
+// decodeObject decodes a JSON object keeping numbers exact
+func decodeObject(data []byte) (map[string]interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var values map[string]interface{}
+	if err := dec.Decode(&values); err != nil {
+		return nil, err
+	}
+	if values == nil {
+		return nil, fmt.Errorf("expected a JSON object")
+	}
+	if offset := dec.InputOffset(); len(bytes.TrimSpace(data[offset:])) > 0 {
+		for strings.IndexByte(" \t\r\n", data[offset]) >= 0 {
+			offset++
+		}
+		return nil, &dataError{offset: offset, msg: "unexpected data after the settings object"}
+	}
+	return values, nil
+}
+
+// dataError is a problem at an offset in a JSON document
+type dataError struct {
+	offset int64
+	msg    string
+}
+
+func (e *dataError) Error() string {
+	return e.msg
+}
+
+// fileError reports why a config file can't be decoded, with the line and
+// column for JSON syntax and type errors
+func fileError(path string, data []byte, err error) error {
+	var offset int64
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var dataErr *dataError
+	switch {
+	case errors.As(err, &syntaxErr):
+		// Offset is just past the byte that broke the syntax
+		offset = max(syntaxErr.Offset-1, 0)
+	case errors.As(err, &typeErr):
+		offset = typeErr.Offset
+	case errors.As(err, &dataErr):
+		offset = dataErr.offset
+	default:
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	line, column := lineColumn(data, offset)
+	return fmt.Errorf("%s:%d:%d: %w", path, line, column, err)
+}
+
+// decodeStrict decodes merged settings into cfg, failing on any field
+// Config doesn't have
+func decodeStrict(values map[string]interface{}, cfg *Config) error {
+	data, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode(cfg)
+}
+
+// toMap converts a value to its JSON object form
+func toMap(v interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return decodeObject(data)
+}
+
+// mergeLayers applies layers in order. Top-level objects such as
+// tool_policies merge entry by entry and everything else is replaced. The
+// origin of each top-level value, or of each entry of an object, is
+// recorded under its dotted path.
+func mergeLayers(layers []layer) (map[string]interface{}, map[string]Origin) {
+	merged := make(map[string]interface{})
+	sources := make(map[string]Origin)
+	for _, l := range layers {
+		// A new provider brings its own model, endpoint and key unless the
+		// same layer names them, so a project that picks another provider
+		// can't have the user's key sent to it
+		if p, ok := l.values["provider"]; ok && p != merged["provider"] {
+			for _, k := range []string{"model", "base_url", "api_key"} {
+				if _, set := l.values[k]; !set {
+					delete(merged, k)
+					delete(sources, k)
+				}
+			}
+		}
+
+		for k, v := range l.values {
+			obj, isObj := v.(map[string]interface{})
+			if !isObj {
+				merged[k] = v
+				sources[k] = l.origin
+				clearSources(sources, k+".")
+				continue
+			}
+			dst, ok := merged[k].(map[string]interface{})
+			if !ok {
+				dst = make(map[string]interface{})
+				merged[k] = dst
+				delete(sources, k)
+			}
+			for ek, ev := range obj {
+				dst[ek] = deepMerge(dst[ek], ev)
+				sources[k+"."+ek] = l.origin
+			}
+		}
+	}
+	return merged, sources
+}
+
+// restoreValue sets path in values back to its value in user, or removes
+// it when the user config doesn't set it
+func restoreValue(values, user map[string]interface{}, path string) {
+	key, entry, nested := strings.Cut(path, ".")
+	if !nested {
+		if v, ok := user[key]; ok {
+			values[key] = v
+		} else {
+			delete(values, key)
+		}
+		return
+	}
+	obj, ok := values[key].(map[string]interface{})
+	if !ok {
+		return
+	}
+	if userObj, ok := user[key].(map[string]interface{}); ok {
+		if v, ok := userObj[entry]; ok {
+			obj[entry] = v
+			return
+		}
+	}
+	delete(obj, entry)
+}
+
+// deepMerge overlays b onto a, merging objects key by key
+func deepMerge(a, b interface{}) interface{} {
+	am, aok := a.(map[string]interface{})
+	bm, bok := b.(map[string]interface{})
+	if !aok || !bok {
+		return b
+	}
+	out := make(map[string]interface{}, len(am)+len(bm))
+	for k, v := range am {
+		out[k] = v
+	}
+	for k, v := range bm {
+		out[k] = deepMerge(out[k], v)
+	}
+	return out
+}
+
+// clearSources forgets the origins recorded under a prefix
+func clearSources(sources map[string]Origin, prefix string) {
+	for path := range sources {
+		if strings.HasPrefix(path, prefix) {
+			delete(sources, path)
+		}
+	}
+}
+
+// FindProjectConfig returns the nearest .zesbe/config.json at or above dir,
+// or "" when there is none
+func FindProjectConfig(dir string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	for {
+		path := filepath.Join(dir, ".zesbe", "config.json")
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// Setting is one effective value and where it came from
+type Setting struct {
+	Name   string
+	Value  string
+	Origin Origin
+}
+
+// maxSettingValue caps the length of a value shown by Settings
+const maxSettingValue = 80
+
+// Settings lists the effective settings that have a value, sorted by name.
+// Entries of object settings such as tool_policies are listed one by one,
+// and API keys are masked.
+func (c *Config) Settings() []Setting {
+	values, err := toMap(c)
+	if err != nil {
+		return nil
+	}
+	maskSecrets(values)
+
+	var settings []Setting
+	add := func(name string, v interface{}, origin Origin) {
+		if isEmptyValue(v) {
+			return
+		}
+		settings = append(settings, Setting{Name: name, Value: compactValue(v), Origin: origin})
+	}
+	for k, v := range values {
+		obj, isObj := v.(map[string]interface{})
+		if !isObj {
+			add(k, v, c.origin(k))
+			continue
+		}
+		for ek, ev := range obj {
+			add(k+"."+ek, ev, c.origin(k+"."+ek))
+		}
+	}
+
+	project := Origin{Source: SourceProject, Location: c.ProjectFile}
+	for _, t := range c.ProjectTools {
+		add("custom_tools."+t.Name, t, project)
+	}
+	if hooks, err := toMap(c.ProjectHooks); err == nil {
+		for event, list := range hooks {
+			add("hooks."+event, list, project)
+		}
+	}
+
+	sort.SliceStable(settings, func(i, j int) bool { return settings[i].Name < settings[j].Name })
+	return settings
+}
+
+// origin returns where a setting came from, falling back to its parent
+// object's origin
+func (c *Config) origin(path string) Origin {
+	if o, ok := c.Sources[path]; ok {
+		return o
+	}
+	if i := strings.IndexByte(path, '.'); i > 0 {
+		if o, ok := c.Sources[path[:i]]; ok {
+			return o
+		}
+	}
+	return Origin{Source: SourceDefault}
+}
+
+// isEmptyValue reports whether a JSON value is unset
+func isEmptyValue(v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case []interface{}:
+		return len(v) == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+// compactValue renders a JSON value on one line
+func compactValue(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	s := string(data)
+	if r := []rune(s); len(r) > maxSettingValue {
+		s = string(r[:maxSettingValue-3]) + "..."
+	}
+	return s
+}
+
+// maskSecret keeps only the last four characters of a secret
+func maskSecret(s string) string {
+	if len(s) <= 8 {
+		return "****"
+	}
+	return "****" + s[len(s)-4:]
+}
+
+// providerEntries returns the providers entries of decoded settings
+func providerEntries(values map[string]interface{}) []map[string]interface{} {
+	providers, _ := values["providers"].(map[string]interface{})
+	var entries []map[string]interface{}
+	for _, p := range providers {
+		if entry, ok := p.(map[string]interface{}); ok {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// hasSecret reports whether decoded settings hold an API key, their own or
+// a provider's
+func hasSecret(values map[string]interface{}) bool {
+	if !isEmptyValue(values["api_key"]) {
+		return true
+	}
+	for _, entry := range providerEntries(values) {
+		if !isEmptyValue(entry["api_key"]) {
+			return true
+		}
+	}
+	return false
+}
+
+// stripSecrets removes the API keys from decoded settings
+func stripSecrets(values map[string]interface{}) {
+	delete(values, "api_key")
+	for _, entry := range providerEntries(values) {
+		delete(entry, "api_key")
+	}
+}
+
+// maskSecrets masks the API keys in decoded settings
+func maskSecrets(values map[string]interface{}) {
+	mask := func(obj map[string]interface{}) {
+		if s, ok := obj["api_key"].(string); ok && s != "" {
+			obj["api_key"] = maskSecret(s)
+		}
+	}
+	mask(values)
+	for _, entry := range providerEntries(values) {
+		mask(entry)
+	}
+}
@@ -0,0 +1,502 @@
+package config
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+//go:embed schema.json
+var schemaJSON []byte
+
+// Schema returns the JSON Schema that config files are checked against
+func Schema() []byte {
+	return schemaJSON
+}
+
+// Issue is a value in a config layer that breaks the schema
+type Issue struct {
+	Origin  Origin // The layer, with its file or variable
+	Line    int    // 1-based position in the file; 0 when there is none
+	Column  int
+	Key     string // The setting, e.g. providers.openai.base_url
+	Message string // e.g. "must be an http or https URL"
+
+	path []string
+}
+
+// String returns e.g. "/home/me/.zesbe-go/config.json:3:5: theme must be
+// one of ..."
+func (i Issue) String() string {
+	where := i.Origin.Location
+	if where == "" {
+		where = i.Origin.String()
+	}
+	if i.Line > 0 {
+		where = fmt.Sprintf("%s:%d:%d", where, i.Line, i.Column)
+	}
+	return fmt.Sprintf("%s: %s %s", where, i.Key, i.Message)
+}
+
+// ValidateFile checks a config file against the schema without loading
+// it. A file that isn't a JSON object is an error with its line and column.
+func ValidateFile(path string) ([]Issue, error) {
+	l, err := fileLayer(path, SourceUser)
+	if err != nil {
+		return nil, err
+	} else if l == nil {
+		return nil, fmt.Errorf("%s: no such file", path)
+	}
+	return l.issues, nil
+}
+
+// schema is the part of JSON Schema the config schema uses
+type schema struct {
+	Ref        string             `json:"$ref"`
+	Type       json.RawMessage    `json:"type"`
+	Properties map[string]*schema `json:"properties"`
+	Additional json.RawMessage    `json:"additionalProperties"`
+	Required   []string           `json:"required"`
+	Enum       []interface{}      `json:"enum"`
+	Minimum    *float64           `json:"minimum"`
+	Maximum    *float64           `json:"maximum"`
+	MinLength  int                `json:"minLength"`
+	MinItems   int                `json:"minItems"`
+	Items      *schema            `json:"items"`
+	Format     string             `json:"format"`
+	Defs       map[string]*schema `json:"$defs"`
+
+	types      []string // Type as a list
+	closed     bool     // additionalProperties is false
+	additional *schema  // additionalProperties as a schema
+}
+
+var (
+	rootSchema     *schema
+	rootSchemaOnce sync.Once
+)
+
+// loadSchema parses the embedded schema once
+func loadSchema() *schema {
+	rootSchemaOnce.Do(func() {
+		rootSchema = &schema{}
+		if err := json.Unmarshal(schemaJSON, rootSchema); err != nil {
+			panic("config: invalid schema.json: " + err.Error())
+		}
+		rootSchema.prepare()
+	})
+	return rootSchema
+}
+
+// prepare decodes the keywords that take more than one form
+func (s *schema) prepare() {
+	if s == nil {
+		return
+	}
+	var one string
+	if json.Unmarshal(s.Type, &one) == nil {
+		s.types = []string{one}
+	} else {
+		json.Unmarshal(s.Type, &s.types)
+	}
+	var allowed bool
+	if json.Unmarshal(s.Additional, &allowed) == nil {
+		s.closed = !allowed
+	} else if len(s.Additional) > 0 {
+		s.additional = &schema{}
+		json.Unmarshal(s.Additional, s.additional)
+	}
+	for _, children := range []map[string]*schema{s.Properties, s.Defs} {
+		for _, child := range children {
+			child.prepare()
+		}
+	}
+	s.additional.prepare()
+	s.Items.prepare()
+}
+
+// schemaError is a value that breaks the schema
+type schemaError struct {
+	path    []string
+	message string
+}
+
+// validateValues checks decoded settings against the schema
+func validateValues(values map[string]interface{}) []schemaError {
+	var errs []schemaError
+	root := loadSchema()
+	root.validate(root, values, nil, &errs)
+	return errs
+}
+
+// validate checks v, found at path, and collects what is wrong with it
+func (s *schema) validate(root *schema, v interface{}, path []string, errs *[]schemaError) {
+	fail := func(format string, args ...interface{}) {
+		*errs = append(*errs, schemaError{path: path, message: fmt.Sprintf(format, args...)})
+	}
+	if s.Ref != "" {
+		name := strings.TrimPrefix(s.Ref, "#/$defs/")
+		if def, ok := root.Defs[name]; ok {
+			def.validate(root, v, path, errs)
+		}
+		return
+	}
+
+	if len(s.types) > 0 {
+		matched := false
+		for _, t := range s.types {
+			matched = matched || hasType(v, t)
+		}
+		if !matched {
+			fail("must be %s, got %s", typeNames(s.types), describeValue(v))
+			return
+		}
+	}
+	if len(s.Enum) > 0 {
+		for _, e := range s.Enum {
+			if e == v {
+				return
+			}
+		}
+		choices := make([]string, len(s.Enum))
+		for i, e := range s.Enum {
+			choices[i] = fmt.Sprintf("%q", e)
+		}
+		fail("must be one of %s, got %s", strings.Join(choices, ", "), describeValue(v))
+		return
+	}
+
+	switch v := v.(type) {
+	case string:
+		if n := utf8.RuneCountInString(v); n < s.MinLength {
+			if s.MinLength == 1 {
+				fail("must not be empty")
+			} else {
+				fail("must be at least %d characters", s.MinLength)
+			}
+			return
+		}
+		if err := checkFormat(s.Format, v); err != nil {
+			fail("%v", err)
+		}
+
+	case json.Number, float64:
+		n, _ := strconv.ParseFloat(fmt.Sprint(v), 64)
+		switch {
+		case s.Minimum != nil && s.Maximum != nil && (n < *s.Minimum || n > *s.Maximum):
+			fail("must be between %g and %g, got %v", *s.Minimum, *s.Maximum, v)
+		case s.Minimum != nil && n < *s.Minimum:
+			fail("must be at least %g, got %v", *s.Minimum, v)
+		case s.Maximum != nil && n > *s.Maximum:
+			fail("must be at most %g, got %v", *s.Maximum, v)
+		}
+
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				fail("needs a %q", name)
+			}
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			child := append(path[:len(path):len(path)], k)
+			switch {
+			case s.Properties[k] != nil:
+				s.Properties[k].validate(root, v[k], child, errs)
+			case s.additional != nil:
+				s.additional.validate(root, v[k], child, errs)
+			case s.closed:
+				message := "is not a setting"
+				if len(path) > 0 {
+					message = "is not a field of " + formatKey(path)
+				}
+				if guess := closest(k, s.Properties); guess != "" {
+					message += fmt.Sprintf(" (did you mean %q?)", guess)
+				}
+				*errs = append(*errs, schemaError{path: child, message: message})
+			}
+		}
+
+	case []interface{}:
+		if len(v) < s.MinItems {
+			fail("must not be empty")
+			return
+		}
+		if s.Items != nil {
+			for i, item := range v {
+				s.Items.validate(root, item, append(path[:len(path):len(path)], fmt.Sprintf("[%d]", i)), errs)
+			}
+		}
+	}
+}
+
+// hasType reports whether a decoded JSON value is of a schema type
+func hasType(v interface{}, t string) bool {
+	switch v := v.(type) {
+	case nil:
+		return t == "null"
+	case bool:
+		return t == "boolean"
+	case string:
+		return t == "string"
+	case json.Number:
+		if t == "integer" {
+			_, err := v.Int64()
+			return err == nil
+		}
+		return t == "number"
+	case float64:
+		return t == "number" || (t == "integer" && v == float64(int64(v)))
+	case map[string]interface{}:
+		return t == "object"
+	case []interface{}:
+		return t == "array"
+	}
+	return false
+}
+
+// typeNames describes schema types, e.g. "a string or null"
+func typeNames(types []string) string {
+	names := map[string]string{
+		"string":  "a string",
+		"integer": "a whole number",
+		"number":  "a number",
+		"boolean": "true or false",
+		"object":  "an object",
+		"array":   "a list",
+		"null":    "null",
+	}
+	described := make([]string, len(types))
+	for i, t := range types {
+		described[i] = names[t]
+	}
+	return strings.Join(described, " or ")
+}
+
+// describeValue shows a value in a message
+func describeValue(v interface{}) string {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return "an object"
+	case []interface{}:
+		return "a list"
+	case nil:
+		return "null"
+	case string:
+		return strconv.Quote(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// checkFormat checks the formats the schema uses
+func checkFormat(format, s string) error {
+	switch format {
+	case "http-url":
+		if s == "" {
+			return nil // Use the provider's
+		}
+		u, err := url.Parse(s)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("must be an http or https URL, got %q", s)
+		}
+	case "duration":
+		// As tools.ParseTimeout reads it: seconds or a Go duration
+		d, err := time.ParseDuration(s)
+		if secs, numErr := strconv.ParseFloat(s, 64); numErr == nil {
+			d, err = time.Duration(secs*float64(time.Second)), nil
+		}
+		if err != nil {
+			return fmt.Errorf("must be a duration such as \"30s\" or \"2m\", got %q", s)
+		}
+		if d <= 0 {
+			return fmt.Errorf("must be positive, got %q", s)
+		}
+	}
+	return nil
+}
+
+// closest returns the property name a misspelt key was most likely meant
+// to be, or ""
+func closest(key string, properties map[string]*schema) string {
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	best, bestDistance := "", min(2, len(key)/3+1)+1
+	for _, name := range names {
+		if d := editDistance(strings.ToLower(key), name); d < bestDistance {
+			best, bestDistance = name, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between two strings
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur := make([]int, len(rb)+1)
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(rb)]
+}
+
+// plainKey matches keys that need no quoting in a setting name
+var plainKey = regexp.MustCompile(`^[A-Za-z0-9_*-]+$`)
+
+// formatKey joins a path into a setting name such as
+// custom_tools[0].command or file_hooks[".go"]
+func formatKey(path []string) string {
+	var b strings.Builder
+	for _, part := range path {
+		switch {
+		case strings.HasPrefix(part, "[") && strings.HasSuffix(part, "]"):
+			b.WriteString(part)
+		case plainKey.MatchString(part):
+			if b.Len() > 0 {
+				b.WriteByte('.')
+			}
+			b.WriteString(part)
+		default:
+			fmt.Fprintf(&b, "[%q]", part)
+		}
+	}
+	return b.String()
+}
+
+// keyPositions maps each key and array element of a JSON document, by path,
+// to its byte offset, for pointing issues at the right line
+func keyPositions(data []byte) map[string]int64 {
+	positions := make(map[string]int64)
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	// start is where the next token begins, past separators
+	start := func() int64 {
+		offset := dec.InputOffset()
+		for offset < int64(len(data)) && strings.IndexByte(" \t\r\n,:", data[offset]) >= 0 {
+			offset++
+		}
+		return offset
+	}
+	var walk func(path []string) bool
+	walk = func(path []string) bool {
+		tok, err := dec.Token()
+		if err != nil {
+			return false
+		}
+		switch tok {
+		case json.Delim('{'):
+			for dec.More() {
+				offset := start()
+				key, err := dec.Token()
+				if err != nil {
+					return false
+				}
+				child := append(path[:len(path):len(path)], fmt.Sprint(key))
+				positions[strings.Join(child, "\x00")] = offset
+				if !walk(child) {
+					return false
+				}
+			}
+			_, err = dec.Token()
+		case json.Delim('['):
+			for i := 0; dec.More(); i++ {
+				child := append(path[:len(path):len(path)], fmt.Sprintf("[%d]", i))
+				positions[strings.Join(child, "\x00")] = start()
+				if !walk(child) {
+					return false
+				}
+			}
+			_, err = dec.Token()
+		}
+		return err == nil
+	}
+	walk(nil)
+	return positions
+}
+
+// lineColumn turns a byte offset into a 1-based line and column, counting
+// columns in characters as editors do
+func lineColumn(data []byte, offset int64) (int, int) {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	before := data[:offset]
+	lineStart := bytes.LastIndexByte(before, '\n') + 1
+	return bytes.Count(before, []byte("\n")) + 1, utf8.RuneCount(before[lineStart:]) + 1
+}
+
+// validateLayer checks a layer against the schema and drops what breaks
+// it: the setting, or for object settings the entry. data is the file the
+// layer came from, if any, for line and column numbers.
+func validateLayer(l *layer, data []byte) []Issue {
+	errs := validateValues(l.values)
+	if len(errs) == 0 {
+		return nil
+	}
+	var positions map[string]int64
+	if data != nil {
+		positions = keyPositions(data)
+	}
+
+	issues := make([]Issue, 0, len(errs))
+	for _, e := range errs {
+		issue := Issue{Origin: l.origin, Key: formatKey(e.path), Message: e.message, path: e.path}
+		if len(e.path) == 0 {
+			issue.Key = "the config"
+		}
+		// The nearest enclosing key with a known position
+		for n := len(e.path); n > 0 && positions != nil; n-- {
+			if offset, ok := positions[strings.Join(e.path[:n], "\x00")]; ok {
+				issue.Line, issue.Column = lineColumn(data, offset)
+				break
+			}
+		}
+		issues = append(issues, issue)
+
+		if len(e.path) == 0 {
+			continue
+		}
+		if obj, ok := l.values[e.path[0]].(map[string]interface{}); ok && len(e.path) > 1 {
+			delete(obj, e.path[1])
+		} else {
+			delete(l.values, e.path[0])
+		}
+	}
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].Line != issues[j].Line {
+			return issues[i].Line < issues[j].Line
+		}
+		return issues[i].Column < issues[j].Column
+	})
+	return issues
+}
@@ -0,0 +1,115 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// validateText writes a config file and validates it
+func validateText(t *testing.T, text string) []Issue {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(text), 0600); err != nil {
+		t.Fatal(err)
+	}
+	issues, err := ValidateFile(path)
+	if err != nil {
+		t.Fatalf("ValidateFile: %v", err)
+	}
+	return issues
+}
+
+func TestValidateFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		key     string
+		message string
+	}{
+		{"valid", `{"theme": "dark", "word_wrap": 80}`, "", ""},
+		{"wrong type", `{"yolo": "yes"}`, "yolo", `must be true or false, got "yes"`},
+		{"fraction for integer", `{"word_wrap": 80.5}`, "word_wrap", "must be a whole number, got 80.5"},
+		{"out of range", `{"word_wrap": 20}`, "word_wrap", "must be between 40 and 400, got 20"},
+		{"enum", `{"theme": "neon"}`, "theme", "must be one of"},
+		{"unknown key", `{"thme": "dark"}`, "thme", `is not a setting (did you mean "theme"?)`},
+		{"nested wrong type", `{"providers": {"openai": {"model": 4}}}`, "providers.openai.model", "must be a string, got 4"},
+		{"nested unknown key", `{"git": {"protect": true}}`, "git.protect", "is not a field of git"},
+		{"new provider needs fields", `{"providers": {"mine": {"model": "m"}}}`, "providers.mine", `needs a "base_url"`},
+		{"bad url", `{"base_url": "ftp://x"}`, "base_url", "must be an http or https URL"},
+		{"bad duration", `{"tool_policies": {"*": {"timeout": "soon"}}}`, "tool_policies.*.timeout", "must be a duration"},
+		{"list item", `{"custom_tools": [{"name": "a", "command": []}]}`, "custom_tools[0].command", "must not be empty"},
+		{"quoted key", `{"file_hooks": {".go": [{"command": "gofmt"}]}}`, `file_hooks[".go"][0].command`, "must be a list"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues := validateText(t, tt.text)
+			if tt.key == "" {
+				if len(issues) != 0 {
+					t.Errorf("issues = %v, want none", issues)
+				}
+				return
+			}
+			if len(issues) != 1 {
+				t.Fatalf("issues = %v, want one", issues)
+			}
+			if issues[0].Key != tt.key || !strings.Contains(issues[0].Message, tt.message) {
+				t.Errorf("issue = %s %s, want %s %s", issues[0].Key, issues[0].Message, tt.key, tt.message)
+			}
+		})
+	}
+}
+
+func TestValidateFilePositions(t *testing.T) {
+	tests := []struct {
+		name         string
+		text         string
+		line, column int
+	}{
+		{"first line", `{"theme": 1}`, 1, 2},
+		{"indented", "{\n  \"yolo\": true,\n    \"theme\": 1\n}", 3, 5},
+		{"crlf", "{\r\n  \"yolo\": true,\r\n  \"theme\": 1\r\n}", 3, 3},
+		{"multi-byte before", "{\"model\": \"模型\", \"theme\": 1}", 1, 17},
+		{"nested", "{\n  \"providers\": {\n    \"openai\": {\"model\": 4}\n  }\n}", 3, 16},
+		{"list item", "{\"custom_tools\": [\n  {\"name\": \"a\", \"command\": [\"x\"]},\n  {\"name\": \"é\", \"command\": []}\n]}", 3, 17},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues := validateText(t, tt.text)
+			if len(issues) != 1 {
+				t.Fatalf("issues = %v, want one", issues)
+			}
+			if issues[0].Line != tt.line || issues[0].Column != tt.column {
+				t.Errorf("position = %d:%d, want %d:%d", issues[0].Line, issues[0].Column, tt.line, tt.column)
+			}
+		})
+	}
+}
+
+func TestValidateFileSyntaxError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte("{\r\n  \"théme\": \"dark\",\r\n}"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	_, err := ValidateFile(path)
+	if err == nil || !strings.Contains(err.Error(), path+":3:1:") {
+		t.Errorf("err = %v, want it at 3:1", err)
+	}
+}
+
+func TestLoadReportsEachIssueOnce(t *testing.T) {
+	cfg := loadIn(t, `{"word_wrap": "wide", "theme": "light"}`, `{"git": {"allow_force_push": "yes"}}`)
+	if len(cfg.Issues) != 2 {
+		t.Errorf("issues = %v, want word_wrap and git.allow_force_push", cfg.Issues)
+	}
+	for _, p := range cfg.Warnings {
+		if strings.Contains(p, "word_wrap") || strings.Contains(p, "allow_force_push") {
+			t.Errorf("issue reported again as a warning: %s", p)
+		}
+	}
+	// The bad values are dropped and the rest of the file still applies
+	if cfg.WordWrap != 100 || cfg.Theme != "light" || cfg.Git.AllowForcePush {
+		t.Errorf("word_wrap %d, theme %s, allow_force_push %v", cfg.WordWrap, cfg.Theme, cfg.Git.AllowForcePush)
+	}
+}
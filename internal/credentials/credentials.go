@@ -0,0 +1,115 @@
+// Package credentials keeps provider API keys out of config files. Keys go
+// in the system keyring when there is one, the Secret Service on Linux or
+// the login keychain on macOS, and otherwise in a file encrypted with a
+// passphrase.
+package credentials
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Backends the credential_store setting accepts
+const (
+	BackendAuto    = "auto"
+	BackendKeyring = "keyring"
+	BackendFile    = "file"
+)
+
+// Service is the name keys are stored under in the keyring
+const Service = "zesbe-go"
+
+// FileName is the encrypted file in the config directory
+const FileName = "credentials.enc"
+
+// PassphraseEnv supplies the encrypted file's passphrase without a prompt
+const PassphraseEnv = "ZESBE_PASSPHRASE"
+
+var (
+	// ErrNotFound means the store holds no key for the provider
+	ErrNotFound = errors.New("no stored key")
+	// ErrLocked means the encrypted file can't be opened without a
+	// passphrase
+	ErrLocked = errors.New("the credentials file is locked; set " + PassphraseEnv + " or run in a terminal")
+	// ErrNoKeyring means there is no keyring to use
+	ErrNoKeyring = errors.New("no system keyring found: install secret-tool (libsecret) or use the file store")
+)
+
+// Store holds API keys by provider name
+type Store interface {
+	// Name describes the store, e.g. "Secret Service keyring"
+	Name() string
+	// Get returns the provider's key or ErrNotFound
+	Get(provider string) (string, error)
+	// Set stores the provider's key, replacing any other
+	Set(provider, key string) error
+	// Delete removes the provider's key or returns ErrNotFound
+	Delete(provider string) error
+}
+
+// Open returns the store for a backend, keeping the encrypted file in dir.
+// "auto" or "" uses the encrypted file when it exists, then the system
+// keyring when there is one, then a new encrypted file.
+func Open(backend, dir string) (Store, error) {
+	path := filepath.Join(dir, FileName)
+	switch backend {
+	case "", BackendAuto:
+		if _, err := os.Stat(path); err == nil {
+			return NewFileStore(path), nil
+		}
+		if k := systemKeyring(); k != nil {
+			return k, nil
+		}
+		return NewFileStore(path), nil
+	case BackendKeyring:
+		if k := systemKeyring(); k != nil {
+			return k, nil
+		}
+		return nil, ErrNoKeyring
+	case BackendFile:
+		return NewFileStore(path), nil
+	default:
+		return nil, fmt.Errorf("unknown credential store %q: want %s, %s or %s", backend, BackendAuto, BackendKeyring, BackendFile)
+	}
+}
+
+// PromptFunc asks for the encrypted file's passphrase. confirm is true when
+// the file is about to be created, so the passphrase should be asked twice.
+type PromptFunc func(confirm bool) (string, error)
+
+var (
+	prompt   PromptFunc
+	promptMu sync.Mutex
+)
+
+// SetPrompt sets how the passphrase is asked for when PassphraseEnv isn't
+// set. With none, the default, the file stays locked.
+func SetPrompt(f PromptFunc) {
+	promptMu.Lock()
+	defer promptMu.Unlock()
+	prompt = f
+}
+
+// passphrase returns the passphrase from the environment or the prompt
+func passphrase(confirm bool) (string, error) {
+	if p := os.Getenv(PassphraseEnv); p != "" {
+		return p, nil
+	}
+	promptMu.Lock()
+	f := prompt
+	promptMu.Unlock()
+	if f == nil {
+		return "", ErrLocked
+	}
+	p, err := f(confirm)
+	if err != nil {
+		return "", err
+	}
+	if p == "" {
+		return "", errors.New("empty passphrase")
+	}
+	return p, nil
+}
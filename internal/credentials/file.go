@@ -0,0 +1,232 @@
+package credentials
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// scrypt cost parameters for new files. Stored files carry their own, up
+// to maxScryptN.
+const (
+	scryptN    = 1 << 15
+	scryptR    = 8
+	scryptP    = 1
+	maxScryptN = 1 << 20
+)
+
+// fileVersion is the format of the encrypted file
+const fileVersion = 1
+
+// envelope is the encrypted file: the scrypt parameters and salt that turn
+// the passphrase into an AES-256-GCM key, and the sealed JSON object of keys
+type envelope struct {
+	Version int    `json:"version"`
+	KDF     string `json:"kdf"`
+	N       int    `json:"n"`
+	R       int    `json:"r"`
+	P       int    `json:"p"`
+	Salt    []byte `json:"salt"`
+	Nonce   []byte `json:"nonce"`
+	Data    []byte `json:"data"`
+}
+
+// FileStore keeps keys in a file encrypted with a passphrase, for systems
+// without a keyring. The file is decrypted once and its contents kept in
+// memory.
+type FileStore struct {
+	path       string
+	mu         sync.Mutex
+	passphrase string
+	keys       map[string]string // Nil until unlocked
+}
+
+// NewFileStore returns the store for an encrypted file, which need not
+// exist yet
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path: path}
+}
+
+// Name describes the store
+func (f *FileStore) Name() string {
+	return "encrypted file " + f.path
+}
+
+// Exists reports whether the encrypted file has been created
+func (f *FileStore) Exists() bool {
+	_, err := os.Stat(f.path)
+	return err == nil
+}
+
+// Unlock decrypts the file, asking for the passphrase when needed, so
+// later calls don't have to
+func (f *FileStore) Unlock() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.unlock()
+}
+
+// Get returns the provider's key
+func (f *FileStore) Get(provider string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.unlock(); err != nil {
+		return "", err
+	}
+	key, ok := f.keys[provider]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return key, nil
+}
+
+// Set stores the provider's key, creating the file when needed
+func (f *FileStore) Set(provider, key string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.unlock(); err != nil {
+		return err
+	}
+	f.keys[provider] = key
+	return f.save()
+}
+
+// Delete removes the provider's key
+func (f *FileStore) Delete(provider string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.unlock(); err != nil {
+		return err
+	}
+	if _, ok := f.keys[provider]; !ok {
+		return ErrNotFound
+	}
+	delete(f.keys, provider)
+	return f.save()
+}
+
+// unlock reads and decrypts the file. A missing file is an empty store
+// whose passphrase is asked for when it is first saved.
+func (f *FileStore) unlock() error {
+	if f.keys != nil {
+		return nil
+	}
+	data, err := os.ReadFile(f.path)
+	if os.IsNotExist(err) {
+		f.keys = make(map[string]string)
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	var env envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return fmt.Errorf("%s: %w", f.path, err)
+	}
+	if env.Version != fileVersion || env.KDF != "scrypt" {
+		return fmt.Errorf("%s: unsupported format version %d (%s)", f.path, env.Version, env.KDF)
+	}
+	if env.N < 2 || env.N > maxScryptN || env.R < 1 || env.R > 32 || env.P < 1 || env.P > 16 {
+		return fmt.Errorf("%s: unsupported scrypt parameters", f.path)
+	}
+
+	pass, err := passphrase(false)
+	if err != nil {
+		return err
+	}
+	gcm, err := newGCM(pass, env.Salt, env.N, env.R, env.P)
+	if err != nil {
+		return err
+	}
+	if len(env.Nonce) != gcm.NonceSize() {
+		return fmt.Errorf("%s: damaged file", f.path)
+	}
+	plain, err := gcm.Open(nil, env.Nonce, env.Data, nil)
+	if err != nil {
+		return fmt.Errorf("%s: wrong passphrase or damaged file", f.path)
+	}
+	keys := make(map[string]string)
+	if err := json.Unmarshal(plain, &keys); err != nil {
+		return fmt.Errorf("%s: damaged file", f.path)
+	}
+	f.passphrase = pass
+	f.keys = keys
+	return nil
+}
+
+// save encrypts the keys with a fresh salt and nonce and replaces the file
+func (f *FileStore) save() error {
+	if f.passphrase == "" {
+		pass, err := passphrase(true)
+		if err != nil {
+			return err
+		}
+		f.passphrase = pass
+	}
+
+	plain, err := json.Marshal(f.keys)
+	if err != nil {
+		return err
+	}
+	env := envelope{Version: fileVersion, KDF: "scrypt", N: scryptN, R: scryptR, P: scryptP, Salt: make([]byte, 16)}
+	if _, err := rand.Read(env.Salt); err != nil {
+		return err
+	}
+	gcm, err := newGCM(f.passphrase, env.Salt, env.N, env.R, env.P)
+	if err != nil {
+		return err
+	}
+	env.Nonce = make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(env.Nonce); err != nil {
+		return err
+	}
+	env.Data = gcm.Seal(nil, env.Nonce, plain, nil)
+
+	data, err := json.MarshalIndent(env, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(f.path), 0700); err != nil {
+		return err
+	}
+	// Write a temporary file and rename it so a failed write can't lose
+	// the keys
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), ".credentials-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	// CreateTemp already makes the file 0600
+	return os.Rename(tmp.Name(), f.path)
+}
+
+// newGCM derives the file key from the passphrase
+func newGCM(pass string, salt []byte, n, r, p int) (cipher.AEAD, error) {
+	if len(salt) == 0 {
+		return nil, errors.New("missing salt")
+	}
+	key, err := scrypt.Key([]byte(pass), salt, n, r, p, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,112 @@
+package credentials
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// noPrompt fails the test if the passphrase is asked for
+func noPrompt(t *testing.T) {
+	t.Helper()
+	SetPrompt(func(bool) (string, error) {
+		t.Error("asked for the passphrase")
+		return "", errors.New("no prompt in this test")
+	})
+	t.Cleanup(func() { SetPrompt(nil) })
+}
+
+func TestFileStoreRoundTrip(t *testing.T) {
+	t.Setenv(PassphraseEnv, "correct horse")
+	noPrompt(t)
+	path := filepath.Join(t.TempDir(), "config", FileName)
+
+	store := NewFileStore(path)
+	if store.Exists() {
+		t.Fatal("file exists before the first key is set")
+	}
+	if err := store.Set("openai", "sk-one"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set("anthropic", "sk-two"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete("anthropic"); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "sk-one") {
+		t.Error("the key is stored in plain text")
+	}
+	if info, err := os.Stat(path); err == nil && runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Errorf("file mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	// A new store decrypts what the first one wrote
+	reopened := NewFileStore(path)
+	if key, err := reopened.Get("openai"); err != nil || key != "sk-one" {
+		t.Errorf("Get(openai) = %q, %v, want sk-one", key, err)
+	}
+	if _, err := reopened.Get("anthropic"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get(anthropic) = %v, want ErrNotFound after Delete", err)
+	}
+}
+
+func TestFileStoreWrongPassphrase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	t.Setenv(PassphraseEnv, "right")
+	if err := NewFileStore(path).Set("openai", "sk-one"); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv(PassphraseEnv, "wrong")
+	_, err := NewFileStore(path).Get("openai")
+	if err == nil || !strings.Contains(err.Error(), "wrong passphrase") {
+		t.Errorf("Get = %v, want a wrong passphrase error", err)
+	}
+}
+
+func TestFileStoreLockedWithoutPassphrase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	t.Setenv(PassphraseEnv, "right")
+	if err := NewFileStore(path).Set("openai", "sk-one"); err != nil {
+		t.Fatal(err)
+	}
+
+	// No passphrase in the environment and no prompt set, as for the
+	// commands that never call a provider
+	t.Setenv(PassphraseEnv, "")
+	if _, err := NewFileStore(path).Get("openai"); !errors.Is(err, ErrLocked) {
+		t.Errorf("Get = %v, want ErrLocked", err)
+	}
+
+	// With a prompt, it is asked once for the file and the answer kept
+	asked := 0
+	SetPrompt(func(confirm bool) (string, error) {
+		asked++
+		if confirm {
+			t.Error("asked to confirm the passphrase of an existing file")
+		}
+		return "right", nil
+	})
+	defer SetPrompt(nil)
+	store := NewFileStore(path)
+	for range 2 {
+		if key, err := store.Get("openai"); err != nil || key != "sk-one" {
+			t.Errorf("Get = %q, %v, want sk-one", key, err)
+		}
+	}
+	if err := store.Set("openai", "sk-three"); err != nil {
+		t.Fatal(err)
+	}
+	if asked != 1 {
+		t.Errorf("asked for the passphrase %d times, want once", asked)
+	}
+}
@@ -0,0 +1,153 @@
+package credentials
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// keyringTimeout leaves time to answer a keyring unlock dialog
+const keyringTimeout = time.Minute
+
+// macNotFound is the exit status security uses for a missing item
+const macNotFound = 44
+
+// keyring stores keys with the platform's keyring tool: secret-tool from
+// libsecret, which talks to the Secret Service, or macOS's security
+type keyring struct {
+	name string
+	tool string
+	mac  bool
+}
+
+// systemKeyring returns the keyring of this system, or nil when there is
+// none to use
+func systemKeyring() *keyring {
+	switch runtime.GOOS {
+	case "darwin":
+		if path, err := exec.LookPath("security"); err == nil {
+			return &keyring{name: "macOS keychain", tool: path, mac: true}
+		}
+	case "linux", "freebsd", "openbsd", "netbsd":
+		// The Secret Service is only reachable over a session bus
+		bus := os.Getenv("DBUS_SESSION_BUS_ADDRESS") != ""
+		if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); !bus && runtimeDir != "" {
+			_, err := os.Stat(filepath.Join(runtimeDir, "bus"))
+			bus = err == nil
+		}
+		if !bus {
+			return nil
+		}
+		if path, err := exec.LookPath("secret-tool"); err == nil {
+			return &keyring{name: "Secret Service keyring", tool: path}
+		}
+	}
+	return nil
+}
+
+// Name describes the keyring
+func (k *keyring) Name() string {
+	return k.name
+}
+
+// Get looks up the provider's key
+func (k *keyring) Get(provider string) (string, error) {
+	var out []byte
+	var code int
+	var err error
+	if k.mac {
+		out, code, err = k.run("", "find-generic-password", "-s", Service, "-a", provider, "-w")
+		if code == macNotFound {
+			return "", ErrNotFound
+		}
+	} else {
+		out, code, err = k.run("", "lookup", "service", Service, "account", provider)
+		// lookup fails silently when there is no such item
+		if code == 1 && err == nil {
+			return "", ErrNotFound
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+	key := strings.TrimRight(string(out), "\r\n")
+	if key == "" {
+		return "", ErrNotFound
+	}
+	return key, nil
+}
+
+// Set stores the provider's key. The key goes through stdin so it never
+// shows up in the process list.
+func (k *keyring) Set(provider, key string) error {
+	label := fmt.Sprintf("%s API key for %s", Service, provider)
+	if k.mac {
+		// security -i reads commands from stdin
+		quote := func(s string) string {
+			return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+		}
+		command := fmt.Sprintf("add-generic-password -U -s %s -a %s -l %s -w %s\n",
+			quote(Service), quote(provider), quote(label), quote(key))
+		return k.check(k.run(command, "-i"))
+	}
+	return k.check(k.run(key, "store", "--label", label, "service", Service, "account", provider))
+}
+
+// Delete removes the provider's key
+func (k *keyring) Delete(provider string) error {
+	if k.mac {
+		out, code, err := k.run("", "delete-generic-password", "-s", Service, "-a", provider)
+		if code == macNotFound {
+			return ErrNotFound
+		}
+		return k.check(out, code, err)
+	}
+	// clear succeeds whether or not there was a key
+	if _, err := k.Get(provider); err != nil {
+		return err
+	}
+	return k.check(k.run("", "clear", "service", Service, "account", provider))
+}
+
+// check turns a run that exited non-zero without saying why into an error
+func (k *keyring) check(_ []byte, code int, err error) error {
+	if err == nil && code != 0 {
+		return fmt.Errorf("%s exited with status %d", filepath.Base(k.tool), code)
+	}
+	return err
+}
+
+// run runs the keyring tool with stdin as its input. A non-zero exit
+// status is returned as the code, and as an error when the tool said why.
+func (k *keyring) run(stdin string, args ...string) ([]byte, int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), keyringTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, k.tool, args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && ctx.Err() == nil {
+		code := exitErr.ExitCode()
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, code, fmt.Errorf("%s: %s", filepath.Base(k.tool), msg)
+		}
+		return nil, code, nil
+	} else if ctx.Err() != nil {
+		return nil, -1, fmt.Errorf("%s timed out", filepath.Base(k.tool))
+	} else if err != nil {
+		return nil, -1, err
+	}
+	return stdout.Bytes(), 0, nil
+}
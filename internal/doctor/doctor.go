@@ -0,0 +1,427 @@
+// Package doctor checks the installation and configuration and gathers the
+// problems in one report, so they don't have to be dug out of the logs.
+package doctor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"golang.org/x/term"
+
+	"github.com/zesbe/zesbe-go/internal/config"
+	"github.com/zesbe/zesbe-go/internal/credentials"
+	"github.com/zesbe/zesbe-go/internal/logger"
+	"github.com/zesbe/zesbe-go/internal/session"
+)
+
+// Check statuses, from best to worst
+const (
+	StatusOK   = "ok"
+	StatusInfo = "info"
+	StatusWarn = "warn"
+	StatusFail = "fail"
+)
+
+// Check categories, in report order
+const (
+	CategoryConfig    = "config"
+	CategoryProviders = "providers"
+	CategoryNetwork   = "network"
+	CategoryStorage   = "storage"
+	CategoryPrograms  = "programs"
+	CategoryTerminal  = "terminal"
+)
+
+// categoryTitles are the report headings
+var categoryTitles = []struct {
+	category string
+	title    string
+}{
+	{CategoryConfig, "Config"},
+	{CategoryProviders, "API keys"},
+	{CategoryNetwork, "Network"},
+	{CategoryStorage, "Storage"},
+	{CategoryPrograms, "Programs"},
+	{CategoryTerminal, "Terminal"},
+}
+
+// DefaultTimeout limits each network request and the database lock wait
+const DefaultTimeout = 5 * time.Second
+
+// DefaultPrograms are the helper programs looked up in PATH
+var DefaultPrograms = []string{"git", "curl", "rg"}
+
+// Check is the outcome of one diagnostic
+type Check struct {
+	Category string `json:"category"`
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	Detail   string `json:"detail"`
+}
+
+// Report is the outcome of every check
+type Report struct {
+	Version string    `json:"version"`
+	Time    time.Time `json:"time"`
+	OS      string    `json:"os"`
+	Arch    string    `json:"arch"`
+	Go      string    `json:"go"`
+	Checks  []Check   `json:"checks"`
+}
+
+// Options tunes what Run checks
+type Options struct {
+	Version  string
+	Offline  bool          // Skip the network checks
+	Timeout  time.Duration // Per request and for the database lock; defaults to DefaultTimeout
+	Client   *http.Client  // For the reachability checks; defaults to http.DefaultClient
+	DataDir  string        // Session database directory; defaults to the store's
+	LogDir   string        // Defaults to the logger's directory
+	Programs []string      // Looked up in PATH; defaults to DefaultPrograms
+}
+
+// Run runs every check against cfg
+func Run(ctx context.Context, cfg *config.Config, opts Options) *Report {
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+	if opts.LogDir == "" {
+		opts.LogDir = filepath.Dir(logger.DefaultConfig().FilePath)
+	}
+	if opts.Programs == nil {
+		opts.Programs = DefaultPrograms
+	}
+
+	r := &Report{
+		Version: opts.Version,
+		Time:    time.Now(),
+		OS:      runtime.GOOS,
+		Arch:    runtime.GOARCH,
+		Go:      runtime.Version(),
+	}
+	r.checkConfig(cfg)
+	r.checkKeys(cfg)
+	if opts.Offline {
+		r.add(CategoryNetwork, "providers", StatusInfo, "skipped (offline)")
+	} else {
+		r.checkNetwork(ctx, cfg, opts)
+	}
+	r.checkSessions(opts)
+	r.checkLogDir(opts.LogDir)
+	r.checkPrograms(opts.Programs)
+	r.checkTerminal()
+	return r
+}
+
+// add records a check
+func (r *Report) add(category, name, status, detail string) {
+	r.Checks = append(r.Checks, Check{Category: category, Name: name, Status: status, Detail: detail})
+}
+
+// checkConfig reports the config files and any settings that were ignored
+func (r *Report) checkConfig(cfg *config.Config) {
+	for _, f := range cfg.Files {
+		name := f.Source + " config"
+		switch {
+		case f.Err != nil:
+			r.add(CategoryConfig, name, StatusFail, fmt.Sprintf("ignored: %v", f.Err))
+		case f.Found:
+			r.add(CategoryConfig, name, StatusOK, f.Path)
+		case f.Source == config.SourceUser:
+			r.add(CategoryConfig, name, StatusInfo, fmt.Sprintf("%s not found, using defaults", f.Path))
+		default:
+			r.add(CategoryConfig, name, StatusInfo, "none found")
+		}
+	}
+	for _, issue := range cfg.Issues {
+		r.add(CategoryConfig, "setting", StatusFail, issue.String()+"; ignored")
+	}
+	for _, w := range cfg.Warnings {
+		r.add(CategoryConfig, "setting", StatusWarn, w)
+	}
+}
+
+// checkKeys reports where API keys are stored and which providers have
+// one. Only a missing key for the provider in use is a failure; keys in
+// plain-text files are warnings.
+func (r *Report) checkKeys(cfg *config.Config) {
+	locked := false
+	switch store := config.Credentials().(type) {
+	case nil:
+		// Load has already warned about credential_store
+	case *credentials.FileStore:
+		// doctor never asks for the passphrase
+		if !store.Exists() {
+			r.add(CategoryProviders, "credential store", StatusInfo, store.Name()+", not created yet")
+		} else if err := store.Unlock(); errors.Is(err, credentials.ErrLocked) {
+			locked = true
+			r.add(CategoryProviders, "credential store", StatusInfo, fmt.Sprintf("%s is locked; set %s to check the keys in it", store.Name(), credentials.PassphraseEnv))
+		} else if err != nil {
+			r.add(CategoryProviders, "credential store", StatusWarn, err.Error())
+		} else {
+			r.add(CategoryProviders, "credential store", StatusOK, store.Name())
+		}
+	default:
+		r.add(CategoryProviders, "credential store", StatusOK, store.Name())
+	}
+
+	for _, name := range providerNames(cfg) {
+		key, origin := cfg.ProviderKey(name)
+		switch {
+		case key != "" && origin.Location == config.GetAPIKeyPath(name):
+			r.add(CategoryProviders, name, StatusWarn, fmt.Sprintf("from the plain-text file %s; move it with `zesbe-go auth login %s`", origin.Location, name))
+		case key != "":
+			r.add(CategoryProviders, name, StatusOK, "from "+origin.String())
+		case locked && name == cfg.Provider:
+			r.add(CategoryProviders, name, StatusWarn, "no API key outside the locked credentials file")
+		case locked:
+			r.add(CategoryProviders, name, StatusInfo, "no API key outside the locked credentials file")
+		case name == cfg.Provider:
+			r.add(CategoryProviders, name, StatusFail, fmt.Sprintf("no API key for the current provider; run `zesbe-go auth login %s` or set %s", name, config.GetAPIKeyEnvVar(name)))
+		default:
+			r.add(CategoryProviders, name, StatusInfo, "no API key")
+		}
+	}
+}
+
+// checkNetwork checks that the base URL of the current provider and of
+// every provider with a key answers. Any HTTP response counts, so a local
+// stub server passes as well as the real API; the key is never sent.
+func (r *Report) checkNetwork(ctx context.Context, cfg *config.Config, opts Options) {
+	type target struct {
+		name string
+		url  string
+	}
+	var targets []target
+	for _, name := range providerNames(cfg) {
+		url := cfg.Providers[name].BaseURL
+		if name == cfg.Provider {
+			url = cfg.BaseURL
+		}
+		key, _ := cfg.ProviderKey(name)
+		if name == cfg.Provider || key != "" {
+			targets = append(targets, target{name, url})
+		}
+	}
+
+	checks := make([]Check, len(targets))
+	var wg sync.WaitGroup
+	for i, t := range targets {
+		wg.Add(1)
+		go func(i int, t target) {
+			defer wg.Done()
+			status, detail := StatusOK, ""
+			elapsed, code, err := probe(ctx, opts.Client, opts.Timeout, t.url)
+			if err != nil {
+				status, detail = StatusWarn, fmt.Sprintf("%s unreachable: %v", t.url, err)
+				if t.name == cfg.Provider {
+					status = StatusFail
+				}
+			} else {
+				detail = fmt.Sprintf("%s answered HTTP %d in %s", t.url, code, elapsed.Round(time.Millisecond))
+			}
+			checks[i] = Check{Category: CategoryNetwork, Name: t.name, Status: status, Detail: detail}
+		}(i, t)
+	}
+	wg.Wait()
+	r.Checks = append(r.Checks, checks...)
+}
+
+// probe sends a GET to target and returns how long the answer took
+func probe(ctx context.Context, client *http.Client, timeout time.Duration, target string) (time.Duration, int, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	start := time.Now()
+	resp, err := client.Do(req)
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		err = urlErr.Err // The URL is already in the report
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	resp.Body.Close()
+	return time.Since(start), resp.StatusCode, nil
+}
+
+// checkSessions checks the session database opens and is consistent
+func (r *Report) checkSessions(opts Options) {
+	h, err := session.CheckStore(opts.DataDir, opts.Timeout)
+	switch {
+	case err != nil:
+		r.add(CategoryStorage, "sessions", StatusFail, err.Error())
+	case !h.Exists:
+		r.add(CategoryStorage, "sessions", StatusInfo, fmt.Sprintf("%s not created yet", h.Path))
+	case h.Locked:
+		r.add(CategoryStorage, "sessions", StatusWarn, fmt.Sprintf("%s is locked by another process, probably a running chat; the sessions commands can't open it until that exits", h.Path))
+	case len(h.Problems) > 0:
+		r.add(CategoryStorage, "sessions", StatusFail, fmt.Sprintf("%s is damaged: %s", h.Path, strings.Join(h.Problems, "; ")))
+	default:
+		r.add(CategoryStorage, "sessions", StatusOK, fmt.Sprintf("%s, %d sessions, %d messages, %s",
+			h.Path, h.Sessions, h.Messages, formatSize(h.Size)))
+	}
+}
+
+// checkLogDir checks the log directory takes new files
+func (r *Report) checkLogDir(dir string) {
+	f, err := os.CreateTemp(dir, ".doctor-*")
+	if err != nil {
+		r.add(CategoryStorage, "logs", StatusFail, fmt.Sprintf("%s is not writable: %v", dir, err))
+		return
+	}
+	f.Close()
+	os.Remove(f.Name())
+	r.add(CategoryStorage, "logs", StatusOK, dir)
+}
+
+// checkPrograms looks up the helper programs the tools use
+func (r *Report) checkPrograms(programs []string) {
+	for _, program := range programs {
+		if path, err := exec.LookPath(program); err == nil {
+			r.add(CategoryPrograms, program, StatusOK, path)
+		} else {
+			r.add(CategoryPrograms, program, StatusWarn, "not found in PATH")
+		}
+	}
+}
+
+// checkTerminal reports what the chat UI can expect from the terminal
+func (r *Report) checkTerminal() {
+	if !term.IsTerminal(int(os.Stdin.Fd())) || !term.IsTerminal(int(os.Stdout.Fd())) {
+		r.add(CategoryTerminal, "tty", StatusWarn, "stdin or stdout is not a terminal; the chat needs an interactive one")
+	} else if w, h, err := term.GetSize(int(os.Stdout.Fd())); err != nil {
+		r.add(CategoryTerminal, "tty", StatusWarn, fmt.Sprintf("size unknown: %v", err))
+	} else if w < 80 || h < 24 {
+		r.add(CategoryTerminal, "tty", StatusWarn, fmt.Sprintf("%dx%d; the chat is cramped below 80x24", w, h))
+	} else {
+		r.add(CategoryTerminal, "tty", StatusOK, fmt.Sprintf("%dx%d", w, h))
+	}
+
+	termName := os.Getenv("TERM")
+	switch termName {
+	case "":
+		r.add(CategoryTerminal, "TERM", StatusWarn, "not set")
+	case "dumb":
+		r.add(CategoryTerminal, "TERM", StatusWarn, "dumb terminals can't show the chat UI")
+	default:
+		r.add(CategoryTerminal, "TERM", StatusOK, termName)
+	}
+
+	profile := lipgloss.ColorProfile().Name()
+	if colorTerm := os.Getenv("COLORTERM"); colorTerm != "" {
+		profile += ", COLORTERM=" + colorTerm
+	}
+	r.add(CategoryTerminal, "colors", StatusInfo, profile)
+}
+
+// providerNames returns the configured providers in name order
+func providerNames(cfg *config.Config) []string {
+	names := cfg.ListProviders()
+	sort.Strings(names)
+	return names
+}
+
+// formatSize renders a byte count
+func formatSize(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", n)
+}
+
+// Count returns how many checks have a status
+func (r *Report) Count(status string) int {
+	n := 0
+	for _, c := range r.Checks {
+		if c.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// Failed reports whether any check failed
+func (r *Report) Failed() bool {
+	return r.Count(StatusFail) > 0
+}
+
+// statusMarks are shown before each check in the text report
+var statusMarks = map[string]string{
+	StatusOK:   "✓",
+	StatusInfo: "·",
+	StatusWarn: "!",
+	StatusFail: "✗",
+}
+
+// Text renders the report for a terminal
+func (r *Report) Text() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Zesbe Go %s · %s/%s · %s · %s\n",
+		r.Version, r.OS, r.Arch, r.Go, r.Time.Format("2006-01-02 15:04")))
+
+	width := 0
+	for _, c := range r.Checks {
+		if len(c.Name) > width {
+			width = len(c.Name)
+		}
+	}
+	for _, cat := range categoryTitles {
+		header := false
+		for _, c := range r.Checks {
+			if c.Category != cat.category {
+				continue
+			}
+			if !header {
+				sb.WriteString("\n" + cat.title + "\n")
+				header = true
+			}
+			sb.WriteString(fmt.Sprintf("  %s %-*s  %s\n", statusMarks[c.Status], width, c.Name, c.Detail))
+		}
+	}
+
+	failures, warnings := r.Count(StatusFail), r.Count(StatusWarn)
+	if failures == 0 && warnings == 0 {
+		sb.WriteString("\nEverything looks good.\n")
+	} else {
+		sb.WriteString(fmt.Sprintf("\n%s, %s\n", plural(failures, "failure"), plural(warnings, "warning")))
+	}
+	return sb.String()
+}
+
+// JSON renders the report for tools and bug reports
+func (r *Report) JSON() ([]byte, error) {
+	return json.MarshalIndent(r, "", "  ")
+}
+
+// plural formats a count with its noun
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
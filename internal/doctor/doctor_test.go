@@ -0,0 +1,244 @@
+package doctor
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/zesbe/zesbe-go/internal/config"
+)
+
+// stubKey is the API key of the provider under test; it must never reach
+// the network
+const stubKey = "sk-test-0123456789"
+
+// stubServer is a provider endpoint that counts requests and remembers
+// what they carried
+type stubServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	hits     int
+	requests []string // Headers and URL of each request
+}
+
+func newStubServer(t *testing.T) *stubServer {
+	s := &stubServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.hits++
+		s.requests = append(s.requests, r.URL.String()+" "+formatHeaders(r.Header))
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// count returns the requests received so far
+func (s *stubServer) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.hits
+}
+
+func formatHeaders(h http.Header) string {
+	var sb strings.Builder
+	for k, v := range h {
+		sb.WriteString(k + ": " + strings.Join(v, ",") + "\n")
+	}
+	return sb.String()
+}
+
+// testConfig returns a config whose current provider "local" uses baseURL,
+// with HOME pointed at an empty directory so no real keys are found
+func testConfig(t *testing.T, baseURL string) *config.Config {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("LOCAL_API_KEY", "")
+	return &config.Config{
+		Provider: "local",
+		BaseURL:  baseURL,
+		APIKey:   stubKey,
+		Providers: map[string]config.Provider{
+			"local": {Name: "local", BaseURL: baseURL},
+		},
+	}
+}
+
+// testOptions keeps Run away from the real data, log and PATH
+func testOptions(t *testing.T) Options {
+	return Options{
+		Version:  "test",
+		Timeout:  2 * time.Second,
+		DataDir:  t.TempDir(),
+		LogDir:   t.TempDir(),
+		Programs: []string{},
+	}
+}
+
+// find returns the check with a category and name
+func find(t *testing.T, r *Report, category, name string) Check {
+	t.Helper()
+	for _, c := range r.Checks {
+		if c.Category == category && c.Name == name {
+			return c
+		}
+	}
+	t.Fatalf("no %s check named %s in %+v", category, name, r.Checks)
+	return Check{}
+}
+
+func TestNetworkReachable(t *testing.T) {
+	stub := newStubServer(t)
+	cfg := testConfig(t, stub.URL)
+	opts := testOptions(t)
+	opts.Client = stub.Client()
+
+	r := Run(context.Background(), cfg, opts)
+
+	c := find(t, r, CategoryNetwork, "local")
+	if c.Status != StatusOK || !strings.Contains(c.Detail, "HTTP 401") {
+		t.Errorf("local = %+v, want ok with the HTTP status", c)
+	}
+	if n := stub.count(); n != 1 {
+		t.Errorf("stub got %d requests, want 1", n)
+	}
+	stub.mu.Lock()
+	defer stub.mu.Unlock()
+	for _, req := range stub.requests {
+		if strings.Contains(req, stubKey) {
+			t.Errorf("API key sent in request: %s", req)
+		}
+	}
+}
+
+func TestNetworkUnreachable(t *testing.T) {
+	stub := newStubServer(t)
+	dead := httptest.NewServer(http.NotFoundHandler())
+	deadURL := dead.URL
+	dead.Close()
+
+	cfg := testConfig(t, deadURL)
+	cfg.Providers["other"] = config.Provider{Name: "other", BaseURL: deadURL}
+	cfg.Providers["keyless"] = config.Provider{Name: "keyless", BaseURL: stub.URL}
+	t.Setenv("OTHER_API_KEY", "sk-other-0123456789")
+	t.Setenv("KEYLESS_API_KEY", "")
+	opts := testOptions(t)
+
+	r := Run(context.Background(), cfg, opts)
+
+	if c := find(t, r, CategoryNetwork, "local"); c.Status != StatusFail || !strings.Contains(c.Detail, "unreachable") {
+		t.Errorf("current provider = %+v, want a failure", c)
+	}
+	if c := find(t, r, CategoryNetwork, "other"); c.Status != StatusWarn {
+		t.Errorf("other provider with a key = %+v, want a warning", c)
+	}
+	for _, c := range r.Checks {
+		if c.Category == CategoryNetwork && c.Name == "keyless" {
+			t.Errorf("provider without a key was probed: %+v", c)
+		}
+	}
+	if n := stub.count(); n != 0 {
+		t.Errorf("keyless provider's endpoint got %d requests, want 0", n)
+	}
+	if !r.Failed() {
+		t.Error("Failed() = false with the current provider unreachable")
+	}
+}
+
+func TestOffline(t *testing.T) {
+	stub := newStubServer(t)
+	opts := testOptions(t)
+	opts.Offline = true
+	opts.Client = stub.Client()
+
+	r := Run(context.Background(), testConfig(t, stub.URL), opts)
+
+	if c := find(t, r, CategoryNetwork, "providers"); c.Status != StatusInfo {
+		t.Errorf("network = %+v, want skipped", c)
+	}
+	if n := stub.count(); n != 0 {
+		t.Errorf("offline run sent %d requests", n)
+	}
+}
+
+func TestSessionsLocked(t *testing.T) {
+	opts := testOptions(t)
+	opts.Offline = true
+	opts.Timeout = 100 * time.Millisecond
+
+	db, err := bolt.Open(filepath.Join(opts.DataDir, "sessions.db"), 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	r := Run(context.Background(), testConfig(t, "http://127.0.0.1"), opts)
+	c := find(t, r, CategoryStorage, "sessions")
+	if c.Status != StatusWarn || !strings.Contains(c.Detail, "locked") {
+		t.Errorf("sessions = %+v, want a locked warning", c)
+	}
+}
+
+func TestSessionsMissing(t *testing.T) {
+	opts := testOptions(t)
+	opts.Offline = true
+
+	r := Run(context.Background(), testConfig(t, "http://127.0.0.1"), opts)
+	if c := find(t, r, CategoryStorage, "sessions"); c.Status != StatusInfo {
+		t.Errorf("sessions = %+v, want not created yet", c)
+	}
+}
+
+func TestLogDir(t *testing.T) {
+	opts := testOptions(t)
+	opts.Offline = true
+	r := Run(context.Background(), testConfig(t, "http://127.0.0.1"), opts)
+	if c := find(t, r, CategoryStorage, "logs"); c.Status != StatusOK {
+		t.Errorf("writable logs = %+v, want ok", c)
+	}
+
+	// A directory under a regular file can't be written, even by root
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	opts.LogDir = filepath.Join(file, "logs")
+	r = Run(context.Background(), testConfig(t, "http://127.0.0.1"), opts)
+	if c := find(t, r, CategoryStorage, "logs"); c.Status != StatusFail || !strings.Contains(c.Detail, "not writable") {
+		t.Errorf("unwritable logs = %+v, want a failure", c)
+	}
+}
+
+func TestJSONExport(t *testing.T) {
+	opts := testOptions(t)
+	opts.Offline = true
+	opts.Programs = []string{"zesbe-no-such-program"}
+	r := Run(context.Background(), testConfig(t, "http://127.0.0.1"), opts)
+
+	data, err := r.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Report
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("export isn't valid JSON: %v", err)
+	}
+	if decoded.Version != "test" || len(decoded.Checks) != len(r.Checks) {
+		t.Errorf("decoded report = %+v, want version test and %d checks", decoded, len(r.Checks))
+	}
+	if c := find(t, &decoded, CategoryPrograms, "zesbe-no-such-program"); c.Status != StatusWarn {
+		t.Errorf("missing program = %+v, want a warning", c)
+	}
+	if strings.Contains(string(data), stubKey) {
+		t.Error("the export contains the API key")
+	}
+}
@@ -0,0 +1,299 @@
+// Package hooks runs user commands at points in the agent loop, so teams
+// can add guardrails, auditing and notifications without patching the
+// binary.
+//
+// A hook receives the event as a JSON Input on stdin. Exiting 0 lets the
+// action go ahead, optionally with a JSON Response on stdout that changes
+// it. Exiting 2 blocks the action, with stderr as the reason. Any other
+// failure, including a timeout, is logged and the action goes ahead.
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/zesbe/zesbe-go/internal/config"
+	"github.com/zesbe/zesbe-go/internal/logger"
+	"github.com/zesbe/zesbe-go/internal/tools"
+)
+
+// Lifecycle events
+const (
+	PreToolUse       = "pre_tool_use"
+	PostToolUse      = "post_tool_use"
+	UserPromptSubmit = "user_prompt_submit"
+	Stop             = "stop"
+)
+
+// DefaultTimeout limits a hook that doesn't set its own timeout
+const DefaultTimeout = 30 * time.Second
+
+// blockExitCode is the exit status a hook uses to block the action
+const blockExitCode = 2
+
+// Input is the event a hook receives on stdin
+type Input struct {
+	Event    string            `json:"event"`
+	Cwd      string            `json:"cwd"`
+	Tool     string            `json:"tool,omitempty"`     // Tool events
+	Params   map[string]string `json:"params,omitempty"`   // Tool events
+	Result   *tools.ToolResult `json:"result,omitempty"`   // post_tool_use
+	Prompt   string            `json:"prompt,omitempty"`   // user_prompt_submit
+	Response string            `json:"response,omitempty"` // stop: the agent's last reply
+}
+
+// Response is what a hook may print on stdout
+type Response struct {
+	Decision string                     `json:"decision,omitempty"` // "block" stops the action
+	Reason   string                     `json:"reason,omitempty"`   // Why it was blocked
+	Params   map[string]json.RawMessage `json:"params,omitempty"`   // pre_tool_use: replacement arguments
+	Prompt   *string                    `json:"prompt,omitempty"`   // user_prompt_submit: replacement prompt
+	Context  string                     `json:"context,omitempty"`  // post_tool_use, user_prompt_submit: text added for the model
+}
+
+// BlockedError reports an action a hook refused
+type BlockedError struct {
+	Event  string
+	Hook   string
+	Reason string
+}
+
+// Error implements error
+func (e *BlockedError) Error() string {
+	return fmt.Sprintf("blocked by %s hook %s: %s", e.Event, e.Hook, e.Reason)
+}
+
+// hook is a validated config.Hook
+type hook struct {
+	name    string
+	matcher *regexp.Regexp // nil matches every tool
+	command []string
+	dir     string
+	timeout time.Duration
+}
+
+// Runner runs the configured hooks. A nil Runner runs none.
+type Runner struct {
+	hooks map[string][]hook
+}
+
+// New validates the hooks config. Invalid hooks are skipped and reported.
+func New(cfg config.Hooks) (*Runner, error) {
+	r := &Runner{hooks: make(map[string][]hook)}
+	var errs []error
+	for _, ev := range []struct {
+		name  string
+		hooks []config.Hook
+	}{
+		{PreToolUse, cfg.PreToolUse},
+		{PostToolUse, cfg.PostToolUse},
+		{UserPromptSubmit, cfg.UserPromptSubmit},
+		{Stop, cfg.Stop},
+	} {
+		for i, h := range ev.hooks {
+			if h.Disabled {
+				continue
+			}
+			parsed, err := newHook(h)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("hooks.%s[%d]: %w", ev.name, i, err))
+				continue
+			}
+			r.hooks[ev.name] = append(r.hooks[ev.name], parsed)
+		}
+	}
+	return r, errors.Join(errs...)
+}
+
+// newHook validates one hook
+func newHook(h config.Hook) (hook, error) {
+	if len(h.Command) == 0 || strings.TrimSpace(h.Command[0]) == "" {
+		return hook{}, fmt.Errorf("command is required")
+	}
+	parsed := hook{name: filepath.Base(h.Command[0]), command: h.Command, dir: h.Dir, timeout: DefaultTimeout}
+	if h.Matcher != "" {
+		if _, err := regexp.Compile(h.Matcher); err != nil {
+			return hook{}, fmt.Errorf("matcher: %w", err)
+		}
+		parsed.matcher = regexp.MustCompile("^(?:" + h.Matcher + ")$") // Whole tool names only
+	}
+	if h.Timeout != "" {
+		d, err := tools.ParseTimeout(h.Timeout)
+		if err != nil {
+			return hook{}, fmt.Errorf("timeout: %w", err)
+		}
+		parsed.timeout = d
+	}
+	return parsed, nil
+}
+
+// forTool returns the hooks of a tool event that match the tool
+func (r *Runner) forTool(event, tool string) []hook {
+	if r == nil {
+		return nil
+	}
+	var matched []hook
+	for _, h := range r.hooks[event] {
+		if h.matcher == nil || h.matcher.MatchString(tool) {
+			matched = append(matched, h)
+		}
+	}
+	return matched
+}
+
+// BeforeTool runs the pre_tool_use hooks for a call. Each hook sees the
+// arguments left by the one before, and the call to execute is returned.
+// The error is a *BlockedError when a hook refuses the call.
+func (r *Runner) BeforeTool(ctx context.Context, call tools.ToolCall) (tools.ToolCall, error) {
+	for _, h := range r.forTool(PreToolUse, call.Name) {
+		resp, err := h.run(ctx, Input{Event: PreToolUse, Tool: call.Name, Params: call.Params})
+		if err != nil {
+			return call, err
+		}
+		if resp.Params != nil {
+			call.Params = tools.StringParams(resp.Params)
+		}
+	}
+	return call, nil
+}
+
+// AfterTool runs the post_tool_use hooks for a call and appends any notes
+// they return to the result the model sees
+func (r *Runner) AfterTool(ctx context.Context, call tools.ToolCall, result tools.ToolResult) tools.ToolResult {
+	var notes []string
+	for _, h := range r.forTool(PostToolUse, call.Name) {
+		resp, err := h.run(ctx, Input{Event: PostToolUse, Tool: call.Name, Params: call.Params, Result: &result})
+		var blocked *BlockedError
+		if errors.As(err, &blocked) {
+			notes = append(notes, fmt.Sprintf("Hook %s: %s", h.name, blocked.Reason))
+			continue
+		}
+		if resp.Context != "" {
+			notes = append(notes, fmt.Sprintf("Hook %s: %s", h.name, resp.Context))
+		}
+	}
+	if len(notes) == 0 {
+		return result
+	}
+	note := strings.Join(notes, "\n")
+	if result.Success {
+		result.Output = strings.TrimRight(result.Output, "\n") + "\n\n" + note
+	} else {
+		result.Error += "\n" + note
+	}
+	return result
+}
+
+// ExecuteTool runs a tool call between the pre and post tool hooks and
+// returns the call as the hooks left it. The chat and mcp serve both run
+// tools through it, so a hook that blocks a call applies to either.
+func (r *Runner) ExecuteTool(ctx context.Context, call tools.ToolCall) (tools.ToolCall, tools.ToolResult) {
+	call, err := r.BeforeTool(ctx, call)
+	if err != nil {
+		return call, tools.ToolResult{Success: false, Error: err.Error()}
+	}
+	result := tools.ExecuteToolContext(ctx, call)
+	return call, r.AfterTool(ctx, call, result)
+}
+
+// SubmitPrompt runs the user_prompt_submit hooks and returns the prompt to
+// send. The error is a *BlockedError when a hook refuses the prompt.
+func (r *Runner) SubmitPrompt(ctx context.Context, prompt string) (string, error) {
+	if r == nil {
+		return prompt, nil
+	}
+	var extra []string
+	for _, h := range r.hooks[UserPromptSubmit] {
+		resp, err := h.run(ctx, Input{Event: UserPromptSubmit, Prompt: prompt})
+		if err != nil {
+			return prompt, err
+		}
+		if resp.Prompt != nil {
+			prompt = *resp.Prompt
+		}
+		if resp.Context != "" {
+			extra = append(extra, resp.Context)
+		}
+	}
+	if len(extra) > 0 {
+		prompt += "\n\n" + strings.Join(extra, "\n\n")
+	}
+	return prompt, nil
+}
+
+// Stop runs the stop hooks with the agent's last reply. Their output is
+// ignored.
+func (r *Runner) Stop(ctx context.Context, response string) {
+	if r == nil {
+		return
+	}
+	for _, h := range r.hooks[Stop] {
+		h.run(ctx, Input{Event: Stop, Response: response})
+	}
+}
+
+// run executes the hook with input on stdin. A refusal, by exit status or
+// a "block" decision, is returned as a *BlockedError; other failures are
+// logged and yield an empty Response.
+func (h hook) run(ctx context.Context, input Input) (Response, error) {
+	input.Cwd, _ = os.Getwd()
+	data, err := json.Marshal(input)
+	if err != nil {
+		return Response{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, h.command[0], h.command[1:]...)
+	cmd.Dir = h.dir
+	cmd.Stdin = bytes.NewReader(data)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	start := time.Now()
+	err = cmd.Run()
+	logger.Debugf("Hook %s (%s) finished in %s", h.name, input.Event, time.Since(start).Round(time.Millisecond))
+
+	if ctx.Err() == context.DeadlineExceeded {
+		logger.Warnf("Hook %s (%s) timed out after %s", h.name, input.Event, h.timeout)
+		return Response{}, nil
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == blockExitCode {
+		reason := strings.TrimSpace(stderr.String())
+		if reason == "" {
+			reason = "no reason given"
+		}
+		return Response{}, &BlockedError{Event: input.Event, Hook: h.name, Reason: reason}
+	}
+	if err != nil {
+		logger.Warnf("Hook %s (%s) failed: %v: %s", h.name, input.Event, err, strings.TrimSpace(stderr.String()))
+		return Response{}, nil
+	}
+
+	var resp Response
+	if out := bytes.TrimSpace(stdout.Bytes()); len(out) > 0 {
+		if err := json.Unmarshal(out, &resp); err != nil {
+			logger.Warnf("Hook %s (%s) printed invalid JSON: %v", h.name, input.Event, err)
+			return Response{}, nil
+		}
+	}
+	if strings.EqualFold(resp.Decision, "block") {
+		reason := resp.Reason
+		if reason == "" {
+			reason = "no reason given"
+		}
+		return resp, &BlockedError{Event: input.Event, Hook: h.name, Reason: reason}
+	}
+	return resp, nil
+}
@@ -0,0 +1,244 @@
+package hooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zesbe/zesbe-go/internal/config"
+	"github.com/zesbe/zesbe-go/internal/tools"
+)
+
+// script writes a shell script to a temporary directory and returns a hook
+// that runs it
+func script(t *testing.T, body string) config.Hook {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("hook scripts need a POSIX shell")
+	}
+	path := filepath.Join(t.TempDir(), "hook.sh")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body+"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return config.Hook{Command: []string{path}}
+}
+
+// newRunner builds a Runner, failing the test on invalid hooks
+func newRunner(t *testing.T, cfg config.Hooks) *Runner {
+	t.Helper()
+	r, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestBlockByExitCode(t *testing.T) {
+	h := script(t, `cat >/dev/null; echo "no writes today" >&2; exit 2`)
+	r := newRunner(t, config.Hooks{PreToolUse: []config.Hook{h}})
+
+	_, err := r.BeforeTool(context.Background(), tools.ToolCall{Name: "write_file"})
+	var blocked *BlockedError
+	if !errors.As(err, &blocked) {
+		t.Fatalf("BeforeTool error = %v, want a *BlockedError", err)
+	}
+	if blocked.Reason != "no writes today" {
+		t.Errorf("Reason = %q, want the hook's stderr", blocked.Reason)
+	}
+	if blocked.Event != PreToolUse || blocked.Hook != "hook.sh" {
+		t.Errorf("blocked by %s hook %s, want %s hook hook.sh", blocked.Event, blocked.Hook, PreToolUse)
+	}
+}
+
+func TestBlockByDecision(t *testing.T) {
+	h := script(t, `cat >/dev/null; echo '{"decision":"block","reason":"off topic"}'`)
+	r := newRunner(t, config.Hooks{UserPromptSubmit: []config.Hook{h}})
+
+	_, err := r.SubmitPrompt(context.Background(), "tell me a joke")
+	var blocked *BlockedError
+	if !errors.As(err, &blocked) {
+		t.Fatalf("SubmitPrompt error = %v, want a *BlockedError", err)
+	}
+	if blocked.Reason != "off topic" {
+		t.Errorf("Reason = %q, want %q", blocked.Reason, "off topic")
+	}
+}
+
+func TestPreToolUseRewritesParams(t *testing.T) {
+	h := script(t, `cat >/dev/null; echo '{"params":{"path":"safe.txt","lines":10}}'`)
+	r := newRunner(t, config.Hooks{PreToolUse: []config.Hook{h}})
+
+	call := tools.ToolCall{Name: "read_file", Params: map[string]string{"path": "/etc/shadow"}}
+	got, err := r.BeforeTool(context.Background(), call)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Params["path"] != "safe.txt" || got.Params["lines"] != "10" {
+		t.Errorf("Params = %v, want the hook's replacement", got.Params)
+	}
+}
+
+func TestPreToolUseSeesCall(t *testing.T) {
+	// The hook echoes the tool name and path it was given back as the path
+	h := script(t, `input=$(cat)
+case "$input" in
+*'"tool":"read_file"'*'"path":"a.txt"'*) echo '{"params":{"path":"seen"}}' ;;
+esac`)
+	r := newRunner(t, config.Hooks{PreToolUse: []config.Hook{h}})
+
+	got, err := r.BeforeTool(context.Background(), tools.ToolCall{Name: "read_file", Params: map[string]string{"path": "a.txt"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Params["path"] != "seen" {
+		t.Errorf("path = %q, want the hook to have seen the call on stdin", got.Params["path"])
+	}
+}
+
+func TestUserPromptSubmitRewritesPrompt(t *testing.T) {
+	rewrite := script(t, `cat >/dev/null; echo '{"prompt":"rewritten"}'`)
+	addContext := script(t, `cat >/dev/null; echo '{"context":"Ticket: ABC-1"}'`)
+	r := newRunner(t, config.Hooks{UserPromptSubmit: []config.Hook{rewrite, addContext}})
+
+	got, err := r.SubmitPrompt(context.Background(), "original")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "rewritten\n\nTicket: ABC-1"; got != want {
+		t.Errorf("prompt = %q, want %q", got, want)
+	}
+}
+
+func TestFailuresLetActionThrough(t *testing.T) {
+	slow := script(t, `exec sleep 10`)
+	slow.Timeout = "200ms"
+	tests := []struct {
+		name string
+		hook config.Hook
+	}{
+		{"timeout", slow},
+		{"exit 1", script(t, `cat >/dev/null; echo broken >&2; exit 1`)},
+		{"killed", script(t, `kill -9 $$`)},
+		{"invalid JSON", script(t, `cat >/dev/null; echo '{"decision":'`)},
+		{"missing command", config.Hook{Command: []string{filepath.Join(t.TempDir(), "missing")}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newRunner(t, config.Hooks{PreToolUse: []config.Hook{tt.hook}})
+			call := tools.ToolCall{Name: "run_command", Params: map[string]string{"command": "ls"}}
+
+			start := time.Now()
+			got, err := r.BeforeTool(context.Background(), call)
+			if err != nil {
+				t.Fatalf("BeforeTool error = %v, want the call let through", err)
+			}
+			if got.Params["command"] != "ls" {
+				t.Errorf("Params = %v, want them unchanged", got.Params)
+			}
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Errorf("took %s, want the timeout to cut the hook short", elapsed)
+			}
+		})
+	}
+}
+
+func TestMatcherSelectsTools(t *testing.T) {
+	h := script(t, `cat >/dev/null; exit 2`)
+	h.Matcher = "write_file|edit_.*"
+	r := newRunner(t, config.Hooks{PreToolUse: []config.Hook{h}})
+
+	tests := []struct {
+		tool    string
+		blocked bool
+	}{
+		{"write_file", true},
+		{"edit_file", true},
+		{"read_file", false},
+		{"write_file_backup", false}, // Matchers cover whole tool names
+		{"mcp__fs__write_file", false},
+	}
+	for _, tt := range tests {
+		_, err := r.BeforeTool(context.Background(), tools.ToolCall{Name: tt.tool})
+		if blocked := err != nil; blocked != tt.blocked {
+			t.Errorf("%s: blocked = %v, want %v", tt.tool, blocked, tt.blocked)
+		}
+	}
+}
+
+func TestInvalidHooksSkipped(t *testing.T) {
+	good := script(t, `cat >/dev/null; exit 2`)
+	r, err := New(config.Hooks{PreToolUse: []config.Hook{
+		{Command: nil},
+		{Command: []string{"true"}, Matcher: "("},
+		{Command: []string{"true"}, Timeout: "soon"},
+		good,
+	}})
+	if err == nil {
+		t.Error("New error = nil, want the invalid hooks reported")
+	}
+	if n := len(r.hooks[PreToolUse]); n != 1 {
+		t.Errorf("%d hooks kept, want 1", n)
+	}
+}
+
+func TestNilRunner(t *testing.T) {
+	var r *Runner
+	call := tools.ToolCall{Name: "read_file"}
+	if got, err := r.BeforeTool(context.Background(), call); err != nil || got.Name != call.Name {
+		t.Errorf("BeforeTool = %v, %v; want the call unchanged", got, err)
+	}
+	if got, err := r.SubmitPrompt(context.Background(), "hi"); err != nil || got != "hi" {
+		t.Errorf("SubmitPrompt = %q, %v; want the prompt unchanged", got, err)
+	}
+	r.Stop(context.Background(), "done")
+}
+
+func TestExecuteToolBlocked(t *testing.T) {
+	h := script(t, `cat >/dev/null; echo "read-only session" >&2; exit 2`)
+	h.Matcher = "write_file"
+	r := newRunner(t, config.Hooks{PreToolUse: []config.Hook{h}})
+	path := filepath.Join(t.TempDir(), "out.txt")
+
+	_, result := r.ExecuteTool(context.Background(), tools.ToolCall{Name: "write_file", Params: map[string]string{"path": path, "content": "x"}})
+	if result.Success || result.Error == "" {
+		t.Errorf("result = %+v, want the hook's refusal", result)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("blocked write_file ran")
+	}
+}
+
+func TestMCPServerRunsHooks(t *testing.T) {
+	pre := script(t, `cat >/dev/null; echo "no writes over MCP" >&2; exit 2`)
+	pre.Matcher = "write_file"
+	post := script(t, `cat >/dev/null; echo '{"context":"checked"}'`)
+	r := newRunner(t, config.Hooks{PreToolUse: []config.Hook{pre}, PostToolUse: []config.Hook{post}})
+	server := tools.NewMCPServer("test", func(ctx context.Context, call tools.ToolCall) tools.ToolResult {
+		_, result := r.ExecuteTool(ctx, call)
+		return result
+	})
+	dir := t.TempDir()
+	path := filepath.Join(dir, "out.txt")
+
+	args := map[string]json.RawMessage{"path": json.RawMessage(strconv.Quote(path)), "content": json.RawMessage(`"x"`)}
+	got := server.CallTool(context.Background(), "write_file", args)
+	if !got.IsError || !strings.Contains(got.Content[0].Text, "no writes over MCP") {
+		t.Errorf("CallTool = %+v, want the pre_tool_use hook's refusal", got)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("write_file ran despite the hook")
+	}
+
+	args = map[string]json.RawMessage{"path": json.RawMessage(strconv.Quote(dir))}
+	got = server.CallTool(context.Background(), "list_directory", args)
+	if got.IsError || !strings.Contains(got.Content[0].Text, "Hook hook.sh: checked") {
+		t.Errorf("CallTool = %+v, want the post_tool_use hook's note", got)
+	}
+}
@@ -0,0 +1,113 @@
+// Package instructions finds the ZESBE.md files that describe a project's
+// conventions and renders them for the system prompt.
+package instructions
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/zesbe/zesbe-go/internal/config"
+)
+
+// FileName is the instructions file looked for in each directory
+const FileName = "ZESBE.md"
+
+// maxFileBytes caps how much of one file goes into the prompt
+const maxFileBytes = 32 * 1024
+
+// File is a loaded instructions file
+type File struct {
+	Path      string
+	Content   string
+	Truncated bool
+}
+
+// UserPath returns the user's global instructions file
+func UserPath() string {
+	return filepath.Join(config.GetConfigDir(), FileName)
+}
+
+// Root returns the git root containing dir, or dir itself outside a
+// repository
+func Root(dir string) string {
+	for d := dir; ; {
+		if _, err := os.Stat(filepath.Join(d, ".git")); err == nil {
+			return d
+		}
+		parent := filepath.Dir(d)
+		if parent == d {
+			return dir
+		}
+		d = parent
+	}
+}
+
+// Discover returns the instruction files that apply in dir: the user's
+// global file first, then one per directory from the git root down to dir,
+// so more specific files come later
+func Discover(dir string) []File {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil
+	}
+
+	var dirs []string
+	root := Root(dir)
+	for d := dir; ; d = filepath.Dir(d) {
+		dirs = append([]string{d}, dirs...)
+		if d == root || filepath.Dir(d) == d {
+			break
+		}
+	}
+
+	paths := []string{UserPath()}
+	for _, d := range dirs {
+		paths = append(paths, filepath.Join(d, FileName))
+	}
+
+	var files []File
+	seen := make(map[string]bool)
+	for _, p := range paths {
+		if seen[p] {
+			continue // The config dir can sit inside the workspace
+		}
+		seen[p] = true
+		if f, ok := load(p); ok {
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+// load reads one file, skipping missing and empty ones
+func load(path string) (File, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return File{}, false
+	}
+	f := File{Path: path}
+	if len(data) > maxFileBytes {
+		data = data[:maxFileBytes]
+		f.Truncated = true
+	}
+	f.Content = strings.TrimSpace(strings.ToValidUTF8(string(data), ""))
+	return f, f.Content != ""
+}
+
+// Render formats files for the system prompt, or "" when there are none
+func Render(files []File) string {
+	if len(files) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("Follow these instructions from the user and the project. When they conflict, later files are more specific and take precedence.\n")
+	for _, f := range files {
+		sb.WriteString(fmt.Sprintf("\n### %s\n%s\n", f.Path, f.Content))
+		if f.Truncated {
+			sb.WriteString(fmt.Sprintf("[Truncated to the first %d KB]\n", maxFileBytes/1024))
+		}
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
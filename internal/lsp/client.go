@@ -0,0 +1,344 @@
+package lsp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/zesbe/zesbe-go/internal/config"
+	"github.com/zesbe/zesbe-go/internal/logger"
+)
+
+// settleDelay is how long to keep listening after the first diagnostics for
+// a change arrive, since servers often publish in several passes
+const settleDelay = 300 * time.Millisecond
+
+// Client is a connection to one language server
+type Client struct {
+	Name string
+
+	cfg    config.LSPServer
+	root   string
+	c      *conn
+	nextID atomic.Int64
+	noSync bool
+	server string // Name the server reported
+
+	mu         sync.Mutex
+	docs       map[string]int               // URI -> version of each open document
+	sums       map[string][sha256.Size]byte // URI -> hash of the text last sent
+	diags      map[string][]Diagnostic
+	pubs       map[string]int // URI -> number of publishes received
+	pubVersion map[string]int // URI -> document version of the last publish
+	changed    chan struct{}  // Closed and replaced on every publish
+}
+
+// syncPoint records a document change so Wait can tell which diagnostics
+// were computed after it
+type syncPoint struct {
+	uri     string
+	version int
+	pubs    int
+}
+
+// NewClient creates a client for a configured server without starting it
+func NewClient(name string, cfg config.LSPServer, root string) *Client {
+	return &Client{
+		Name:       name,
+		cfg:        cfg,
+		root:       root,
+		docs:       make(map[string]int),
+		sums:       make(map[string][sha256.Size]byte),
+		diags:      make(map[string][]Diagnostic),
+		pubs:       make(map[string]int),
+		pubVersion: make(map[string]int),
+		changed:    make(chan struct{}),
+	}
+}
+
+// Start launches the server and runs the initialize handshake
+func (c *Client) Start(ctx context.Context) error {
+	conn, err := startConn(c.cfg.Command, c.cfg.Args, c.cfg.Env, c.root, c.handle)
+	if err != nil {
+		return fmt.Errorf("failed to start %s: %w", c.cfg.Command, err)
+	}
+	c.c = conn
+
+	rootURI := PathToURI(c.root)
+	params := map[string]interface{}{
+		"processId":  os.Getpid(),
+		"clientInfo": map[string]string{"name": "zesbe-go"},
+		"rootUri":    rootURI,
+		"rootPath":   c.root,
+		"workspaceFolders": []workspaceFolder{
+			{URI: rootURI, Name: filepath.Base(c.root)},
+		},
+		"capabilities": map[string]interface{}{
+			"textDocument": map[string]interface{}{
+				"synchronization":    map[string]interface{}{"didSave": true},
+				"publishDiagnostics": map[string]interface{}{"versionSupport": true},
+			},
+			"workspace": map[string]interface{}{
+				"workspaceFolders": true,
+				"configuration":    true,
+			},
+		},
+	}
+	if len(c.cfg.InitializationOptions) > 0 {
+		params["initializationOptions"] = c.cfg.InitializationOptions
+	}
+
+	var result initializeResult
+	if err := c.request(ctx, "initialize", params, &result); err != nil {
+		conn.close()
+		return fmt.Errorf("initialize failed: %w", err)
+	}
+	if err := c.notify("initialized", struct{}{}); err != nil {
+		conn.close()
+		return fmt.Errorf("initialize failed: %w", err)
+	}
+
+	c.noSync = result.syncNone()
+	if result.ServerInfo != nil {
+		c.server = result.ServerInfo.Name
+	}
+	return nil
+}
+
+// request sends a request and decodes its result into out
+func (c *Client) request(ctx context.Context, method string, params, out interface{}) error {
+	id := json.RawMessage(strconv.FormatInt(c.nextID.Add(1), 10))
+	req, err := newRequest(id, method, params)
+	if err != nil {
+		return err
+	}
+	resp, err := c.c.call(ctx, req)
+	if err != nil {
+		return err
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(resp.Result, out)
+}
+
+// notify sends a notification
+func (c *Client) notify(method string, params interface{}) error {
+	n, err := newRequest(nil, method, params)
+	if err != nil {
+		return err
+	}
+	return c.c.write(n)
+}
+
+// handle answers server-initiated requests and records diagnostics
+func (c *Client) handle(msg *message) *message {
+	switch msg.Method {
+	case "textDocument/publishDiagnostics":
+		var p publishDiagnosticsParams
+		if err := json.Unmarshal(msg.Params, &p); err == nil {
+			c.publish(p)
+		}
+		return nil
+	case "workspace/configuration":
+		// No settings of our own; one null per requested item
+		var p struct {
+			Items []json.RawMessage `json:"items"`
+		}
+		json.Unmarshal(msg.Params, &p)
+		return newResponse(msg.ID, make([]interface{}, len(p.Items)))
+	case "workspace/workspaceFolders":
+		return newResponse(msg.ID, []workspaceFolder{{URI: PathToURI(c.root), Name: filepath.Base(c.root)}})
+	case "client/registerCapability", "client/unregisterCapability", "window/workDoneProgress/create", "window/showMessageRequest":
+		return newResponse(msg.ID, nil)
+	case "window/logMessage", "window/showMessage":
+		var p struct {
+			Message string `json:"message"`
+		}
+		json.Unmarshal(msg.Params, &p)
+		logger.Debugf("LSP %s: %s", c.Name, p.Message)
+		return nil
+	}
+	if msg.isRequest() {
+		return newErrorResponse(msg.ID, CodeMethodNotFound, "method not found: "+msg.Method)
+	}
+	return nil
+}
+
+// publish stores diagnostics and wakes anyone waiting for them
+func (c *Client) publish(p publishDiagnosticsParams) {
+	uri := PathToURI(URIToPath(p.URI)) // Normalise the server's encoding
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.diags[uri] = p.Diagnostics
+	c.pubs[uri]++
+	if p.Version != nil {
+		c.pubVersion[uri] = *p.Version
+	} else {
+		delete(c.pubVersion, uri)
+	}
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+// Sync sends the current text of a file: didOpen the first time, didChange
+// and didSave after that
+func (c *Client) Sync(path, languageID, text string) (syncPoint, error) {
+	uri := PathToURI(path)
+	c.mu.Lock()
+	version, open := c.docs[uri]
+	version++
+	c.docs[uri] = version
+	c.sums[uri] = sha256.Sum256([]byte(text))
+	point := syncPoint{uri: uri, version: version, pubs: c.pubs[uri]}
+	c.mu.Unlock()
+
+	if c.noSync {
+		return point, nil
+	}
+	var err error
+	if !open {
+		err = c.notify("textDocument/didOpen", map[string]interface{}{
+			"textDocument": textDocumentItem{URI: uri, LanguageID: languageID, Version: version, Text: text},
+		})
+	} else {
+		// A change without a range replaces the whole document
+		err = c.notify("textDocument/didChange", map[string]interface{}{
+			"textDocument":   versionedTextDocumentIdentifier{URI: uri, Version: version},
+			"contentChanges": []map[string]string{{"text": text}},
+		})
+		if err == nil {
+			err = c.notify("textDocument/didSave", map[string]interface{}{
+				"textDocument": textDocumentIdentifier{URI: uri},
+			})
+		}
+	}
+	return point, err
+}
+
+// Outdated compares the open documents with the files on disk. changed are
+// files whose contents differ from what the server last got, removed are
+// files that no longer exist.
+func (c *Client) Outdated() (changed, removed []string) {
+	c.mu.Lock()
+	sums := make(map[string][sha256.Size]byte, len(c.sums))
+	for uri, sum := range c.sums {
+		sums[uri] = sum
+	}
+	c.mu.Unlock()
+
+	for uri, sum := range sums {
+		path := URIToPath(uri)
+		data, err := os.ReadFile(path)
+		switch {
+		case os.IsNotExist(err):
+			removed = append(removed, path)
+		case err == nil && sha256.Sum256(data) != sum:
+			changed = append(changed, path)
+		}
+	}
+	sort.Strings(changed)
+	sort.Strings(removed)
+	return changed, removed
+}
+
+// Forget closes a document, e.g. after the file was deleted
+func (c *Client) Forget(path string) {
+	uri := PathToURI(path)
+	c.mu.Lock()
+	_, open := c.docs[uri]
+	delete(c.docs, uri)
+	delete(c.sums, uri)
+	delete(c.diags, uri)
+	c.mu.Unlock()
+	if open && !c.noSync {
+		c.notify("textDocument/didClose", map[string]interface{}{
+			"textDocument": textDocumentIdentifier{URI: uri},
+		})
+	}
+}
+
+// Wait blocks until the server publishes diagnostics computed after point,
+// then returns them. When ctx ends first, the latest known diagnostics are
+// returned; servers skip publishing when nothing changed.
+func (c *Client) Wait(ctx context.Context, point syncPoint) []Diagnostic {
+	settled := false
+	var settle <-chan time.Time
+	for {
+		c.mu.Lock()
+		if !settled && c.pubs[point.uri] > point.pubs {
+			if v, ok := c.pubVersion[point.uri]; !ok || v >= point.version {
+				settled = true
+				settle = time.After(settleDelay)
+			}
+		}
+		changed := c.changed
+		c.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-settle:
+			return c.Diagnostics(point.uri)
+		case <-ctx.Done():
+			return c.Diagnostics(point.uri)
+		case <-c.c.done:
+			return c.Diagnostics(point.uri)
+		}
+	}
+}
+
+// Diagnostics returns the latest diagnostics for a document URI
+func (c *Client) Diagnostics(uri string) []Diagnostic {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Diagnostic(nil), c.diags[uri]...)
+}
+
+// AllDiagnostics returns the latest diagnostics of every document, by path
+func (c *Client) AllDiagnostics() map[string][]Diagnostic {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	all := make(map[string][]Diagnostic, len(c.diags))
+	for uri, d := range c.diags {
+		if len(d) > 0 {
+			all[URIToPath(uri)] = append([]Diagnostic(nil), d...)
+		}
+	}
+	return all
+}
+
+// Alive reports whether the server process is still running
+func (c *Client) Alive() bool {
+	return c.c != nil && c.c.alive()
+}
+
+// ServerName returns the name the server reported, or its command
+func (c *Client) ServerName() string {
+	if c.server != "" {
+		return c.server
+	}
+	return c.cfg.Command
+}
+
+// Shutdown asks the server to exit and closes the connection
+func (c *Client) Shutdown(ctx context.Context) {
+	if c.c == nil {
+		return
+	}
+	if c.c.alive() {
+		c.request(ctx, "shutdown", nil, nil)
+		c.notify("exit", nil)
+	}
+	c.c.close()
+}
@@ -0,0 +1,57 @@
+package lsp
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/zesbe/zesbe-go/internal/config"
+)
+
+func TestClientOutdated(t *testing.T) {
+	dir := t.TempDir()
+	same := filepath.Join(dir, "same.go")
+	edited := filepath.Join(dir, "edited.go")
+	deleted := filepath.Join(dir, "deleted.go")
+
+	// noSync keeps Sync from talking to a server that isn't there
+	c := NewClient("test", config.LSPServer{}, dir)
+	c.noSync = true
+	for _, path := range []string{same, edited, deleted} {
+		if err := os.WriteFile(path, []byte("package a\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.Sync(path, "go", "package a\n"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if changed, removed := c.Outdated(); len(changed)+len(removed) != 0 {
+		t.Fatalf("Outdated before any change = %v, %v", changed, removed)
+	}
+
+	// As a shell command or git checkout would, behind the server's back
+	if err := os.WriteFile(edited, []byte("package b\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(deleted); err != nil {
+		t.Fatal(err)
+	}
+
+	changed, removed := c.Outdated()
+	if !reflect.DeepEqual(changed, []string{edited}) {
+		t.Errorf("changed = %v, want %v", changed, []string{edited})
+	}
+	if !reflect.DeepEqual(removed, []string{deleted}) {
+		t.Errorf("removed = %v, want %v", removed, []string{deleted})
+	}
+
+	if _, err := c.Sync(edited, "go", "package b\n"); err != nil {
+		t.Fatal(err)
+	}
+	c.Forget(deleted)
+	if changed, removed := c.Outdated(); len(changed)+len(removed) != 0 {
+		t.Errorf("Outdated after resync = %v, %v", changed, removed)
+	}
+}
@@ -0,0 +1,283 @@
+package lsp
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zesbe/zesbe-go/internal/config"
+)
+
+// StartTimeout bounds the initialize handshake with each server
+const StartTimeout = 20 * time.Second
+
+// DefaultServers are used for any language the config doesn't set up.
+// Servers whose command isn't installed are skipped.
+var DefaultServers = map[string]config.LSPServer{
+	"gopls":    {Command: "gopls", Extensions: []string{".go"}},
+	"pyright":  {Command: "pyright-langserver", Args: []string{"--stdio"}, Extensions: []string{".py"}},
+	"tsserver": {Command: "typescript-language-server", Args: []string{"--stdio"}, Extensions: []string{".ts", ".tsx", ".js", ".jsx"}},
+}
+
+// languageIDs maps file extensions to LSP language identifiers
+var languageIDs = map[string]string{
+	".go":  "go",
+	".py":  "python",
+	".ts":  "typescript",
+	".tsx": "typescriptreact",
+	".js":  "javascript",
+	".jsx": "javascriptreact",
+	".rs":  "rust",
+	".c":   "c",
+	".h":   "c",
+	".cpp": "cpp",
+	".cc":  "cpp",
+	".hpp": "cpp",
+}
+
+// ServerStatus describes a configured server for display
+type ServerStatus struct {
+	Name       string
+	Command    string
+	Extensions []string
+	Running    bool
+	Disabled   bool
+	Error      string
+}
+
+// Manager starts language servers on demand, one per configured server,
+// the first time a file with one of its extensions is touched
+type Manager struct {
+	root    string
+	servers map[string]config.LSPServer
+
+	mu      sync.Mutex
+	clients map[string]*Client
+	errors  map[string]string // Servers that failed to start aren't retried
+}
+
+// NewManager merges servers over DefaultServers for a workspace root
+func NewManager(servers map[string]config.LSPServer, root string) *Manager {
+	merged := make(map[string]config.LSPServer)
+	for name, s := range DefaultServers {
+		if _, err := exec.LookPath(s.Command); err == nil {
+			merged[name] = s
+		}
+	}
+	for name, s := range servers {
+		merged[name] = s
+	}
+	return &Manager{
+		root:    root,
+		servers: merged,
+		clients: make(map[string]*Client),
+		errors:  make(map[string]string),
+	}
+}
+
+// serverFor returns the name of the enabled server handling a file
+func (m *Manager) serverFor(path string) (string, bool) {
+	ext := strings.ToLower(filepath.Ext(path))
+	names := make([]string, 0, len(m.servers))
+	for name := range m.servers {
+		names = append(names, name)
+	}
+	sort.Strings(names) // Deterministic when two servers claim an extension
+	for _, name := range names {
+		s := m.servers[name]
+		if s.Disabled || s.Command == "" {
+			continue
+		}
+		for _, e := range s.Extensions {
+			if strings.EqualFold(e, ext) {
+				return name, true
+			}
+		}
+	}
+	return "", false
+}
+
+// client returns the running client for a server, starting it if needed
+func (m *Manager) client(name string) (*Client, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if msg, failed := m.errors[name]; failed {
+		return nil, false, fmt.Errorf("%s", msg)
+	}
+	if c, ok := m.clients[name]; ok && c.Alive() {
+		return c, false, nil
+	}
+
+	c := NewClient(name, m.servers[name], m.root)
+	ctx, cancel := context.WithTimeout(context.Background(), StartTimeout)
+	defer cancel()
+	if err := c.Start(ctx); err != nil {
+		m.errors[name] = err.Error()
+		return nil, false, err
+	}
+	m.clients[name] = c
+	return c, true, nil
+}
+
+// Handles reports whether some server covers the file
+func (m *Manager) Handles(path string) bool {
+	_, ok := m.serverFor(path)
+	return ok
+}
+
+// Touch sends the file's current contents to its server and waits up to
+// timeout for fresh diagnostics. A server that has just started gets longer
+// since it first has to load the workspace. ok is false when no server
+// handles the file.
+func (m *Manager) Touch(ctx context.Context, path string, timeout time.Duration) (diags []Diagnostic, ok bool, err error) {
+	c, point, started, ok, err := m.sync(path)
+	if !ok || err != nil {
+		return nil, ok, err
+	}
+	if started {
+		timeout *= 4
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return c.Wait(ctx, point), true, nil
+}
+
+// Resync sends the file's current contents to its server without waiting
+// for diagnostics
+func (m *Manager) Resync(path string) error {
+	_, _, _, _, err := m.sync(path)
+	return err
+}
+
+// sync reads a file and sends it to the server handling it, starting the
+// server if needed
+func (m *Manager) sync(path string) (c *Client, point syncPoint, started, ok bool, err error) {
+	name, handled := m.serverFor(path)
+	if !handled {
+		return nil, point, false, false, nil
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, point, false, true, err
+	}
+	data, err := os.ReadFile(abs)
+	if err != nil {
+		return nil, point, false, true, err
+	}
+
+	c, started, err = m.client(name)
+	if err != nil {
+		return nil, point, false, true, err
+	}
+
+	languageID := c.cfg.LanguageID
+	if languageID == "" {
+		languageID = languageIDs[strings.ToLower(filepath.Ext(abs))]
+	}
+	point, err = c.Sync(abs, languageID, string(data))
+	return c, point, started, true, err
+}
+
+// Forget tells the server handling a deleted or moved file to close it
+func (m *Manager) Forget(path string) {
+	name, ok := m.serverFor(path)
+	if !ok {
+		return
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return
+	}
+	m.mu.Lock()
+	c := m.clients[name]
+	m.mu.Unlock()
+	if c != nil && c.Alive() {
+		c.Forget(abs)
+	}
+}
+
+// Outdated lists the open documents of every running server that were
+// changed or removed on disk behind its back, e.g. by a shell command
+func (m *Manager) Outdated() (changed, removed []string) {
+	m.mu.Lock()
+	clients := make([]*Client, 0, len(m.clients))
+	for _, c := range m.clients {
+		clients = append(clients, c)
+	}
+	m.mu.Unlock()
+
+	for _, c := range clients {
+		if !c.Alive() {
+			continue
+		}
+		ch, rm := c.Outdated()
+		changed = append(changed, ch...)
+		removed = append(removed, rm...)
+	}
+	sort.Strings(changed)
+	sort.Strings(removed)
+	return changed, removed
+}
+
+// AllDiagnostics returns the latest diagnostics from every running server,
+// by file path
+func (m *Manager) AllDiagnostics() map[string][]Diagnostic {
+	m.mu.Lock()
+	clients := make([]*Client, 0, len(m.clients))
+	for _, c := range m.clients {
+		clients = append(clients, c)
+	}
+	m.mu.Unlock()
+
+	all := make(map[string][]Diagnostic)
+	for _, c := range clients {
+		for path, d := range c.AllDiagnostics() {
+			all[path] = append(all[path], d...)
+		}
+	}
+	return all
+}
+
+// Status returns the state of every configured server sorted by name
+func (m *Manager) Status() []ServerStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	result := make([]ServerStatus, 0, len(m.servers))
+	for name, s := range m.servers {
+		st := ServerStatus{Name: name, Command: s.Command, Extensions: s.Extensions, Disabled: s.Disabled, Error: m.errors[name]}
+		if c, ok := m.clients[name]; ok && c.Alive() {
+			st.Running = true
+			st.Command = c.ServerName()
+		}
+		result = append(result, st)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// Close shuts every running server down
+func (m *Manager) Close() {
+	m.mu.Lock()
+	clients := m.clients
+	m.clients = make(map[string]*Client)
+	m.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, c := range clients {
+		wg.Add(1)
+		go func(c *Client) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+			defer cancel()
+			c.Shutdown(ctx)
+		}(c)
+	}
+	wg.Wait()
+}
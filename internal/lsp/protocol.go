@@ -0,0 +1,203 @@
+// Package lsp implements a minimal Language Server Protocol client: enough
+// to keep documents in sync with a server and collect the diagnostics it
+// publishes for them.
+package lsp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// JSON-RPC error codes
+const (
+	CodeMethodNotFound = -32601
+	CodeInternalError  = -32603
+)
+
+// Diagnostic severities
+const (
+	SeverityError       = 1
+	SeverityWarning     = 2
+	SeverityInformation = 3
+	SeverityHint        = 4
+)
+
+// message is any JSON-RPC 2.0 message: a request (ID and Method), a
+// notification (Method only) or a response (ID and Result or Error)
+type message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+}
+
+// isResponse reports whether the message answers an earlier request
+func (m *message) isResponse() bool {
+	return m.Method == "" && len(m.ID) > 0
+}
+
+// isRequest reports whether the message is a request expecting a response
+func (m *message) isRequest() bool {
+	return m.Method != "" && len(m.ID) > 0
+}
+
+// RPCError is a JSON-RPC error object
+type RPCError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+// Error implements error
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("%s (code %d)", e.Message, e.Code)
+}
+
+// newRequest builds a request or, with a nil id, a notification
+func newRequest(id json.RawMessage, method string, params interface{}) (*message, error) {
+	msg := &message{JSONRPC: "2.0", ID: id, Method: method}
+	if params != nil {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return nil, err
+		}
+		msg.Params = data
+	}
+	return msg, nil
+}
+
+// newResponse builds a successful response to id
+func newResponse(id json.RawMessage, result interface{}) *message {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return newErrorResponse(id, CodeInternalError, err.Error())
+	}
+	return &message{JSONRPC: "2.0", ID: id, Result: data}
+}
+
+// newErrorResponse builds an error response to id
+func newErrorResponse(id json.RawMessage, code int, msg string) *message {
+	return &message{JSONRPC: "2.0", ID: id, Error: &RPCError{Code: code, Message: msg}}
+}
+
+// Position is a zero-based line and UTF-16 character offset
+type Position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// Range is a span between two positions
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// Diagnostic is an error, warning or hint a server reports for a document
+type Diagnostic struct {
+	Range    Range           `json:"range"`
+	Severity int             `json:"severity,omitempty"`
+	Code     json.RawMessage `json:"code,omitempty"`
+	Source   string          `json:"source,omitempty"`
+	Message  string          `json:"message"`
+}
+
+// SeverityName returns "error", "warning", "info" or "hint"
+func (d Diagnostic) SeverityName() string {
+	switch d.Severity {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	case SeverityInformation:
+		return "info"
+	default:
+		return "hint"
+	}
+}
+
+// publishDiagnosticsParams is the payload of textDocument/publishDiagnostics
+type publishDiagnosticsParams struct {
+	URI         string       `json:"uri"`
+	Version     *int         `json:"version,omitempty"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
+// textDocumentItem opens a document
+type textDocumentItem struct {
+	URI        string `json:"uri"`
+	LanguageID string `json:"languageId"`
+	Version    int    `json:"version"`
+	Text       string `json:"text"`
+}
+
+// versionedTextDocumentIdentifier names a document at a version
+type versionedTextDocumentIdentifier struct {
+	URI     string `json:"uri"`
+	Version int    `json:"version"`
+}
+
+// textDocumentIdentifier names a document
+type textDocumentIdentifier struct {
+	URI string `json:"uri"`
+}
+
+// workspaceFolder is a root the server should index
+type workspaceFolder struct {
+	URI  string `json:"uri"`
+	Name string `json:"name"`
+}
+
+// initializeResult is the part of the initialize response the client uses
+type initializeResult struct {
+	Capabilities struct {
+		// A number (the sync kind) or an options object
+		TextDocumentSync json.RawMessage `json:"textDocumentSync,omitempty"`
+	} `json:"capabilities"`
+	ServerInfo *struct {
+		Name    string `json:"name"`
+		Version string `json:"version,omitempty"`
+	} `json:"serverInfo,omitempty"`
+}
+
+// syncNone reports whether the server asked for no document sync at all
+func (r *initializeResult) syncNone() bool {
+	raw := strings.TrimSpace(string(r.Capabilities.TextDocumentSync))
+	if raw == "0" {
+		return true
+	}
+	var opts struct {
+		Change *int `json:"change"`
+	}
+	if json.Unmarshal([]byte(raw), &opts) == nil && opts.Change != nil {
+		return *opts.Change == 0
+	}
+	return false
+}
+
+// PathToURI converts an absolute file path to a file:// URI
+func PathToURI(path string) string {
+	path = filepath.ToSlash(path)
+	if runtime.GOOS == "windows" {
+		path = "/" + path
+	}
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
+
+// URIToPath converts a file:// URI back to a file path
+func URIToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	path := u.Path
+	if runtime.GOOS == "windows" {
+		path = strings.TrimPrefix(path, "/")
+	}
+	return filepath.FromSlash(path)
+}
@@ -0,0 +1,244 @@
+package lsp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxMessageSize caps a single message read from a server
+const maxMessageSize = 64 * 1024 * 1024
+
+// requestHandler answers requests the server sends to the client and sees
+// its notifications
+type requestHandler func(msg *message) *message
+
+// conn talks to a language server subprocess using the LSP base protocol:
+// JSON-RPC messages framed by Content-Length headers
+type conn struct {
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	handler requestHandler
+	stderr  *tailBuffer
+
+	writeMu sync.Mutex
+	mu      sync.Mutex
+	pending map[string]chan *message
+	done    chan struct{}
+	err     error
+}
+
+// startConn launches the server and begins reading its stdout
+func startConn(command string, args []string, env map[string]string, dir string, handler requestHandler) (*conn, error) {
+	cmd := exec.Command(command, args...)
+	cmd.Dir = dir
+	cmd.Env = os.Environ()
+	for k, v := range env {
+		cmd.Env = append(cmd.Env, k+"="+os.ExpandEnv(v))
+	}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	c := &conn{
+		cmd:     cmd,
+		stdin:   stdin,
+		handler: handler,
+		stderr:  &tailBuffer{max: 4096},
+		pending: make(map[string]chan *message),
+		done:    make(chan struct{}),
+	}
+	cmd.Stderr = c.stderr
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	go c.readLoop(bufio.NewReader(stdout))
+	return c, nil
+}
+
+// readMessage reads one framed message
+func readMessage(r *bufio.Reader) ([]byte, error) {
+	length := -1
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			break
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if ok && strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+			length, err = strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				return nil, fmt.Errorf("bad Content-Length %q", value)
+			}
+		}
+	}
+	if length < 0 || length > maxMessageSize {
+		return nil, fmt.Errorf("bad Content-Length %d", length)
+	}
+	body := make([]byte, length)
+	_, err := io.ReadFull(r, body)
+	return body, err
+}
+
+// readLoop dispatches messages until the server's stdout closes
+func (c *conn) readLoop(r *bufio.Reader) {
+	for {
+		body, err := readMessage(r)
+		if err != nil {
+			break
+		}
+		var msg message
+		if err := json.Unmarshal(body, &msg); err != nil {
+			continue
+		}
+
+		if msg.isResponse() {
+			c.mu.Lock()
+			ch, ok := c.pending[string(msg.ID)]
+			delete(c.pending, string(msg.ID))
+			c.mu.Unlock()
+			if ok {
+				ch <- &msg
+			}
+			continue
+		}
+		if reply := c.handler(&msg); reply != nil && msg.isRequest() {
+			c.write(reply)
+		}
+	}
+
+	c.cmd.Wait()
+	c.mu.Lock()
+	c.err = fmt.Errorf("server exited")
+	if tail := strings.TrimSpace(c.stderr.String()); tail != "" {
+		c.err = fmt.Errorf("server exited: %s", lastLine(tail))
+	}
+	for id, ch := range c.pending {
+		close(ch)
+		delete(c.pending, id)
+	}
+	c.mu.Unlock()
+	close(c.done)
+}
+
+// write sends one framed message
+func (c *conn) write(msg *message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if _, err := fmt.Fprintf(c.stdin, "Content-Length: %d\r\n\r\n", len(data)); err != nil {
+		return err
+	}
+	_, err = c.stdin.Write(data)
+	return err
+}
+
+// call sends a request and waits for the matching response
+func (c *conn) call(ctx context.Context, req *message) (*message, error) {
+	ch := make(chan *message, 1)
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return nil, c.err
+	}
+	c.pending[string(req.ID)] = ch
+	c.mu.Unlock()
+
+	if err := c.write(req); err != nil {
+		c.mu.Lock()
+		delete(c.pending, string(req.ID))
+		c.mu.Unlock()
+		return nil, err
+	}
+
+	select {
+	case resp, ok := <-ch:
+		if !ok {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			return nil, c.err
+		}
+		return resp, nil
+	case <-ctx.Done():
+		c.mu.Lock()
+		delete(c.pending, string(req.ID))
+		c.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+// alive reports whether the server is still running
+func (c *conn) alive() bool {
+	select {
+	case <-c.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// close ends stdin and kills the server if it doesn't exit on its own
+func (c *conn) close() {
+	c.stdin.Close()
+	select {
+	case <-c.done:
+	case <-time.After(2 * time.Second):
+		c.cmd.Process.Kill()
+		<-c.done
+	}
+}
+
+// tailBuffer keeps the last max bytes written, for server stderr
+type tailBuffer struct {
+	mu  sync.Mutex
+	buf []byte
+	max int
+}
+
+// Write implements io.Writer
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf = append(b.buf, p...)
+	if len(b.buf) > b.max {
+		b.buf = append([]byte(nil), b.buf[len(b.buf)-b.max:]...)
+	}
+	return len(p), nil
+}
+
+// String returns the buffered output
+func (b *tailBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return string(b.buf)
+}
+
+// lastLine returns the final line of s
+func lastLine(s string) string {
+	if i := strings.LastIndexByte(s, '\n'); i >= 0 {
+		return s[i+1:]
+	}
+	return s
+}
@@ -0,0 +1,266 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/zesbe/zesbe-go/internal/config"
+)
+
+// ClientInfo identifies zesbe-go to MCP servers
+var ClientInfo = Implementation{Name: "zesbe-go", Version: "1.2.1"}
+
+// Client is a connection to one MCP server
+type Client struct {
+	Name      string
+	Transport string // "stdio" or "http"
+
+	cfg    config.MCPServer
+	t      transport
+	nextID atomic.Int64
+
+	mu        sync.RWMutex
+	info      InitializeResult
+	tools     []Tool
+	resources []Resource
+	prompts   []Prompt
+}
+
+// NewClient creates a client for a configured server without connecting
+func NewClient(name string, cfg config.MCPServer) *Client {
+	c := &Client{Name: name, cfg: cfg, Transport: "stdio"}
+	if cfg.URL != "" {
+		c.Transport = "http"
+	}
+	return c
+}
+
+// Connect starts the transport, runs the initialize handshake and lists
+// the server's tools, resources and prompts
+func (c *Client) Connect(ctx context.Context) error {
+	switch {
+	case c.cfg.URL != "":
+		c.t = newHTTPTransport(c.cfg.URL, c.cfg.Headers, c.handle)
+	case c.cfg.Command != "":
+		t, err := newStdioTransport(c.cfg.Command, c.cfg.Args, c.cfg.Env, c.handle)
+		if err != nil {
+			return fmt.Errorf("failed to start %s: %w", c.cfg.Command, err)
+		}
+		c.t = t
+	default:
+		return fmt.Errorf("server needs either command or url")
+	}
+
+	var info InitializeResult
+	err := c.request(ctx, "initialize", InitializeParams{
+		ProtocolVersion: ProtocolVersion,
+		Capabilities:    json.RawMessage(`{}`),
+		ClientInfo:      ClientInfo,
+	}, &info)
+	if err != nil {
+		c.t.close()
+		return fmt.Errorf("initialize failed: %w", err)
+	}
+
+	n, _ := newRequest(nil, "notifications/initialized", nil)
+	if err := c.t.notify(ctx, n); err != nil {
+		c.t.close()
+		return fmt.Errorf("initialize failed: %w", err)
+	}
+
+	c.mu.Lock()
+	c.info = info
+	c.mu.Unlock()
+
+	return c.Refresh(ctx)
+}
+
+// Refresh re-lists tools, resources and prompts for the capabilities the
+// server declared
+func (c *Client) Refresh(ctx context.Context) error {
+	c.mu.RLock()
+	caps := c.info.Capabilities
+	c.mu.RUnlock()
+
+	var tools []Tool
+	var resources []Resource
+	var prompts []Prompt
+
+	if caps.Tools != nil {
+		err := c.paginate(ctx, "tools/list", func(raw json.RawMessage) (string, error) {
+			var page listToolsResult
+			err := json.Unmarshal(raw, &page)
+			tools = append(tools, page.Tools...)
+			return page.NextCursor, err
+		})
+		if err != nil {
+			return fmt.Errorf("tools/list failed: %w", err)
+		}
+	}
+	if caps.Resources != nil {
+		err := c.paginate(ctx, "resources/list", func(raw json.RawMessage) (string, error) {
+			var page listResourcesResult
+			err := json.Unmarshal(raw, &page)
+			resources = append(resources, page.Resources...)
+			return page.NextCursor, err
+		})
+		if err != nil {
+			return fmt.Errorf("resources/list failed: %w", err)
+		}
+	}
+	if caps.Prompts != nil {
+		err := c.paginate(ctx, "prompts/list", func(raw json.RawMessage) (string, error) {
+			var page listPromptsResult
+			err := json.Unmarshal(raw, &page)
+			prompts = append(prompts, page.Prompts...)
+			return page.NextCursor, err
+		})
+		if err != nil {
+			return fmt.Errorf("prompts/list failed: %w", err)
+		}
+	}
+
+	c.mu.Lock()
+	c.tools, c.resources, c.prompts = tools, resources, prompts
+	c.mu.Unlock()
+	return nil
+}
+
+// paginate calls a list method until the server stops returning a cursor
+func (c *Client) paginate(ctx context.Context, method string, page func(json.RawMessage) (string, error)) error {
+	cursor := ""
+	for {
+		var raw json.RawMessage
+		if err := c.request(ctx, method, listParams{Cursor: cursor}, &raw); err != nil {
+			return err
+		}
+		next, err := page(raw)
+		if err != nil {
+			return err
+		}
+		if next == "" {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+// request sends a request and decodes its result into out
+func (c *Client) request(ctx context.Context, method string, params, out interface{}) error {
+	id := json.RawMessage(strconv.FormatInt(c.nextID.Add(1), 10))
+	req, err := newRequest(id, method, params)
+	if err != nil {
+		return err
+	}
+	resp, err := c.t.call(ctx, req)
+	if err != nil {
+		return err
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(resp.Result, out)
+}
+
+// handle answers server-initiated requests and reacts to list changes
+func (c *Client) handle(msg *message) *message {
+	switch msg.Method {
+	case "ping":
+		return newResponse(msg.ID, struct{}{})
+	case "roots/list":
+		return newResponse(msg.ID, map[string]interface{}{"roots": []interface{}{}})
+	case "notifications/tools/list_changed", "notifications/resources/list_changed", "notifications/prompts/list_changed":
+		// Refresh off the read loop so the list calls can get their replies
+		go c.Refresh(context.Background())
+		return nil
+	}
+	if msg.isRequest() {
+		return newErrorResponse(msg.ID, CodeMethodNotFound, "method not found: "+msg.Method)
+	}
+	return nil
+}
+
+// Info returns the server's initialize result
+func (c *Client) Info() InitializeResult {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.info
+}
+
+// Tools returns the server's tools
+func (c *Client) Tools() []Tool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.tools
+}
+
+// Resources returns the server's resources
+func (c *Client) Resources() []Resource {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.resources
+}
+
+// Prompts returns the server's prompt templates
+func (c *Client) Prompts() []Prompt {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.prompts
+}
+
+// ReadOnly reports whether the user config marks a tool as changing
+// nothing. The server's readOnlyHint is its own claim and isn't trusted.
+func (c *Client) ReadOnly(tool string) bool {
+	for _, name := range c.cfg.ReadOnlyTools {
+		if name == "*" || name == tool {
+			return true
+		}
+	}
+	return false
+}
+
+// CallTool invokes a tool on the server
+func (c *Client) CallTool(ctx context.Context, name string, args map[string]interface{}) (*CallToolResult, error) {
+	var result CallToolResult
+	if err := c.request(ctx, "tools/call", CallToolParams{Name: name, Arguments: args}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ReadResource fetches a resource by URI
+func (c *Client) ReadResource(ctx context.Context, uri string) (*ReadResourceResult, error) {
+	var result ReadResourceResult
+	if err := c.request(ctx, "resources/read", map[string]string{"uri": uri}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetPrompt expands a prompt template with arguments
+func (c *Client) GetPrompt(ctx context.Context, name string, args map[string]string) (*GetPromptResult, error) {
+	params := map[string]interface{}{"name": name}
+	if len(args) > 0 {
+		params["arguments"] = args
+	}
+	var result GetPromptResult
+	if err := c.request(ctx, "prompts/get", params, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Close disconnects from the server
+func (c *Client) Close() error {
+	if c.t == nil {
+		return nil
+	}
+	return c.t.close()
+}
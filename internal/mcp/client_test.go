@@ -0,0 +1,288 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/zesbe/zesbe-go/internal/config"
+)
+
+// helperEnv makes the test binary act as an MCP server; its value picks
+// the server's behaviour
+const helperEnv = "ZESBE_MCP_TEST_SERVER"
+
+// TestHelperServer isn't a real test: it is the stdio server the other
+// tests launch, by running the test binary again with helperEnv set
+func TestHelperServer(t *testing.T) {
+	mode := os.Getenv(helperEnv)
+	if mode == "" {
+		return
+	}
+	if mode == "slow" {
+		time.Sleep(time.Minute)
+	}
+	serveTestServer()
+	os.Exit(0)
+}
+
+// testServer returns the config that launches the helper server
+func testServer(mode string) config.MCPServer {
+	return config.MCPServer{
+		Command: os.Args[0],
+		Args:    []string{"-test.run=^TestHelperServer$"},
+		Env:     map[string]string{helperEnv: mode},
+	}
+}
+
+// serveTestServer answers MCP requests on stdin with a small fixed set of
+// tools, resources and prompts. tools/list comes in two pages.
+func serveTestServer() {
+	tools := []Tool{
+		{Name: "echo", Description: "Echo text", InputSchema: json.RawMessage(`{"type":"object","properties":{"text":{"type":"string"}},"required":["text"]}`)},
+		{Name: "fail", InputSchema: json.RawMessage(`{"type":"object"}`), Annotations: &ToolAnnotations{ReadOnlyHint: true}},
+	}
+	reply := func(msg *message) *message {
+		switch msg.Method {
+		case "initialize":
+			return newResponse(msg.ID, InitializeResult{
+				ProtocolVersion: ProtocolVersion,
+				Capabilities:    ServerCapabilities{Tools: &struct{}{}, Resources: &struct{}{}, Prompts: &struct{}{}},
+				ServerInfo:      Implementation{Name: "test-server", Version: "0.1"},
+			})
+		case "tools/list":
+			var p listParams
+			json.Unmarshal(msg.Params, &p)
+			if p.Cursor == "" {
+				return newResponse(msg.ID, listToolsResult{Tools: tools[:1], NextCursor: "page2"})
+			}
+			return newResponse(msg.ID, listToolsResult{Tools: tools[1:]})
+		case "tools/call":
+			var p struct {
+				Name      string            `json:"name"`
+				Arguments map[string]string `json:"arguments"`
+			}
+			json.Unmarshal(msg.Params, &p)
+			if p.Name == "fail" {
+				return newResponse(msg.ID, CallToolResult{Content: []Content{{Type: "text", Text: "it broke"}}, IsError: true})
+			}
+			return newResponse(msg.ID, CallToolResult{Content: []Content{{Type: "text", Text: "echo: " + p.Arguments["text"]}}})
+		case "resources/list":
+			return newResponse(msg.ID, listResourcesResult{Resources: []Resource{{URI: "file:///notes.md", Name: "notes"}}})
+		case "resources/read":
+			var p struct {
+				URI string `json:"uri"`
+			}
+			json.Unmarshal(msg.Params, &p)
+			if p.URI != "file:///notes.md" {
+				return newErrorResponse(msg.ID, CodeInvalidParams, "no such resource")
+			}
+			return newResponse(msg.ID, ReadResourceResult{Contents: []ResourceContents{{URI: p.URI, Text: "# Notes"}}})
+		case "prompts/list":
+			return newResponse(msg.ID, listPromptsResult{Prompts: []Prompt{{Name: "greet", Arguments: []PromptArgument{{Name: "who", Required: true}}}}})
+		case "prompts/get":
+			var p struct {
+				Name      string            `json:"name"`
+				Arguments map[string]string `json:"arguments"`
+			}
+			json.Unmarshal(msg.Params, &p)
+			text := fmt.Sprintf("Say hello to %s", p.Arguments["who"])
+			return newResponse(msg.ID, GetPromptResult{Messages: []PromptMessage{{Role: "user", Content: Content{Type: "text", Text: text}}}})
+		}
+		if msg.isRequest() {
+			return newErrorResponse(msg.ID, CodeMethodNotFound, "method not found: "+msg.Method)
+		}
+		return nil
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		var msg message
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			continue
+		}
+		if resp := reply(&msg); resp != nil {
+			data, _ := json.Marshal(resp)
+			os.Stdout.Write(append(data, '\n'))
+		}
+	}
+}
+
+// connect starts the helper server and connects a client to it
+func connect(t *testing.T, cfg config.MCPServer) *Client {
+	t.Helper()
+	c := NewClient("test", cfg)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := c.Connect(ctx); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestClientInitialize(t *testing.T) {
+	c := connect(t, testServer("ok"))
+	if c.Transport != "stdio" {
+		t.Errorf("Transport = %q, want stdio", c.Transport)
+	}
+	info := c.Info()
+	if info.ServerInfo.Name != "test-server" || info.ProtocolVersion != ProtocolVersion {
+		t.Errorf("Info = %+v, want test-server speaking %s", info, ProtocolVersion)
+	}
+}
+
+func TestClientListsAllPages(t *testing.T) {
+	c := connect(t, testServer("ok"))
+	var names []string
+	for _, tool := range c.Tools() {
+		names = append(names, tool.Name)
+	}
+	if want := []string{"echo", "fail"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("Tools = %v, want %v", names, want)
+	}
+}
+
+func TestClientCallTool(t *testing.T) {
+	c := connect(t, testServer("ok"))
+	ctx := context.Background()
+
+	result, err := c.CallTool(ctx, "echo", map[string]interface{}{"text": "hi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.IsError || len(result.Content) != 1 || result.Content[0].Text != "echo: hi" {
+		t.Errorf("echo result = %+v", result)
+	}
+
+	result, err = c.CallTool(ctx, "fail", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.IsError {
+		t.Error("fail result isn't marked as an error")
+	}
+}
+
+func TestClientResources(t *testing.T) {
+	c := connect(t, testServer("ok"))
+	resources := c.Resources()
+	if len(resources) != 1 || resources[0].URI != "file:///notes.md" {
+		t.Fatalf("Resources = %+v", resources)
+	}
+
+	result, err := c.ReadResource(context.Background(), resources[0].URI)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Contents) != 1 || result.Contents[0].Text != "# Notes" {
+		t.Errorf("ReadResource = %+v", result)
+	}
+
+	_, err = c.ReadResource(context.Background(), "file:///missing")
+	if rpcErr, ok := err.(*RPCError); !ok || rpcErr.Code != CodeInvalidParams {
+		t.Errorf("ReadResource of a missing URI error = %v, want invalid params", err)
+	}
+}
+
+func TestClientPrompts(t *testing.T) {
+	c := connect(t, testServer("ok"))
+	prompts := c.Prompts()
+	if len(prompts) != 1 || prompts[0].Name != "greet" {
+		t.Fatalf("Prompts = %+v", prompts)
+	}
+
+	result, err := c.GetPrompt(context.Background(), "greet", map[string]string{"who": "Ada"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Messages) != 1 || result.Messages[0].Content.Text != "Say hello to Ada" {
+		t.Errorf("GetPrompt = %+v", result)
+	}
+}
+
+func TestClientReadOnly(t *testing.T) {
+	c := connect(t, testServer("ok"))
+	// The server claims fail is read-only; only the user config counts
+	if c.ReadOnly("fail") || c.ReadOnly("echo") {
+		t.Error("tools read-only without the user config saying so")
+	}
+
+	cfg := testServer("ok")
+	cfg.ReadOnlyTools = []string{"echo"}
+	if c := NewClient("test", cfg); !c.ReadOnly("echo") || c.ReadOnly("fail") {
+		t.Error("read_only_tools: [echo] not applied to echo alone")
+	}
+	cfg.ReadOnlyTools = []string{"*"}
+	if c := NewClient("test", cfg); !c.ReadOnly("fail") {
+		t.Error(`read_only_tools: ["*"] not applied`)
+	}
+}
+
+func TestManagerStartDoesNotBlock(t *testing.T) {
+	m := NewManager()
+	defer m.Close()
+
+	start := time.Now()
+	m.Start(map[string]config.MCPServer{
+		"fast": testServer("ok"),
+		"slow": testServer("slow"),
+		"off":  {Command: "unused", Disabled: true},
+	})
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Start took %s, want it to return before servers connect", elapsed)
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		if _, ok := m.Client("fast"); ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("fast server never connected: %+v", m.Status())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	status := make(map[string]ServerStatus)
+	for _, s := range m.Status() {
+		status[s.Name] = s
+	}
+	if s := status["fast"]; !s.Connected || s.Tools != 2 || s.Resources != 1 || s.Prompts != 1 {
+		t.Errorf("fast status = %+v", s)
+	}
+	if s := status["slow"]; !s.Connecting {
+		t.Errorf("slow status = %+v, want connecting", s)
+	}
+	if s := status["off"]; !s.Disabled {
+		t.Errorf("off status = %+v, want disabled", s)
+	}
+}
+
+func TestManagerCloseAbandonsConnect(t *testing.T) {
+	m := NewManager()
+	m.Start(map[string]config.MCPServer{"slow": testServer("slow")})
+
+	m.Close()
+	done := make(chan struct{})
+	go func() {
+		m.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close didn't stop the pending connection")
+	}
+	if clients := m.Clients(); len(clients) != 0 {
+		t.Errorf("Clients after Close = %d, want 0", len(clients))
+	}
+	if s := m.Status(); len(s) != 1 || s[0].Connected || s[0].Connecting || s[0].Error == "" {
+		t.Errorf("Status after Close = %+v, want an error", s)
+	}
+}
@@ -0,0 +1,151 @@
+package mcp
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/zesbe/zesbe-go/internal/config"
+)
+
+// ConnectTimeout bounds the initialize handshake with each server
+const ConnectTimeout = 15 * time.Second
+
+// ServerStatus describes a configured server for display
+type ServerStatus struct {
+	Name       string
+	Transport  string
+	Connecting bool
+	Connected  bool
+	Disabled   bool
+	Error      string
+	Server     Implementation
+	Tools      int
+	Resources  int
+	Prompts    int
+}
+
+// Manager owns the connections to all configured servers
+type Manager struct {
+	mu      sync.RWMutex
+	clients map[string]*Client
+	status  map[string]ServerStatus
+	closed  bool
+	wg      sync.WaitGroup // Connections in progress
+
+	ctx    context.Context // Cancelled by Close to abandon handshakes
+	cancel context.CancelFunc
+}
+
+// NewManager creates an empty manager
+func NewManager() *Manager {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Manager{clients: make(map[string]*Client), status: make(map[string]ServerStatus), ctx: ctx, cancel: cancel}
+}
+
+// Start connects to every enabled server in the background and returns at
+// once. Each server joins Clients when its handshake completes; servers that
+// fail to connect are recorded in Status and skipped.
+func (m *Manager) Start(servers map[string]config.MCPServer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for name, cfg := range servers {
+		client := NewClient(name, cfg)
+		if cfg.Disabled {
+			m.status[name] = ServerStatus{Name: name, Transport: client.Transport, Disabled: true}
+			continue
+		}
+		m.status[name] = ServerStatus{Name: name, Transport: client.Transport, Connecting: true}
+
+		m.wg.Add(1)
+		go func() {
+			defer m.wg.Done()
+			ctx, cancel := context.WithTimeout(m.ctx, ConnectTimeout)
+			defer cancel()
+			err := client.Connect(ctx)
+
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			switch {
+			case err != nil:
+				m.status[name] = ServerStatus{Name: name, Transport: client.Transport, Error: err.Error()}
+			case m.closed:
+				client.Close()
+			default:
+				m.clients[name] = client
+				m.status[name] = ServerStatus{Name: name, Transport: client.Transport, Connected: true}
+			}
+		}()
+	}
+}
+
+// Wait blocks until every connection started so far has succeeded or failed
+func (m *Manager) Wait() {
+	m.wg.Wait()
+}
+
+// Client returns a connected server by name
+func (m *Manager) Client(name string) (*Client, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	c, ok := m.clients[name]
+	return c, ok
+}
+
+// Clients returns the connected servers sorted by name
+func (m *Manager) Clients() []*Client {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	clients := make([]*Client, 0, len(m.clients))
+	for _, c := range m.clients {
+		clients = append(clients, c)
+	}
+	sort.Slice(clients, func(i, j int) bool { return clients[i].Name < clients[j].Name })
+	return clients
+}
+
+// Status returns the state of every configured server sorted by name
+func (m *Manager) Status() []ServerStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	result := make([]ServerStatus, 0, len(m.status))
+	for name, s := range m.status {
+		if c, ok := m.clients[name]; ok {
+			s.Server = c.Info().ServerInfo
+			s.Tools = len(c.Tools())
+			s.Resources = len(c.Resources())
+			s.Prompts = len(c.Prompts())
+		}
+		result = append(result, s)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// Close disconnects from every server and abandons connections in progress
+func (m *Manager) Close() {
+	m.cancel()
+	m.mu.Lock()
+	m.closed = true
+	clients := m.clients
+	m.clients = make(map[string]*Client)
+	for name, s := range m.status {
+		if s.Connected || s.Connecting {
+			s.Connected, s.Connecting = false, false
+			s.Error = "closed"
+			m.status[name] = s
+		}
+	}
+	m.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, c := range clients {
+		wg.Add(1)
+		go func(c *Client) {
+			defer wg.Done()
+			c.Close()
+		}(c)
+	}
+	wg.Wait()
+}
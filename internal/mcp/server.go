@@ -0,0 +1,123 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// supportedVersions are the protocol revisions the server accepts from
+// clients; anything else is answered with ProtocolVersion
+var supportedVersions = map[string]bool{
+	"2024-11-05": true,
+	"2025-03-26": true,
+	"2025-06-18": true,
+}
+
+// ToolHandler runs a tool called by a client
+type ToolHandler func(ctx context.Context, name string, args map[string]json.RawMessage) CallToolResult
+
+// Server answers MCP requests with a fixed set of tools
+type Server struct {
+	Info         Implementation
+	Instructions string
+	Tools        []Tool
+	CallTool     ToolHandler
+
+	writeMu sync.Mutex
+}
+
+// ServeStdio reads newline-delimited JSON-RPC from r and writes replies to
+// w until r is closed or ctx is cancelled. Requests are handled one at a
+// time, since tools share the process working directory.
+func (s *Server) ServeStdio(ctx context.Context, r io.Reader, w io.Writer) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxMessageSize)
+
+	for scanner.Scan() {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var msg message
+		if err := json.Unmarshal(line, &msg); err != nil {
+			s.write(w, newErrorResponse(json.RawMessage("null"), CodeParseError, "parse error: "+err.Error()))
+			continue
+		}
+		if reply := s.handle(ctx, &msg); reply != nil {
+			s.write(w, reply)
+		}
+	}
+	return scanner.Err()
+}
+
+// write sends one message as a single line
+func (s *Server) write(w io.Writer, msg *message) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	w.Write(append(data, '\n'))
+}
+
+// handle dispatches a request; notifications and responses get no reply
+func (s *Server) handle(ctx context.Context, msg *message) *message {
+	if !msg.isRequest() {
+		return nil
+	}
+
+	switch msg.Method {
+	case "initialize":
+		var params InitializeParams
+		json.Unmarshal(msg.Params, &params)
+		version := ProtocolVersion
+		if supportedVersions[params.ProtocolVersion] {
+			version = params.ProtocolVersion
+		}
+		return newResponse(msg.ID, InitializeResult{
+			ProtocolVersion: version,
+			Capabilities:    ServerCapabilities{Tools: &struct{}{}},
+			ServerInfo:      s.Info,
+			Instructions:    s.Instructions,
+		})
+
+	case "ping":
+		return newResponse(msg.ID, struct{}{})
+
+	case "tools/list":
+		return newResponse(msg.ID, listToolsResult{Tools: s.Tools})
+
+	case "tools/call":
+		var params struct {
+			Name      string                     `json:"name"`
+			Arguments map[string]json.RawMessage `json:"arguments"`
+		}
+		if err := json.Unmarshal(msg.Params, &params); err != nil || params.Name == "" {
+			return newErrorResponse(msg.ID, CodeInvalidParams, "tools/call needs a tool name")
+		}
+		if !s.hasTool(params.Name) {
+			return newErrorResponse(msg.ID, CodeInvalidParams, "unknown tool: "+params.Name)
+		}
+		return newResponse(msg.ID, s.CallTool(ctx, params.Name, params.Arguments))
+	}
+
+	return newErrorResponse(msg.ID, CodeMethodNotFound, "method not found: "+msg.Method)
+}
+
+// hasTool reports whether name is one of the served tools
+func (s *Server) hasTool(name string) bool {
+	for _, t := range s.Tools {
+		if t.Name == name {
+			return true
+		}
+	}
+	return false
+}
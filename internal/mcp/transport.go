@@ -0,0 +1,383 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxMessageSize caps a single JSON-RPC message read from a server
+const maxMessageSize = 16 * 1024 * 1024
+
+// transport carries JSON-RPC messages to and from a server
+type transport interface {
+	// call sends a request and waits for the matching response
+	call(ctx context.Context, req *message) (*message, error)
+	// notify sends a notification
+	notify(ctx context.Context, n *message) error
+	// close shuts the connection down
+	close() error
+}
+
+// requestHandler answers requests the server sends to the client (ping,
+// roots/list, ...) and sees its notifications
+type requestHandler func(msg *message) *message
+
+// stdioTransport talks to a server subprocess over newline-delimited JSON
+type stdioTransport struct {
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	handler requestHandler
+	stderr  *tailBuffer
+
+	writeMu sync.Mutex
+	mu      sync.Mutex
+	pending map[string]chan *message
+	done    chan struct{}
+	err     error
+}
+
+// newStdioTransport starts command and begins reading its stdout
+func newStdioTransport(command string, args []string, env map[string]string, handler requestHandler) (*stdioTransport, error) {
+	cmd := exec.Command(command, args...)
+	cmd.Env = os.Environ()
+	for k, v := range env {
+		cmd.Env = append(cmd.Env, k+"="+os.ExpandEnv(v))
+	}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	t := &stdioTransport{
+		cmd:     cmd,
+		stdin:   stdin,
+		handler: handler,
+		stderr:  &tailBuffer{max: 4096},
+		pending: make(map[string]chan *message),
+		done:    make(chan struct{}),
+	}
+	cmd.Stderr = t.stderr
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	go t.readLoop(stdout)
+	return t, nil
+}
+
+// readLoop dispatches messages until the server's stdout closes
+func (t *stdioTransport) readLoop(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxMessageSize)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var msg message
+		if err := json.Unmarshal(line, &msg); err != nil {
+			continue // Servers sometimes log to stdout; skip non-JSON lines
+		}
+
+		switch {
+		case msg.isResponse():
+			t.mu.Lock()
+			ch, ok := t.pending[string(msg.ID)]
+			delete(t.pending, string(msg.ID))
+			t.mu.Unlock()
+			if ok {
+				ch <- &msg
+			}
+		default:
+			if reply := t.handler(&msg); reply != nil && msg.isRequest() {
+				t.write(reply)
+			}
+		}
+	}
+
+	t.cmd.Wait()
+	t.mu.Lock()
+	t.err = fmt.Errorf("server exited")
+	if tail := strings.TrimSpace(t.stderr.String()); tail != "" {
+		t.err = fmt.Errorf("server exited: %s", lastLine(tail))
+	}
+	for id, ch := range t.pending {
+		close(ch)
+		delete(t.pending, id)
+	}
+	t.mu.Unlock()
+	close(t.done)
+}
+
+// write sends one message as a single line
+func (t *stdioTransport) write(msg *message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	_, err = t.stdin.Write(append(data, '\n'))
+	return err
+}
+
+func (t *stdioTransport) call(ctx context.Context, req *message) (*message, error) {
+	ch := make(chan *message, 1)
+	t.mu.Lock()
+	if t.err != nil {
+		t.mu.Unlock()
+		return nil, t.err
+	}
+	t.pending[string(req.ID)] = ch
+	t.mu.Unlock()
+
+	if err := t.write(req); err != nil {
+		t.mu.Lock()
+		delete(t.pending, string(req.ID))
+		t.mu.Unlock()
+		// A dead server explains itself better on stderr than a broken pipe
+		select {
+		case <-t.done:
+			return nil, t.err
+		case <-time.After(time.Second):
+			return nil, err
+		}
+	}
+
+	select {
+	case resp, ok := <-ch:
+		if !ok {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			return nil, t.err
+		}
+		return resp, nil
+	case <-ctx.Done():
+		t.mu.Lock()
+		delete(t.pending, string(req.ID))
+		t.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+func (t *stdioTransport) notify(ctx context.Context, n *message) error {
+	return t.write(n)
+}
+
+// close ends stdin and kills the server if it doesn't exit on its own
+func (t *stdioTransport) close() error {
+	t.stdin.Close()
+	select {
+	case <-t.done:
+	case <-time.After(2 * time.Second):
+		t.cmd.Process.Kill()
+		<-t.done
+	}
+	return nil
+}
+
+// httpTransport talks to a streamable-HTTP server. Each message is POSTed;
+// the server replies with JSON or an SSE stream carrying the response.
+type httpTransport struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+	handler requestHandler
+
+	mu        sync.Mutex
+	sessionID string
+}
+
+// newHTTPTransport prepares a transport for url; nothing is sent until the
+// first call
+func newHTTPTransport(url string, headers map[string]string, handler requestHandler) *httpTransport {
+	expanded := make(map[string]string, len(headers))
+	for k, v := range headers {
+		expanded[k] = os.ExpandEnv(v)
+	}
+	return &httpTransport{url: url, headers: expanded, client: &http.Client{}, handler: handler}
+}
+
+// post sends msg and returns the HTTP response for the caller to consume
+func (t *httpTransport) post(ctx context.Context, msg *message) (*http.Response, error) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	req.Header.Set("MCP-Protocol-Version", ProtocolVersion)
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	t.mu.Lock()
+	if t.sessionID != "" {
+		req.Header.Set("Mcp-Session-Id", t.sessionID)
+	}
+	t.mu.Unlock()
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if id := resp.Header.Get("Mcp-Session-Id"); id != "" {
+		t.mu.Lock()
+		t.sessionID = id
+		t.mu.Unlock()
+	}
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		resp.Body.Close()
+		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return resp, nil
+}
+
+func (t *httpTransport) call(ctx context.Context, req *message) (*message, error) {
+	resp, err := t.post(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		return t.readStream(ctx, resp.Body, req.ID)
+	}
+
+	var msg message
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxMessageSize)).Decode(&msg); err != nil {
+		return nil, fmt.Errorf("invalid response: %v", err)
+	}
+	return &msg, nil
+}
+
+// readStream reads SSE events until the response to id arrives, answering
+// any server requests sent on the way
+func (t *httpTransport) readStream(ctx context.Context, body io.Reader, id json.RawMessage) (*message, error) {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), maxMessageSize)
+
+	var data strings.Builder
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "data:") {
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+			continue
+		}
+		if line != "" || data.Len() == 0 {
+			continue
+		}
+
+		// A blank line ends the event
+		var msg message
+		err := json.Unmarshal([]byte(data.String()), &msg)
+		data.Reset()
+		if err != nil {
+			continue
+		}
+		if msg.isResponse() {
+			if string(msg.ID) == string(id) {
+				return &msg, nil
+			}
+			continue
+		}
+		if reply := t.handler(&msg); reply != nil && msg.isRequest() {
+			go func() {
+				if resp, err := t.post(ctx, reply); err == nil {
+					resp.Body.Close()
+				}
+			}()
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("stream ended without a response")
+}
+
+func (t *httpTransport) notify(ctx context.Context, n *message) error {
+	resp, err := t.post(ctx, n)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// close ends the server-side session, if one was assigned
+func (t *httpTransport) close() error {
+	t.mu.Lock()
+	id := t.sessionID
+	t.mu.Unlock()
+	if id == "" {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, t.url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Mcp-Session-Id", id)
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// tailBuffer keeps the last max bytes written, for server stderr
+type tailBuffer struct {
+	mu  sync.Mutex
+	buf []byte
+	max int
+}
+
+// Write implements io.Writer
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf = append(b.buf, p...)
+	if len(b.buf) > b.max {
+		b.buf = append([]byte(nil), b.buf[len(b.buf)-b.max:]...)
+	}
+	return len(p), nil
+}
+
+// String returns the buffered output
+func (b *tailBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return string(b.buf)
+}
+
+// lastLine returns the final line of s
+func lastLine(s string) string {
+	if i := strings.LastIndexByte(s, '\n'); i >= 0 {
+		return s[i+1:]
+	}
+	return s
+}
@@ -0,0 +1,242 @@
+// Package prompt builds the system prompt from named sections rendered with
+// Go templates, so every provider gets the same prompt adjusted to what it
+// supports, and users can change single sections instead of the whole text.
+package prompt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/zesbe/zesbe-go/internal/config"
+	"github.com/zesbe/zesbe-go/internal/instructions"
+	"github.com/zesbe/zesbe-go/internal/logger"
+	"github.com/zesbe/zesbe-go/internal/tools"
+)
+
+// Section names
+const (
+	SectionIdentity     = "identity"
+	SectionPrinciples   = "principles"
+	SectionGuidelines   = "guidelines"
+	SectionEnvironment  = "environment"
+	SectionInstructions = "instructions"
+	SectionTools        = "tools"
+)
+
+// Data is what section templates can use
+type Data struct {
+	Provider    string
+	Model       string
+	NativeTools bool // Tools are passed through the API rather than described in the prompt
+	Cwd         string
+	OS          string
+	Arch        string
+	Date        string               // e.g. "Monday, 2 January 2006"
+	Git         *tools.GitStatusInfo // nil outside a git repository
+
+	Instructions     string // ZESBE.md files rendered for the prompt
+	InstructionFiles []instructions.File
+	ToolsPrompt      string // How to call tools as text, for providers without native tools
+}
+
+// defaultSections are the built-in sections in prompt order
+var defaultSections = []struct {
+	name string
+	text string
+}{
+	{SectionIdentity, `You are Zesbe, an enterprise-grade AI coding assistant with direct access to the filesystem and shell.
+
+You are powerful, precise, and proactive. You can read, write, and edit files, run commands, and help with complex coding tasks.`},
+
+	{SectionPrinciples, `## Core Principles
+1. **Be Direct** - Execute actions immediately using tools, don't just describe them
+2. **Be Thorough** - Read files before modifying, understand context before acting
+3. **Be Safe** - Validate operations, handle errors gracefully
+4. **Be Efficient** - Use the right tools for the job, minimize unnecessary operations`},
+
+	{SectionGuidelines, `## Guidelines
+- Use markdown for code blocks with language specifier
+- Always respond in the same language the user uses
+- Use tools proactively when needed - don't ask for permission for basic file operations
+- After using a tool, explain what you found or did clearly
+- If a tool fails, explain the error and try alternatives
+- For complex tasks, break them down into steps
+- Always verify your changes work as expected`},
+
+	{SectionEnvironment, `## Environment
+- Working directory: {{.Cwd}}
+- Platform: {{.OS}}/{{.Arch}}
+- Date: {{.Date}}
+{{- with .Git}}
+- Git branch: {{if .Branch}}{{.Branch}}{{else}}detached at {{.Commit}}{{end}}
+{{- with .Upstream}} tracking {{.}}{{if or $.Git.Ahead $.Git.Behind}} ({{$.Git.Ahead}} ahead, {{$.Git.Behind}} behind){{end}}{{end}}
+- Git status: {{if .Clean}}clean{{else}}{{len .Staged}} staged, {{len .Unstaged}} modified, {{len .Untracked}} untracked{{with .Conflicted}}, {{len .}} conflicted{{end}}{{end}}
+{{- end}}`},
+
+	{SectionInstructions, `{{with .Instructions}}## Project Instructions
+{{.}}{{end}}`},
+
+	{SectionTools, `{{if not .NativeTools}}{{.ToolsPrompt}}{{end}}`},
+}
+
+// section is a parsed section with its optional addition
+type section struct {
+	name   string
+	body   *template.Template // nil when the section was removed
+	append *template.Template
+}
+
+// Builder renders the system prompt
+type Builder struct {
+	sections []section
+}
+
+// New parses the built-in sections and applies the config's overrides.
+// system_prompt replaces the identity, principles and guidelines sections
+// unless prompt_sections sets them. Overrides that don't parse are skipped
+// and reported.
+func New(cfg *config.Config) (*Builder, error) {
+	overrides := make(map[string]config.PromptSection, len(cfg.PromptSections))
+	for name, o := range cfg.PromptSections {
+		overrides[name] = o
+	}
+	if cfg.SystemPrompt != "" {
+		legacy := map[string]string{
+			SectionIdentity:   cfg.SystemPrompt,
+			SectionPrinciples: "",
+			SectionGuidelines: "",
+		}
+		for name, text := range legacy {
+			if o, ok := overrides[name]; !ok || o.Replace == nil {
+				text := text
+				o.Replace = &text
+				overrides[name] = o
+			}
+		}
+	}
+
+	known := make(map[string]bool, len(defaultSections))
+	for _, d := range defaultSections {
+		known[d.name] = true
+	}
+	var errs []error
+	names := make([]string, 0, len(overrides))
+	for name := range overrides {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !known[name] {
+			errs = append(errs, fmt.Errorf("prompt_sections: unknown section %q", name))
+		}
+	}
+
+	b := &Builder{}
+	for _, d := range defaultSections {
+		s := section{name: d.name, body: template.Must(parse(d.name, d.text))}
+		o, ok := overrides[d.name]
+		if ok && o.Replace != nil {
+			s.body = nil
+			if strings.TrimSpace(*o.Replace) != "" {
+				t, err := parse(d.name, *o.Replace)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("prompt_sections[%s].replace: %w", d.name, err))
+					t = template.Must(parse(d.name, d.text))
+				}
+				s.body = t
+			}
+		}
+		if ok && o.Append != "" {
+			t, err := parse(d.name+".append", o.Append)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("prompt_sections[%s].append: %w", d.name, err))
+			} else {
+				s.append = t
+			}
+		}
+		b.sections = append(b.sections, s)
+	}
+	return b, errors.Join(errs...)
+}
+
+// parse parses a section template and checks it runs against Data, so
+// mistakes such as unknown fields surface when the config is loaded
+func parse(name, text string) (*template.Template, error) {
+	t, err := template.New(name).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.Execute(io.Discard, Data{Git: &tools.GitStatusInfo{}}); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Collect gathers the prompt data for a working directory
+func Collect(dir, provider, model string, nativeTools bool) Data {
+	d := Data{
+		Provider:    provider,
+		Model:       model,
+		NativeTools: nativeTools,
+		Cwd:         dir,
+		OS:          runtime.GOOS,
+		Arch:        runtime.GOARCH,
+		Date:        time.Now().Format("Monday, 2 January 2006"),
+	}
+	if info, err := tools.ParseGitStatus(context.Background(), dir); err == nil {
+		d.Git = info
+	}
+	d.InstructionFiles = instructions.Discover(dir)
+	d.Instructions = instructions.Render(d.InstructionFiles)
+	if !nativeTools {
+		d.ToolsPrompt = tools.GetToolsPrompt()
+	}
+	return d
+}
+
+// Render renders the sections that aren't empty, separated by blank lines
+func (b *Builder) Render(d Data) string {
+	var parts []string
+	for _, s := range b.sections {
+		var sb strings.Builder
+		if s.body != nil {
+			if err := s.body.Execute(&sb, d); err != nil {
+				logger.Warnf("Prompt section %s failed: %v", s.name, err)
+				sb.Reset()
+			}
+		}
+		if s.append != nil {
+			var extra strings.Builder
+			if err := s.append.Execute(&extra, d); err != nil {
+				logger.Warnf("Prompt section %s addition failed: %v", s.name, err)
+			} else if text := strings.TrimSpace(extra.String()); text != "" {
+				if strings.TrimSpace(sb.String()) != "" {
+					sb.WriteString("\n")
+				}
+				sb.WriteString(text)
+			}
+		}
+		if text := strings.TrimSpace(sb.String()); text != "" {
+			parts = append(parts, text)
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// Build renders the prompt for the current directory
+func (b *Builder) Build(provider, model string, nativeTools bool) (string, []instructions.File) {
+	dir, err := os.Getwd()
+	if err != nil {
+		dir = "."
+	}
+	d := Collect(dir, provider, model, nativeTools)
+	return b.Render(d), d.InstructionFiles
+}
@@ -0,0 +1,154 @@
+package review
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Export formats
+const (
+	FormatText  = "text"
+	FormatJSON  = "json"
+	FormatSARIF = "sarif"
+)
+
+// sarifRuleID identifies review findings in SARIF output
+const sarifRuleID = "zesbe-review"
+
+// CheckFormat rejects export formats Export doesn't know
+func CheckFormat(format string) error {
+	switch format {
+	case FormatText, FormatJSON, FormatSARIF, "":
+		return nil
+	}
+	return fmt.Errorf("unknown format %q (use text, json or sarif)", format)
+}
+
+// Export renders the report in the given format. version is reported as the
+// tool version in SARIF output.
+func (r *Report) Export(format, version string) ([]byte, error) {
+	switch format {
+	case FormatText, "":
+		return []byte(r.Text()), nil
+	case FormatJSON:
+		return json.MarshalIndent(r, "", "  ")
+	case FormatSARIF:
+		return r.SARIF(version)
+	default:
+		return nil, CheckFormat(format)
+	}
+}
+
+// Text renders the report as compiler-style lines
+func (r *Report) Text() string {
+	var b strings.Builder
+	for _, f := range r.Findings {
+		fmt.Fprintf(&b, "%s: %s: %s\n", f.Location(), f.Severity, f.Title)
+		if f.Message != "" {
+			fmt.Fprintf(&b, "    %s\n", f.Message)
+		}
+		if f.Suggestion != "" {
+			fmt.Fprintf(&b, "    suggestion: %s\n", f.Suggestion)
+		}
+	}
+	for _, e := range r.Errors {
+		fmt.Fprintf(&b, "review failed: %s\n", e)
+	}
+	b.WriteString(r.Summary() + "\n")
+	return b.String()
+}
+
+// SARIF renders the report as a SARIF 2.1.0 log for code scanning tools
+func (r *Report) SARIF(version string) ([]byte, error) {
+	type region struct {
+		StartLine int `json:"startLine"`
+	}
+	type physicalLocation struct {
+		ArtifactLocation struct {
+			URI string `json:"uri"`
+		} `json:"artifactLocation"`
+		Region *region `json:"region,omitempty"`
+	}
+	type location struct {
+		PhysicalLocation physicalLocation `json:"physicalLocation"`
+	}
+	type message struct {
+		Text string `json:"text"`
+	}
+	type result struct {
+		RuleID     string            `json:"ruleId"`
+		Level      string            `json:"level"`
+		Message    message           `json:"message"`
+		Locations  []location        `json:"locations"`
+		Properties map[string]string `json:"properties,omitempty"`
+	}
+
+	results := make([]result, 0, len(r.Findings))
+	for _, f := range r.Findings {
+		var loc physicalLocation
+		loc.ArtifactLocation.URI = (&url.URL{Path: f.File}).String()
+		if f.Line > 0 {
+			loc.Region = &region{StartLine: f.Line}
+		}
+
+		text := f.Title
+		if f.Message != "" {
+			text += ": " + f.Message
+		}
+		res := result{
+			RuleID:    sarifRuleID,
+			Level:     sarifLevel(f.Severity),
+			Message:   message{Text: text},
+			Locations: []location{{PhysicalLocation: loc}},
+		}
+		if f.Suggestion != "" {
+			res.Properties = map[string]string{"suggestion": f.Suggestion}
+		}
+		results = append(results, res)
+	}
+
+	// Chunks that failed are reported as tool notifications, not results
+	notifications := make([]interface{}, 0, len(r.Errors))
+	for _, e := range r.Errors {
+		notifications = append(notifications, map[string]interface{}{"level": "error", "message": message{Text: e}})
+	}
+	invocation := map[string]interface{}{"executionSuccessful": len(r.Errors) == 0}
+	if len(notifications) > 0 {
+		invocation["toolExecutionNotifications"] = notifications
+	}
+
+	log := map[string]interface{}{
+		"$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+		"version": "2.1.0",
+		"runs": []interface{}{map[string]interface{}{
+			"invocations": []interface{}{invocation},
+			"tool": map[string]interface{}{
+				"driver": map[string]interface{}{
+					"name":           "zesbe-go",
+					"version":        version,
+					"informationUri": "https://github.com/zesbe/zesbe-go",
+					"rules": []interface{}{map[string]interface{}{
+						"id":               sarifRuleID,
+						"shortDescription": message{Text: "AI code review finding"},
+					}},
+				},
+			},
+			"results": results,
+		}},
+	}
+	return json.MarshalIndent(log, "", "  ")
+}
+
+// sarifLevel maps a severity onto a SARIF result level
+func sarifLevel(severity string) string {
+	switch severity {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	default:
+		return "note"
+	}
+}
@@ -0,0 +1,400 @@
+// Package review runs an AI code review over a git diff and reports the
+// findings with a severity, location and suggested fix.
+package review
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zesbe/zesbe-go/internal/logger"
+	"github.com/zesbe/zesbe-go/internal/tools"
+)
+
+// DefaultChunkBytes is how much diff goes into a single review request
+const DefaultChunkBytes = 12000
+
+// Severity levels, most serious first
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+	SeverityInfo    = "info"
+)
+
+// Completer sends a single prompt to a model. ai.Client implements it.
+type Completer interface {
+	Complete(ctx context.Context, system, prompt string) (string, error)
+}
+
+// Finding is one issue raised by the review
+type Finding struct {
+	Severity   string `json:"severity"`
+	File       string `json:"file"`
+	Line       int    `json:"line,omitempty"`
+	Title      string `json:"title"`
+	Message    string `json:"message,omitempty"`
+	Suggestion string `json:"suggestion,omitempty"`
+}
+
+// Location returns file:line, or just the file when the line is unknown
+func (f Finding) Location() string {
+	if f.Line > 0 {
+		return fmt.Sprintf("%s:%d", f.File, f.Line)
+	}
+	return f.File
+}
+
+// Report is the result of reviewing a diff
+type Report struct {
+	Range    string    `json:"range"`
+	Files    []string  `json:"files"`
+	Findings []Finding `json:"findings"`
+	Errors   []string  `json:"errors,omitempty"` // Chunks that couldn't be reviewed
+	Created  time.Time `json:"created"`
+}
+
+// Count returns the number of findings with the given severity
+func (r *Report) Count(severity string) int {
+	n := 0
+	for _, f := range r.Findings {
+		if f.Severity == severity {
+			n++
+		}
+	}
+	return n
+}
+
+// Summary describes the findings in one line
+func (r *Report) Summary() string {
+	var summary string
+	if len(r.Findings) == 0 {
+		summary = fmt.Sprintf("No issues found in %d file(s)", len(r.Files))
+	} else {
+		summary = fmt.Sprintf("%d error(s), %d warning(s), %d info in %d file(s)",
+			r.Count(SeverityError), r.Count(SeverityWarning), r.Count(SeverityInfo), len(r.Files))
+	}
+	if len(r.Errors) > 0 {
+		summary += fmt.Sprintf("; %d chunk(s) could not be reviewed", len(r.Errors))
+	}
+	return summary
+}
+
+// FileDiff is the part of a diff that touches one file
+type FileDiff struct {
+	Path string
+	Diff string
+}
+
+// Options controls a review
+type Options struct {
+	Dir        string // Repository directory; "" is the current directory
+	Range      string // Revision range; "" reviews uncommitted changes
+	ChunkBytes int    // Diff bytes per request; 0 uses DefaultChunkBytes
+}
+
+// systemPrompt tells the model what a review should return
+const systemPrompt = `You are a meticulous senior engineer reviewing a code change. Look for bugs, security problems, race conditions, error handling gaps, performance issues and unclear code. Only comment on the changed lines and what they affect; do not nitpick formatting.
+
+Lines of the new version are prefixed with their line number. Reply with a JSON array and nothing else, one object per issue:
+[{"severity": "error|warning|info", "file": "path as shown in the diff", "line": 42, "title": "short summary", "message": "what is wrong and why it matters", "suggestion": "how to fix it"}]
+
+Use "error" for bugs and vulnerabilities, "warning" for likely problems and "info" for improvements. Reply with [] when the change looks good.`
+
+// Run reviews the diff for opts.Range
+func Run(ctx context.Context, c Completer, opts Options) (*Report, error) {
+	dir := opts.Dir
+	if dir == "" {
+		dir = "."
+	}
+	result := tools.GitDiffRange(ctx, dir, opts.Range)
+	if !result.Success {
+		return nil, fmt.Errorf("git diff: %s", result.Error)
+	}
+	return Review(ctx, c, opts, result.Output)
+}
+
+// Review reviews an already collected diff. A chunk the model fails on is
+// recorded in Report.Errors and the rest are still reviewed; Review only
+// fails when ctx is done or no chunk could be reviewed.
+func Review(ctx context.Context, c Completer, opts Options, diff string) (*Report, error) {
+	files := SplitDiff(diff)
+	report := &Report{Range: opts.Range, Files: []string{}, Findings: []Finding{}, Created: time.Now()}
+	if report.Range == "" {
+		report.Range = "working tree"
+	}
+	for _, f := range files {
+		report.Files = append(report.Files, f.Path)
+	}
+
+	limit := opts.ChunkBytes
+	if limit <= 0 {
+		limit = DefaultChunkBytes
+	}
+	chunks := Chunk(files, limit)
+	for _, chunk := range chunks {
+		findings, err := reviewChunk(ctx, c, chunk, limit)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			logger.Warnf("Review chunk failed: %v", err)
+			report.Errors = append(report.Errors, err.Error())
+			continue
+		}
+		report.Findings = append(report.Findings, findings...)
+	}
+	if len(chunks) > 0 && len(report.Errors) == len(chunks) {
+		return nil, fmt.Errorf("%s", report.Errors[0])
+	}
+
+	sort.SliceStable(report.Findings, func(i, j int) bool {
+		a, b := report.Findings[i], report.Findings[j]
+		if rank(a.Severity) != rank(b.Severity) {
+			return rank(a.Severity) < rank(b.Severity)
+		}
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Line < b.Line
+	})
+	return report, nil
+}
+
+// reviewChunk sends one chunk of files to the model
+func reviewChunk(ctx context.Context, c Completer, chunk []FileDiff, limit int) ([]Finding, error) {
+	var prompt strings.Builder
+	known := make(map[string]bool, len(chunk))
+	names := make([]string, 0, len(chunk))
+	for _, f := range chunk {
+		known[f.Path] = true
+		names = append(names, f.Path)
+		prompt.WriteString(tools.TruncateOutput(numberLines(f.Diff), limit, tools.TruncateHead))
+		prompt.WriteString("\n")
+	}
+
+	logger.Infof("Reviewing %s", strings.Join(names, ", "))
+	reply, err := c.Complete(ctx, systemPrompt, prompt.String())
+	if err != nil {
+		return nil, fmt.Errorf("reviewing %s: %w", strings.Join(names, ", "), err)
+	}
+	findings, err := ParseFindings(reply)
+	if err != nil {
+		return nil, fmt.Errorf("reviewing %s: %w", strings.Join(names, ", "), err)
+	}
+
+	kept := findings[:0]
+	for _, f := range findings {
+		if f.File == "" && len(chunk) == 1 {
+			f.File = chunk[0].Path
+		}
+		if !known[f.File] {
+			logger.Warnf("Dropping review finding for file outside the diff: %q", f.File)
+			continue
+		}
+		kept = append(kept, f)
+	}
+	return kept, nil
+}
+
+// ParseFindings extracts the JSON array of findings from a model reply
+func ParseFindings(reply string) ([]Finding, error) {
+	start := strings.Index(reply, "[")
+	end := strings.LastIndex(reply, "]")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("reply has no JSON array of findings")
+	}
+
+	var raw []struct {
+		Severity   string          `json:"severity"`
+		File       string          `json:"file"`
+		Line       json.RawMessage `json:"line"`
+		Title      string          `json:"title"`
+		Message    string          `json:"message"`
+		Suggestion string          `json:"suggestion"`
+	}
+	if err := json.Unmarshal([]byte(reply[start:end+1]), &raw); err != nil {
+		return nil, fmt.Errorf("invalid findings: %v", err)
+	}
+
+	findings := make([]Finding, 0, len(raw))
+	for _, r := range raw {
+		f := Finding{
+			Severity:   normalizeSeverity(r.Severity),
+			File:       strings.TrimPrefix(strings.TrimPrefix(r.File, "b/"), "./"),
+			Title:      strings.TrimSpace(r.Title),
+			Message:    strings.TrimSpace(r.Message),
+			Suggestion: strings.TrimSpace(r.Suggestion),
+		}
+		// Models sometimes quote the number
+		f.Line, _ = strconv.Atoi(strings.Trim(string(r.Line), `"`))
+		if f.Title == "" {
+			f.Title, f.Message = f.Message, ""
+		}
+		if f.Title == "" {
+			continue
+		}
+		findings = append(findings, f)
+	}
+	return findings, nil
+}
+
+// normalizeSeverity maps the names models use onto the three levels
+func normalizeSeverity(s string) string {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "error", "critical", "high", "blocker", "bug":
+		return SeverityError
+	case "warning", "warn", "medium", "major":
+		return SeverityWarning
+	default:
+		return SeverityInfo
+	}
+}
+
+// ParseSeverity checks a severity named by the user, such as --fail-on
+func ParseSeverity(s string) (string, error) {
+	switch severity := strings.ToLower(strings.TrimSpace(s)); severity {
+	case SeverityError, SeverityWarning, SeverityInfo:
+		return severity, nil
+	}
+	return "", fmt.Errorf("unknown severity %q (use error, warning or info)", s)
+}
+
+// AtLeast reports whether severity is as serious as threshold or more.
+// threshold must be one of the three levels; see ParseSeverity.
+func AtLeast(severity, threshold string) bool {
+	return rank(severity) <= rank(threshold)
+}
+
+// rank orders severities for sorting
+func rank(severity string) int {
+	switch severity {
+	case SeverityError:
+		return 0
+	case SeverityWarning:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// SplitDiff splits a unified git diff into per-file parts. Binary and
+// deleted files are left out since there is nothing to review in them.
+func SplitDiff(diff string) []FileDiff {
+	var files []FileDiff
+	var current strings.Builder
+	var path string
+	skip := false
+
+	flush := func() {
+		if current.Len() > 0 && path != "" && !skip {
+			files = append(files, FileDiff{Path: path, Diff: current.String()})
+		}
+		current.Reset()
+		path = ""
+		skip = false
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(diff))
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			flush()
+		case strings.HasPrefix(line, "+++ "):
+			if name := diffPath(line[4:]); name != "" {
+				path = name
+			}
+		case strings.HasPrefix(line, "deleted file mode"), strings.HasPrefix(line, "Binary files "):
+			skip = true
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+	}
+	flush()
+	return files
+}
+
+// diffPath returns the path from a ---/+++ header, without its b/ prefix
+func diffPath(s string) string {
+	s = strings.TrimSuffix(s, "\t")
+	if strings.HasPrefix(s, `"`) {
+		if unquoted, err := strconv.Unquote(s); err == nil {
+			s = unquoted
+		}
+	}
+	if s == "/dev/null" {
+		return ""
+	}
+	return strings.TrimPrefix(s, "b/")
+}
+
+// Chunk groups file diffs into batches of about limit bytes. A file larger
+// than limit gets a batch of its own.
+func Chunk(files []FileDiff, limit int) [][]FileDiff {
+	var chunks [][]FileDiff
+	var current []FileDiff
+	size := 0
+	for _, f := range files {
+		if len(current) > 0 && size+len(f.Diff) > limit {
+			chunks = append(chunks, current)
+			current, size = nil, 0
+		}
+		current = append(current, f)
+		size += len(f.Diff)
+	}
+	if len(current) > 0 {
+		chunks = append(chunks, current)
+	}
+	return chunks
+}
+
+// numberLines prefixes the lines of each hunk with their line number in the
+// new version of the file so findings can point at them
+func numberLines(diff string) string {
+	var b strings.Builder
+	inHunk := false
+	line := 0
+	for _, l := range strings.Split(strings.TrimSuffix(diff, "\n"), "\n") {
+		if strings.HasPrefix(l, "@@") {
+			inHunk = true
+			line = hunkStart(l)
+			b.WriteString(l + "\n")
+			continue
+		}
+		if !inHunk || l == "" {
+			b.WriteString(l + "\n")
+			continue
+		}
+		switch l[0] {
+		case '+', ' ':
+			fmt.Fprintf(&b, "%5d %s\n", line, l)
+			line++
+		default:
+			fmt.Fprintf(&b, "      %s\n", l)
+		}
+	}
+	return b.String()
+}
+
+// hunkStart returns the new-file start line of a "@@ -a,b +c,d @@" header
+func hunkStart(header string) int {
+	i := strings.Index(header, " +")
+	if i < 0 {
+		return 1
+	}
+	rest := header[i+2:]
+	if j := strings.IndexAny(rest, ", "); j >= 0 {
+		rest = rest[:j]
+	}
+	n, err := strconv.Atoi(rest)
+	if err != nil {
+		return 1
+	}
+	return n
+}
@@ -0,0 +1,67 @@
+package review
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// fakeModel answers each review request with the reply for the first file
+// named in the prompt
+type fakeModel map[string]string
+
+func (m fakeModel) Complete(ctx context.Context, system, prompt string) (string, error) {
+	for file, reply := range m {
+		if strings.Contains(prompt, "b/"+file) {
+			return reply, nil
+		}
+	}
+	return "[]", nil
+}
+
+// fileDiff returns a one-line diff adding a file
+func fileDiff(path string) string {
+	return "diff --git a/" + path + " b/" + path + "\n--- a/" + path + "\n+++ b/" + path + "\n@@ -0,0 +1 @@\n+x\n"
+}
+
+func TestReviewKeepsFindingsWhenAChunkFails(t *testing.T) {
+	model := fakeModel{
+		"a.go": `[{"severity":"error","file":"a.go","line":1,"title":"bug"}]`,
+		"b.go": `[{"severity": "error", "title": }]`,
+	}
+	// Room for one file per chunk, with space for the line numbers
+	limit := len(fileDiff("a.go")) + 20
+	report, err := Review(context.Background(), model, Options{ChunkBytes: limit}, fileDiff("a.go")+fileDiff("b.go"))
+	if err != nil {
+		t.Fatalf("Review error = %v, want the failure recorded in the report", err)
+	}
+	if len(report.Findings) != 1 || report.Findings[0].File != "a.go" {
+		t.Errorf("Findings = %+v, want the one for a.go", report.Findings)
+	}
+	if len(report.Errors) != 1 || !strings.Contains(report.Errors[0], "b.go") {
+		t.Errorf("Errors = %v, want the b.go chunk", report.Errors)
+	}
+	if !strings.Contains(report.Summary(), "1 chunk(s) could not be reviewed") {
+		t.Errorf("Summary = %q, want the failure counted", report.Summary())
+	}
+}
+
+func TestReviewFailsWhenEveryChunkFails(t *testing.T) {
+	model := fakeModel{"a.go": "no JSON here"}
+	if _, err := Review(context.Background(), model, Options{}, fileDiff("a.go")); err == nil {
+		t.Error("Review error = nil with nothing reviewed")
+	}
+}
+
+func TestParseSeverity(t *testing.T) {
+	for _, s := range []string{"error", "Warning", " info "} {
+		if _, err := ParseSeverity(s); err != nil {
+			t.Errorf("ParseSeverity(%q) = %v", s, err)
+		}
+	}
+	for _, s := range []string{"", "errors", "critical"} {
+		if _, err := ParseSeverity(s); err == nil {
+			t.Errorf("ParseSeverity(%q) accepted", s)
+		}
+	}
+}
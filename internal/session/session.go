@@ -2,10 +2,12 @@ package session
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -64,14 +66,85 @@ type Store struct {
 	messages []Message
 }
 
+// DefaultDataDir returns the directory sessions are stored in by default
+func DefaultDataDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".zesbe-go", "data"), nil
+}
+
+// Health describes the session database
+type Health struct {
+	Path     string   `json:"path"`
+	Exists   bool     `json:"exists"`
+	Locked   bool     `json:"locked"` // Another process, usually a running chat, holds the database
+	Size     int64    `json:"size"`
+	Sessions int      `json:"sessions"`
+	Messages int      `json:"messages"`
+	Problems []string `json:"problems,omitempty"` // Inconsistencies found in the file
+}
+
+// maxHealthProblems caps the inconsistencies CheckStore collects
+const maxHealthProblems = 10
+
+// CheckStore inspects the session database in dataDir, or the default
+// directory, without changing it. A database another process holds is
+// reported as Locked rather than as an error.
+func CheckStore(dataDir string, timeout time.Duration) (*Health, error) {
+	if dataDir == "" {
+		dir, err := DefaultDataDir()
+		if err != nil {
+			return nil, err
+		}
+		dataDir = dir
+	}
+	h := &Health{Path: filepath.Join(dataDir, "sessions.db")}
+	info, err := os.Stat(h.Path)
+	if os.IsNotExist(err) {
+		return h, nil
+	} else if err != nil {
+		return h, err
+	}
+	h.Exists = true
+	h.Size = info.Size()
+
+	db, err := bolt.Open(h.Path, 0600, &bolt.Options{ReadOnly: true, Timeout: timeout})
+	if errors.Is(err, bolt.ErrTimeout) {
+		h.Locked = true
+		return h, nil
+	}
+	if err != nil {
+		return h, fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	err = db.View(func(tx *bolt.Tx) error {
+		for problem := range tx.Check() {
+			if len(h.Problems) < maxHealthProblems {
+				h.Problems = append(h.Problems, problem.Error())
+			}
+		}
+		if b := tx.Bucket(BucketSessions); b != nil {
+			h.Sessions = b.Stats().KeyN
+		}
+		if b := tx.Bucket(BucketMessages); b != nil {
+			h.Messages = b.Stats().KeyN
+		}
+		return nil
+	})
+	return h, err
+}
+
 // NewStore creates a new session store
 func NewStore(dataDir string) (*Store, error) {
 	if dataDir == "" {
-		home, err := os.UserHomeDir()
+		dir, err := DefaultDataDir()
 		if err != nil {
-			return nil, fmt.Errorf("failed to get home directory: %w", err)
+			return nil, err
 		}
-		dataDir = filepath.Join(home, ".zesbe-go", "data")
+		dataDir = dir
 	}
 
 	// Ensure directory exists
@@ -85,6 +158,9 @@ func NewStore(dataDir string) (*Store, error) {
 	db, err := bolt.Open(dbPath, 0600, &bolt.Options{
 		Timeout: 1 * time.Second,
 	})
+	if errors.Is(err, bolt.ErrTimeout) {
+		return nil, fmt.Errorf("session database is in use by another zesbe-go process: %w", err)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -172,6 +248,31 @@ func (s *Store) GetSession(id string) (*Session, error) {
 	return &session, nil
 }
 
+// FindSession retrieves a session by ID or by a prefix of its ID, such as
+// the eight characters shown in the app, as long as only one session matches
+func (s *Store) FindSession(prefix string) (*Session, error) {
+	if session, err := s.GetSession(prefix); err == nil {
+		return session, nil
+	}
+	sessions, err := s.ListSessions(0)
+	if err != nil {
+		return nil, err
+	}
+	var match *Session
+	for i := range sessions {
+		if prefix != "" && strings.HasPrefix(sessions[i].ID, prefix) {
+			if match != nil {
+				return nil, fmt.Errorf("session ID %s is ambiguous", prefix)
+			}
+			match = &sessions[i]
+		}
+	}
+	if match == nil {
+		return nil, fmt.Errorf("session not found: %s", prefix)
+	}
+	return match, nil
+}
+
 // ListSessions returns all sessions sorted by updated time
 func (s *Store) ListSessions(limit int) ([]Session, error) {
 	var sessions []Session
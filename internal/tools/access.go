@@ -0,0 +1,70 @@
+package tools
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/zesbe/zesbe-go/internal/config"
+)
+
+var (
+	accessMu   sync.RWMutex
+	toolAccess = config.ToolAccessAll
+)
+
+// SetToolAccess limits the tools offered to the model: all of them, only
+// read-only ones, or none. An empty mode means all.
+func SetToolAccess(mode string) error {
+	switch mode {
+	case "":
+		mode = config.ToolAccessAll
+	case config.ToolAccessAll, config.ToolAccessReadOnly, config.ToolAccessNone:
+	default:
+		return fmt.Errorf("tool_access: unknown mode %q (want all, read_only or none)", mode)
+	}
+	accessMu.Lock()
+	toolAccess = mode
+	accessMu.Unlock()
+	return nil
+}
+
+// ToolAccess returns the current tool access mode
+func ToolAccess() string {
+	accessMu.RLock()
+	defer accessMu.RUnlock()
+	return toolAccess
+}
+
+// allowed reports whether the access mode lets the model use a tool
+func allowed(t Tool) bool {
+	switch ToolAccess() {
+	case config.ToolAccessNone:
+		return false
+	case config.ToolAccessReadOnly:
+		return t.IsReadOnly()
+	}
+	return true
+}
+
+// Available returns the registered tools the access mode lets the model use
+func Available() []Tool {
+	var result []Tool
+	for _, t := range DefaultRegistry.List() {
+		if allowed(t) {
+			result = append(result, t)
+		}
+	}
+	return result
+}
+
+// checkAccess refuses a call the access mode doesn't allow
+func checkAccess(name string) (ToolResult, bool) {
+	t, ok := DefaultRegistry.Get(name)
+	if !ok || allowed(t) {
+		return ToolResult{}, true
+	}
+	if ToolAccess() == config.ToolAccessNone {
+		return ToolResult{Success: false, Error: "tools are disabled for this session"}, false
+	}
+	return ToolResult{Success: false, Error: fmt.Sprintf("%s changes files or runs commands, and this session is read-only", name)}, false
+}
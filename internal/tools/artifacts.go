@@ -0,0 +1,169 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zesbe/zesbe-go/internal/config"
+)
+
+// artifactMaxAge is how long spilled outputs of old sessions are kept
+const artifactMaxAge = 7 * 24 * time.Hour
+
+// ArtifactStore keeps full tool outputs that were too large to return to the
+// model, so they can be paged through later by handle
+type ArtifactStore struct {
+	mu   sync.Mutex
+	dir  string
+	next int
+}
+
+// Artifacts is the store used for spilled tool outputs
+var Artifacts = &ArtifactStore{}
+
+// GetArtifactsDir returns the directory holding per-session artifact dirs
+func GetArtifactsDir() string {
+	return filepath.Join(config.GetConfigDir(), "artifacts")
+}
+
+// SetArtifactSession points the store at the directory for a chat session
+// and removes artifact dirs of sessions untouched for a week
+func SetArtifactSession(sessionID string) {
+	Artifacts.mu.Lock()
+	defer Artifacts.mu.Unlock()
+
+	Artifacts.dir = filepath.Join(GetArtifactsDir(), sessionID)
+	Artifacts.next = 0
+
+	// Continue numbering after handles left by an earlier run of this session
+	if entries, err := os.ReadDir(Artifacts.dir); err == nil {
+		for _, e := range entries {
+			if n, err := strconv.Atoi(strings.TrimPrefix(e.Name(), "out-")); err == nil && n > Artifacts.next {
+				Artifacts.next = n
+			}
+		}
+	}
+
+	go pruneArtifacts(Artifacts.dir)
+}
+
+// pruneArtifacts deletes old session artifact dirs other than keep
+func pruneArtifacts(keep string) {
+	entries, err := os.ReadDir(GetArtifactsDir())
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		path := filepath.Join(GetArtifactsDir(), e.Name())
+		info, err := e.Info()
+		if err != nil || !e.IsDir() || path == keep {
+			continue
+		}
+		if time.Since(info.ModTime()) > artifactMaxAge {
+			os.RemoveAll(path)
+		}
+	}
+}
+
+// Save writes output to a new artifact and returns its handle
+func (s *ArtifactStore) Save(output string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.dir == "" {
+		// No session yet: keep artifacts for this process only
+		s.dir = filepath.Join(GetArtifactsDir(), fmt.Sprintf("pid-%d", os.Getpid()))
+	}
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		return "", err
+	}
+
+	s.next++
+	handle := fmt.Sprintf("out-%d", s.next)
+	if err := os.WriteFile(filepath.Join(s.dir, handle), []byte(output), 0600); err != nil {
+		return "", err
+	}
+	return handle, nil
+}
+
+// path resolves a handle to its file, rejecting anything but out-N
+func (s *ArtifactStore) path(handle string) (string, error) {
+	if _, err := strconv.Atoi(strings.TrimPrefix(handle, "out-")); err != nil || !strings.HasPrefix(handle, "out-") {
+		return "", fmt.Errorf("invalid handle %q (expected out-N)", handle)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.dir == "" {
+		return "", fmt.Errorf("no such output: %s", handle)
+	}
+	return filepath.Join(s.dir, handle), nil
+}
+
+// spillOutput stores output that exceeds the tool policy and returns a
+// truncated preview that names the handle
+func spillOutput(name string, output string, p Policy) string {
+	preview := TruncateOutput(output, p.MaxOutputBytes, p.Truncate)
+	if name == "read_tool_output" {
+		return preview + "\n(use a smaller limit or a pattern to see the rest)"
+	}
+
+	handle, err := Artifacts.Save(output)
+	if err != nil {
+		return preview
+	}
+	return preview + fmt.Sprintf("\n[Full output (%d lines, %s) saved as %s; use read_tool_output with handle=%s and offset/limit or pattern to see the rest]",
+		countLines(output), formatSize(int64(len(output))), handle, handle)
+}
+
+// ReadToolOutput pages through or searches an earlier spilled output
+func ReadToolOutput(handle string, offset, limit int, pattern string, context int) ToolResult {
+	path, err := Artifacts.path(handle)
+	if err != nil {
+		return ToolResult{Success: false, Error: err.Error()}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return ToolResult{Success: false, Error: fmt.Sprintf("no such output: %s", handle)}
+	}
+	defer f.Close()
+
+	if pattern == "" {
+		info, err := f.Stat()
+		if err != nil {
+			return ToolResult{Success: false, Error: fmt.Sprintf("failed to read output: %v", err)}
+		}
+		return readLineRange(f, "Output "+handle, info.Size(), offset, limit)
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		re = regexp.MustCompile(regexp.QuoteMeta(pattern))
+	}
+	result, ok := grepFile(filepath.Dir(path), path, re, context)
+	if !ok {
+		return ToolResult{Success: true, Output: "No matches found"}
+	}
+
+	lines := result.lines
+	if limit <= 0 {
+		limit = DefaultMaxResults
+	}
+	lines, truncated := capGrepLines(lines, limit)
+
+	var sb strings.Builder
+	for _, l := range lines {
+		sb.WriteString(l.text)
+		sb.WriteString("\n")
+	}
+	if truncated {
+		sb.WriteString(fmt.Sprintf("... (showing first %d of %d matches; raise limit to see more)\n", limit, result.matches))
+	}
+	return ToolResult{Success: true, Output: sb.String()}
+}
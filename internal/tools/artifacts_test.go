@@ -0,0 +1,151 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zesbe/zesbe-go/internal/config"
+)
+
+// useArtifacts points the artifact store at a fresh directory for a test
+func useArtifacts(t *testing.T) *ArtifactStore {
+	t.Helper()
+	old := Artifacts
+	Artifacts = &ArtifactStore{dir: t.TempDir()}
+	t.Cleanup(func() { Artifacts = old })
+	return Artifacts
+}
+
+// runTool runs a tool with its policy applied
+func runTool(name string, params map[string]string) ToolResult {
+	return ExecuteToolContext(context.Background(), ToolCall{Name: name, Params: params})
+}
+
+func TestSpilledOutputReadBack(t *testing.T) {
+	store := useArtifacts(t)
+	setPolicies(t, map[string]config.ToolPolicy{"read_file": {MaxOutputBytes: 200}})
+	path := numberedFile(t, 100)
+
+	result := runTool("read_file", map[string]string{"path": path})
+	if !result.Success || len(result.Output) > 400 {
+		t.Fatalf("read_file = %d bytes, want a preview near the 200 byte limit", len(result.Output))
+	}
+	m := regexp.MustCompile(`saved as (out-\d+); use read_tool_output`).FindStringSubmatch(result.Output)
+	if m == nil {
+		t.Fatalf("preview names no handle:\n%s", result.Output)
+	}
+	handle := m[1]
+	if data, err := os.ReadFile(filepath.Join(store.dir, handle)); err != nil || !strings.Contains(string(data), "line 100\n") {
+		t.Fatalf("spilled output missing the end of the file: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		params  map[string]string
+		want    []string
+		wantNot []string
+	}{
+		{"page", map[string]string{"offset": "50", "limit": "3"}, []string{"line 48\n", "line 50\n", "use offset=53"}, []string{"line 51\n"}},
+		{"pattern", map[string]string{"pattern": `line 9\d$`}, []string{"line 90", "line 99"}, []string{"line 9\n", "line 100"}},
+		{"literal when not a regex", map[string]string{"pattern": "line 7("}, nil, []string{"line 7"}},
+		{"match limit", map[string]string{"pattern": "line 1", "limit": "2"}, []string{"showing first 2 of"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.params["handle"] = handle
+			r := runTool("read_tool_output", tt.params)
+			if !r.Success {
+				t.Fatalf("read_tool_output: %s", r.Error)
+			}
+			for _, s := range tt.want {
+				if !strings.Contains(r.Output, s) {
+					t.Errorf("output missing %q:\n%s", s, r.Output)
+				}
+			}
+			for _, s := range tt.wantNot {
+				if strings.Contains(r.Output, s) {
+					t.Errorf("output has %q:\n%s", s, r.Output)
+				}
+			}
+		})
+	}
+}
+
+func TestReadToolOutputNotSpilledAgain(t *testing.T) {
+	store := useArtifacts(t)
+	setPolicies(t, map[string]config.ToolPolicy{"read_tool_output": {MaxOutputBytes: 100}})
+	handle, err := store.Save(strings.Repeat("some output line\n", 50))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := runTool("read_tool_output", map[string]string{"handle": handle})
+	if !strings.Contains(r.Output, "use a smaller limit or a pattern") || strings.Contains(r.Output, "saved as") {
+		t.Errorf("output = %q, want a preview pointing at limit and pattern", r.Output)
+	}
+	if entries, _ := os.ReadDir(store.dir); len(entries) != 1 {
+		t.Errorf("%d artifacts, want only the original", len(entries))
+	}
+}
+
+func TestReadToolOutputHandles(t *testing.T) {
+	useArtifacts(t)
+	for _, handle := range []string{"../config.json", "out-", "out-1/../x", "result-1"} {
+		r := ReadToolOutput(handle, 1, 0, "", 0)
+		if r.Success || !strings.Contains(r.Error, "invalid handle") {
+			t.Errorf("ReadToolOutput(%q) = %+v, want an invalid handle error", handle, r)
+		}
+	}
+	if r := ReadToolOutput("out-99", 1, 0, "", 0); r.Success || !strings.Contains(r.Error, "no such output") {
+		t.Errorf("missing handle = %+v, want no such output", r)
+	}
+}
+
+func TestArtifactSession(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	old := Artifacts
+	Artifacts = &ArtifactStore{}
+	defer func() { Artifacts = old }()
+
+	// A resumed session numbers after the handles it left
+	dir := filepath.Join(GetArtifactsDir(), "resumed")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"out-2", "out-7"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stale := filepath.Join(GetArtifactsDir(), "stale")
+	if err := os.MkdirAll(stale, 0700); err != nil {
+		t.Fatal(err)
+	}
+	weekOld := time.Now().Add(-artifactMaxAge - time.Hour)
+	if err := os.Chtimes(stale, weekOld, weekOld); err != nil {
+		t.Fatal(err)
+	}
+
+	SetArtifactSession("resumed")
+	if handle, err := Artifacts.Save("x"); err != nil || handle != "out-8" {
+		t.Errorf("Save = %s, %v, want out-8", handle, err)
+	}
+
+	// Pruning runs in the background; wait for it so it can't outlive HOME
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		if _, err := os.Stat(stale); os.IsNotExist(err) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the week-old session dir was kept")
+		}
+	}
+	if _, err := os.Stat(dir); err != nil {
+		t.Errorf("the current session dir was removed: %v", err)
+	}
+}
@@ -0,0 +1,794 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// builtinTool is a tool implemented in this package
+type builtinTool struct {
+	def       ToolDefinition
+	indicator ToolIndicator
+	readOnly  bool
+	run       func(ctx context.Context, p map[string]string) ToolResult
+}
+
+func (t *builtinTool) Name() string             { return t.def.Name }
+func (t *builtinTool) Schema() ToolDefinition   { return t.def }
+func (t *builtinTool) Indicator() ToolIndicator { return t.indicator }
+func (t *builtinTool) IsReadOnly() bool         { return t.readOnly }
+
+// Execute implements Tool. Git tools are bounded by their policy timeout
+// so a stalled fetch or hook can't hold up the turn.
+func (t *builtinTool) Execute(ctx context.Context, p map[string]string) ToolResult {
+	for _, name := range t.def.Parameters {
+		if err := checkIntParam(p, name); err != nil {
+			return ToolResult{Success: false, Error: err.Error()}
+		}
+	}
+	if t.indicator.Category == "git" {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, PolicyFor(t.def.Name).Timeout)
+		defer cancel()
+	}
+	return t.run(ctx, p)
+}
+
+// intParams names the parameters that take a whole number
+var intParams = map[string]bool{
+	"column": true, "context": true, "count": true, "depth": true, "end": true,
+	"limit": true, "line": true, "max_results": true, "offset": true, "start": true,
+}
+
+// checkIntParam reports a whole-number parameter the model filled with
+// something else, so a typo isn't silently read as the default
+func checkIntParam(p map[string]string, name string) error {
+	v := strings.TrimSpace(p[name])
+	if !intParams[name] || v == "" {
+		return nil
+	}
+	if n, err := strconv.Atoi(v); err != nil || n < 0 {
+		return fmt.Errorf("%s must be a whole number of 0 or more, got %q", name, p[name])
+	}
+	return nil
+}
+
+// intParam parses an integer parameter, falling back to def. Execute has
+// already rejected values that aren't whole numbers.
+func intParam(p map[string]string, name string, def int) int {
+	if n, err := strconv.Atoi(strings.TrimSpace(p[name])); err == nil {
+		return n
+	}
+	return def
+}
+
+// pathParam returns the path parameter, defaulting to the current directory
+func pathParam(p map[string]string) string {
+	if path := p["path"]; path != "" {
+		return path
+	}
+	return "."
+}
+
+// fileList parses a list of paths given as a JSON array or one per line, so
+// paths containing spaces survive
+func fileList(s string) []string {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+	var files []string
+	if strings.HasPrefix(s, "[") && json.Unmarshal([]byte(s), &files) == nil {
+		return files
+	}
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			files = append(files, line)
+		}
+	}
+	return files
+}
+
+// goQuery builds the symbol query shared by the Go code tools
+func goQuery(p map[string]string) GoSymbolQuery {
+	return GoSymbolQuery{
+		Symbol: p["symbol"],
+		File:   p["file"],
+		Line:   intParam(p, "line", 0),
+		Column: intParam(p, "column", 0),
+	}
+}
+
+// goQueryRoot picks the module to search: the one containing file, if given
+func goQueryRoot(p map[string]string) string {
+	if p["file"] != "" {
+		return p["file"]
+	}
+	return "."
+}
+
+// withTimeoutNote prefixes a result with the note from requestTimeout
+func withTimeoutNote(result ToolResult, note string) ToolResult {
+	if note != "" {
+		result.Output = note + "\n" + result.Output
+	}
+	return result
+}
+
+func init() {
+	for _, t := range builtinTools {
+		DefaultRegistry.Register(t)
+	}
+}
+
+// builtinTools lists every built-in tool with its schema, indicator and
+// implementation side by side
+var builtinTools = []*builtinTool{
+	// File Operations
+	{
+		def: ToolDefinition{
+			Name:        "read_file",
+			Description: "Read a file with line numbers. Use offset (1-based start line) and limit (line count) to page through large files",
+			Parameters:  []string{"path", "offset", "limit"},
+			Optional:    []string{"offset", "limit"},
+		},
+		indicator: ToolIndicator{Icon: "📖", Action: "Reading", Description: "Reading file contents", Category: "file", Detail: "path"},
+		readOnly:  true,
+		run: func(ctx context.Context, p map[string]string) ToolResult {
+			if p["path"] == "" {
+				return ToolResult{Success: false, Error: "path parameter required"}
+			}
+			return ReadFileRange(p["path"], intParam(p, "offset", 1), intParam(p, "limit", DefaultReadLimit))
+		},
+	},
+	{
+		def: ToolDefinition{
+			Name:        "write_file",
+			Description: "Write content to a file (creates or overwrites). Configured formatters and linters run afterwards unless skip_hooks is true",
+			Parameters:  []string{"path", "content", "skip_hooks"},
+			Optional:    []string{"skip_hooks"},
+		},
+		indicator: ToolIndicator{Icon: "✍️", Action: "Writing", Description: "Writing file", Category: "file", Detail: "path"},
+		run: func(ctx context.Context, p map[string]string) ToolResult {
+			if p["path"] == "" {
+				return ToolResult{Success: false, Error: "path parameter required"}
+			}
+			return runFileHooks(ctx, p, WriteFile(p["path"], p["content"]))
+		},
+	},
+	{
+		def: ToolDefinition{
+			Name:        "edit_file",
+			Description: "Replace specific text in a file. Configured formatters and linters run afterwards unless skip_hooks is true",
+			Parameters:  []string{"path", "old_content", "new_content", "skip_hooks"},
+			Optional:    []string{"skip_hooks"},
+		},
+		indicator: ToolIndicator{Icon: "📝", Action: "Editing", Description: "Editing file", Category: "file", Detail: "path"},
+		run: func(ctx context.Context, p map[string]string) ToolResult {
+			if p["path"] == "" || p["old_content"] == "" {
+				return ToolResult{Success: false, Error: "path and old_content parameters required"}
+			}
+			return runFileHooks(ctx, p, EditFile(p["path"], p["old_content"], p["new_content"]))
+		},
+	},
+	{
+		def: ToolDefinition{
+			Name:        "list_directory",
+			Description: "List files and folders in a directory",
+			Parameters:  []string{"path"},
+		},
+		indicator: ToolIndicator{Icon: "📁", Action: "Listing", Description: "Listing directory", Category: "file", Detail: "path"},
+		readOnly:  true,
+		run: func(ctx context.Context, p map[string]string) ToolResult {
+			return ListDirectory(pathParam(p))
+		},
+	},
+	{
+		def: ToolDefinition{
+			Name:        "create_directory",
+			Description: "Create a new directory",
+			Parameters:  []string{"path"},
+		},
+		indicator: ToolIndicator{Icon: "📂", Action: "Creating", Description: "Creating directory", Category: "file", Detail: "path"},
+		run: func(ctx context.Context, p map[string]string) ToolResult {
+			if p["path"] == "" {
+				return ToolResult{Success: false, Error: "path parameter required"}
+			}
+			return CreateDirectory(p["path"])
+		},
+	},
+	{
+		def: ToolDefinition{
+			Name:        "delete_file",
+			Description: "Delete a file or empty directory",
+			Parameters:  []string{"path"},
+		},
+		indicator: ToolIndicator{Icon: "🗑️", Action: "Deleting", Description: "Deleting file", Category: "file", Detail: "path"},
+		run: func(ctx context.Context, p map[string]string) ToolResult {
+			if p["path"] == "" {
+				return ToolResult{Success: false, Error: "path parameter required"}
+			}
+			return DeleteFile(p["path"])
+		},
+	},
+	{
+		def: ToolDefinition{
+			Name:        "copy_file",
+			Description: "Copy a file to a new location",
+			Parameters:  []string{"source", "destination"},
+		},
+		indicator: ToolIndicator{Icon: "📋", Action: "Copying", Description: "Copying file", Category: "file", Detail: "source"},
+		run: func(ctx context.Context, p map[string]string) ToolResult {
+			if p["source"] == "" || p["destination"] == "" {
+				return ToolResult{Success: false, Error: "source and destination parameters required"}
+			}
+			return CopyFile(p["source"], p["destination"])
+		},
+	},
+	{
+		def: ToolDefinition{
+			Name:        "move_file",
+			Description: "Move a file to a new location",
+			Parameters:  []string{"source", "destination"},
+		},
+		indicator: ToolIndicator{Icon: "📦", Action: "Moving", Description: "Moving file", Category: "file", Detail: "source"},
+		run: func(ctx context.Context, p map[string]string) ToolResult {
+			if p["source"] == "" || p["destination"] == "" {
+				return ToolResult{Success: false, Error: "source and destination parameters required"}
+			}
+			return MoveFile(p["source"], p["destination"])
+		},
+	},
+
+	// Search & Analysis
+	{
+		def: ToolDefinition{
+			Name:        "glob",
+			Description: "Find files by glob pattern. Supports ** (any depth) and braces, e.g. internal/**/*_test.go or **/*.{ts,tsx}. A pattern without / matches file names at any depth. Honours .gitignore. Returns paths relative to path, newest first (sort=name for alphabetical), up to limit (default 100)",
+			Parameters:  []string{"pattern", "path", "sort", "limit"},
+			Optional:    []string{"path", "sort", "limit"},
+		},
+		indicator: ToolIndicator{Icon: "🗂️", Action: "Globbing", Description: "Matching files", Category: "search", Detail: "pattern"},
+		readOnly:  true,
+		run: func(ctx context.Context, p map[string]string) ToolResult {
+			if p["pattern"] == "" {
+				return ToolResult{Success: false, Error: "pattern parameter required"}
+			}
+			return Glob(pathParam(p), GlobOptions{Pattern: p["pattern"], Sort: p["sort"], Limit: intParam(p, "limit", 0)})
+		},
+	},
+	{
+		def: ToolDefinition{
+			Name:        "find_files",
+			Description: "Search for files matching a glob pattern, sorted by name",
+			Parameters:  []string{"path", "pattern"},
+		},
+		indicator: ToolIndicator{Icon: "🔍", Action: "Searching", Description: "Finding files", Category: "search", Detail: "pattern"},
+		readOnly:  true,
+		run: func(ctx context.Context, p map[string]string) ToolResult {
+			pattern := p["pattern"]
+			if pattern == "" {
+				pattern = "*"
+			}
+			return FindFiles(pathParam(p), pattern)
+		},
+	},
+	{
+		def: ToolDefinition{
+			Name:        "grep_files",
+			Description: "Search file contents with an RE2 regex. Honours .gitignore and skips binaries. file_pattern filters by file name glob (e.g. *.go), ignore_case=true for case-insensitive, context=N for surrounding lines, max_results caps matches (default 100)",
+			Parameters:  []string{"path", "pattern", "file_pattern", "ignore_case", "context", "max_results"},
+			Optional:    []string{"path", "file_pattern", "ignore_case", "context", "max_results"},
+		},
+		indicator: ToolIndicator{Icon: "🔎", Action: "Searching", Description: "Searching in files", Category: "search", Detail: "pattern"},
+		readOnly:  true,
+		run: func(ctx context.Context, p map[string]string) ToolResult {
+			if p["pattern"] == "" {
+				return ToolResult{Success: false, Error: "pattern parameter required"}
+			}
+			return Grep(ctx, pathParam(p), GrepOptions{
+				Pattern:     p["pattern"],
+				FilePattern: p["file_pattern"],
+				IgnoreCase:  p["ignore_case"] == "true",
+				Context:     intParam(p, "context", 0),
+				MaxResults:  intParam(p, "max_results", 0),
+			})
+		},
+	},
+	{
+		def: ToolDefinition{
+			Name:        "code_search",
+			Description: "Case-insensitive literal text search with language filter (go, python, js, ts, rust, etc)",
+			Parameters:  []string{"path", "pattern", "language"},
+			Optional:    []string{"path", "language"},
+		},
+		indicator: ToolIndicator{Icon: "🔬", Action: "Analyzing", Description: "Searching code", Category: "search", Detail: "pattern"},
+		readOnly:  true,
+		run: func(ctx context.Context, p map[string]string) ToolResult {
+			if p["pattern"] == "" {
+				return ToolResult{Success: false, Error: "pattern parameter required"}
+			}
+			return CodeSearch(ctx, pathParam(p), p["pattern"], p["language"])
+		},
+	},
+	{
+		def: ToolDefinition{
+			Name:        "find_todos",
+			Description: "Find TODO, FIXME, HACK comments in code",
+			Parameters:  []string{"path"},
+		},
+		indicator: ToolIndicator{Icon: "📋", Action: "Finding", Description: "Finding TODOs", Category: "analysis"},
+		readOnly:  true,
+		run: func(ctx context.Context, p map[string]string) ToolResult {
+			return FindTodos(pathParam(p))
+		},
+	},
+	{
+		def: ToolDefinition{
+			Name:        "count_lines",
+			Description: "Count lines of code in project",
+			Parameters:  []string{"path"},
+		},
+		indicator: ToolIndicator{Icon: "📏", Action: "Counting", Description: "Counting lines", Category: "analysis"},
+		readOnly:  true,
+		run: func(ctx context.Context, p map[string]string) ToolResult {
+			return CountLines(pathParam(p))
+		},
+	},
+	{
+		def: ToolDefinition{
+			Name:        "analyze_code",
+			Description: "Analyze code structure and statistics",
+			Parameters:  []string{"path"},
+		},
+		indicator: ToolIndicator{Icon: "🔬", Action: "Analyzing", Description: "Analyzing code", Category: "analysis"},
+		readOnly:  true,
+		run: func(ctx context.Context, p map[string]string) ToolResult {
+			return AnalyzeCode(pathParam(p))
+		},
+	},
+	{
+		def: ToolDefinition{
+			Name:        "list_symbols",
+			Description: "List the top-level Go declarations (funcs, methods, types, vars, consts) of a file or package directory with signatures and line numbers. exported_only=true hides unexported ones",
+			Parameters:  []string{"path", "exported_only"},
+			Optional:    []string{"path", "exported_only"},
+		},
+		indicator: ToolIndicator{Icon: "📇", Action: "Listing", Description: "Listing Go symbols", Category: "analysis", Detail: "path"},
+		readOnly:  true,
+		run: func(ctx context.Context, p map[string]string) ToolResult {
+			return ListSymbols(pathParam(p), p["exported_only"] == "true")
+		},
+	},
+	{
+		def: ToolDefinition{
+			Name:        "find_definition",
+			Description: "Find where a Go identifier is declared, with its type and source. Give symbol as Name, Type.Method, pkg.Name or pkg.Type.Method, or point at a use with file and line (plus column or symbol to pick one identifier on the line)",
+			Parameters:  []string{"symbol", "file", "line", "column"},
+			Optional:    []string{"symbol", "file", "line", "column"},
+		},
+		indicator: ToolIndicator{Icon: "🧭", Action: "Locating", Description: "Finding definition", Category: "analysis", Detail: "symbol"},
+		readOnly:  true,
+		run: func(ctx context.Context, p map[string]string) ToolResult {
+			return FindDefinition(goQueryRoot(p), goQuery(p))
+		},
+	},
+	{
+		def: ToolDefinition{
+			Name:        "find_references",
+			Description: "List every use of a Go identifier across the module, including tests, using type information rather than text matching. Identify it like find_definition",
+			Parameters:  []string{"symbol", "file", "line", "column"},
+			Optional:    []string{"symbol", "file", "line", "column"},
+		},
+		indicator: ToolIndicator{Icon: "🔗", Action: "Tracing", Description: "Finding references", Category: "analysis", Detail: "symbol"},
+		readOnly:  true,
+		run: func(ctx context.Context, p map[string]string) ToolResult {
+			return FindReferences(goQueryRoot(p), goQuery(p))
+		},
+	},
+	{
+		def: ToolDefinition{
+			Name:        "package_deps",
+			Description: "Show the Go package import graph of the module. package (import path or directory) focuses on one package: its direct and indirect module imports and the packages importing it",
+			Parameters:  []string{"package"},
+			Optional:    []string{"package"},
+		},
+		indicator: ToolIndicator{Icon: "🕸️", Action: "Mapping", Description: "Mapping package dependencies", Category: "analysis", Detail: "package"},
+		readOnly:  true,
+		run: func(ctx context.Context, p map[string]string) ToolResult {
+			return PackageDeps(".", p["package"])
+		},
+	},
+	{
+		def: ToolDefinition{
+			Name:        "diagnostics",
+			Description: "Get compiler and linter diagnostics from the language server for a file. Without path, list problems in every file the servers have open. severity: error, warning (default), info or all",
+			Parameters:  []string{"path", "severity"},
+			Optional:    []string{"path", "severity"},
+		},
+		indicator: ToolIndicator{Icon: "🩺", Action: "Checking", Description: "Checking diagnostics", Category: "analysis", Detail: "path"},
+		readOnly:  true,
+		run: func(ctx context.Context, p map[string]string) ToolResult {
+			return Diagnostics(ctx, p["path"], p["severity"])
+		},
+	},
+	{
+		def: ToolDefinition{
+			Name:        "project_tree",
+			Description: "Show project structure as tree",
+			Parameters:  []string{"path", "depth"},
+		},
+		indicator: ToolIndicator{Icon: "🌳", Action: "Mapping", Description: "Building project tree", Category: "analysis"},
+		readOnly:  true,
+		run: func(ctx context.Context, p map[string]string) ToolResult {
+			return ProjectTree(pathParam(p), intParam(p, "depth", 3))
+		},
+	},
+
+	// Command Execution
+	{
+		def: ToolDefinition{
+			Name:        "run_command",
+			Description: "Execute a shell command. Set timeout (e.g. 300 or 5m) for long builds or test runs",
+			Parameters:  []string{"command", "timeout"},
+			Optional:    []string{"timeout"},
+		},
+		indicator: ToolIndicator{Icon: "⚡", Action: "Running", Description: "Executing command", Category: "command", Detail: "command"},
+		run: func(ctx context.Context, p map[string]string) ToolResult {
+			if p["command"] == "" {
+				return ToolResult{Success: false, Error: "command parameter required"}
+			}
+			timeout, note, err := requestTimeout(PolicyFor("run_command"), p["timeout"])
+			if err != nil {
+				return ToolResult{Success: false, Error: err.Error()}
+			}
+			return withTimeoutNote(ExecuteCommand(ctx, p["command"], timeout), note)
+		},
+	},
+	{
+		def: ToolDefinition{
+			Name:        "read_tool_output",
+			Description: "Read a large earlier tool output by its handle (e.g. out-3): page with offset/limit lines, or grep with pattern",
+			Parameters:  []string{"handle", "offset", "limit", "pattern", "context"},
+			Optional:    []string{"offset", "limit", "pattern", "context"},
+		},
+		indicator: ToolIndicator{Icon: "📜", Action: "Reading", Description: "Reading saved output", Category: "file", Detail: "handle"},
+		readOnly:  true,
+		run: func(ctx context.Context, p map[string]string) ToolResult {
+			if p["handle"] == "" {
+				return ToolResult{Success: false, Error: "handle parameter required"}
+			}
+			return ReadToolOutput(p["handle"], intParam(p, "offset", 1), intParam(p, "limit", 0), p["pattern"], intParam(p, "context", 0))
+		},
+	},
+
+	// Background Processes
+	{
+		def: ToolDefinition{
+			Name:        "start_process",
+			Description: "Start a long-running shell command in the background (dev server, watcher, long test run). Returns a job id; optionally choose the id yourself",
+			Parameters:  []string{"command", "id"},
+			Optional:    []string{"id"},
+		},
+		indicator: ToolIndicator{Icon: "🚦", Action: "Starting", Description: "Starting background job", Category: "command", Detail: "command"},
+		run: func(ctx context.Context, p map[string]string) ToolResult {
+			if p["command"] == "" {
+				return ToolResult{Success: false, Error: "command parameter required"}
+			}
+			return StartBackgroundProcess(p["id"], p["command"])
+		},
+	},
+	{
+		def: ToolDefinition{
+			Name:        "read_process_output",
+			Description: "Read stdout/stderr a background job produced since the last read, plus its status",
+			Parameters:  []string{"id"},
+		},
+		indicator: ToolIndicator{Icon: "📡", Action: "Polling", Description: "Reading job output", Category: "command", Detail: "id"},
+		readOnly:  true,
+		run: func(ctx context.Context, p map[string]string) ToolResult {
+			if p["id"] == "" {
+				return ToolResult{Success: false, Error: "id parameter required"}
+			}
+			return ReadProcessOutput(p["id"])
+		},
+	},
+	{
+		def: ToolDefinition{
+			Name:        "send_process_input",
+			Description: "Write a line to a background job's stdin",
+			Parameters:  []string{"id", "input"},
+		},
+		indicator: ToolIndicator{Icon: "⌨️", Action: "Sending", Description: "Sending job input", Category: "command", Detail: "id"},
+		run: func(ctx context.Context, p map[string]string) ToolResult {
+			if p["id"] == "" {
+				return ToolResult{Success: false, Error: "id parameter required"}
+			}
+			return SendProcessInput(p["id"], p["input"])
+		},
+	},
+	{
+		def: ToolDefinition{
+			Name:        "kill_process",
+			Description: "Terminate a background job and its child processes",
+			Parameters:  []string{"id"},
+		},
+		indicator: ToolIndicator{Icon: "🛑", Action: "Stopping", Description: "Killing background job", Category: "command", Detail: "id"},
+		run: func(ctx context.Context, p map[string]string) ToolResult {
+			if p["id"] == "" {
+				return ToolResult{Success: false, Error: "id parameter required"}
+			}
+			return KillProcess(p["id"])
+		},
+	},
+	{
+		def: ToolDefinition{
+			Name:        "list_processes",
+			Description: "List background jobs and whether they are still running",
+			Parameters:  []string{},
+		},
+		indicator: ToolIndicator{Icon: "📋", Action: "Listing", Description: "Listing background jobs", Category: "command"},
+		readOnly:  true,
+		run: func(ctx context.Context, p map[string]string) ToolResult {
+			return ListProcesses()
+		},
+	},
+
+	// Git Operations
+	{
+		def: ToolDefinition{
+			Name:        "git_status",
+			Description: "Show the current branch, upstream, ahead/behind counts and staged, unstaged, untracked and conflicted files as JSON",
+			Parameters:  []string{},
+		},
+		indicator: ToolIndicator{Icon: "📊", Action: "Checking", Description: "Git status", Category: "git"},
+		readOnly:  true,
+		run: func(ctx context.Context, p map[string]string) ToolResult {
+			return GitStatus(ctx, ".")
+		},
+	},
+	{
+		def: ToolDefinition{
+			Name:        "git_diff",
+			Description: "Show unstaged changes, or staged ones with staged=true. range diffs revisions instead, e.g. main..HEAD, or a single revision against the working tree. paths limits the diff (JSON array or one per line)",
+			Parameters:  []string{"staged", "range", "paths"},
+			Optional:    []string{"staged", "range", "paths"},
+		},
+		indicator: ToolIndicator{Icon: "📃", Action: "Diffing", Description: "Git diff", Category: "git", Detail: "range"},
+		readOnly:  true,
+		run: func(ctx context.Context, p map[string]string) ToolResult {
+			if p["range"] != "" {
+				return GitDiffRange(ctx, ".", p["range"], fileList(p["paths"])...)
+			}
+			return GitDiff(ctx, ".", p["staged"] == "true", fileList(p["paths"])...)
+		},
+	},
+	{
+		def: ToolDefinition{
+			Name:        "git_log",
+			Description: "List recent commits as JSON (hash, author, date, subject). ref picks a revision or range such as main..HEAD; path keeps only commits touching that path",
+			Parameters:  []string{"count", "ref", "path"},
+			Optional:    []string{"count", "ref", "path"},
+		},
+		indicator: ToolIndicator{Icon: "📜", Action: "Viewing", Description: "Git history", Category: "git", Detail: "path"},
+		readOnly:  true,
+		run: func(ctx context.Context, p map[string]string) ToolResult {
+			return GitLog(ctx, ".", GitLogOptions{Count: intParam(p, "count", 10), Ref: p["ref"], Path: p["path"]})
+		},
+	},
+	{
+		def: ToolDefinition{
+			Name:        "git_show",
+			Description: "Show a commit (default HEAD) with its patch, or only changed files with stat=true. With path, show that file as it was at ref",
+			Parameters:  []string{"ref", "path", "stat"},
+			Optional:    []string{"ref", "path", "stat"},
+		},
+		indicator: ToolIndicator{Icon: "🔖", Action: "Showing", Description: "Git show", Category: "git", Detail: "ref"},
+		readOnly:  true,
+		run: func(ctx context.Context, p map[string]string) ToolResult {
+			return GitShow(ctx, ".", p["ref"], p["path"], p["stat"] == "true")
+		},
+	},
+	{
+		def: ToolDefinition{
+			Name:        "git_blame",
+			Description: "Show the commit, author and date that last changed each line of a file. start and end limit it to a 1-based line range",
+			Parameters:  []string{"path", "start", "end"},
+			Optional:    []string{"start", "end"},
+		},
+		indicator: ToolIndicator{Icon: "🕵️", Action: "Blaming", Description: "Git blame", Category: "git", Detail: "path"},
+		readOnly:  true,
+		run: func(ctx context.Context, p map[string]string) ToolResult {
+			if p["path"] == "" {
+				return ToolResult{Success: false, Error: "path parameter required"}
+			}
+			return GitBlame(ctx, ".", p["path"], intParam(p, "start", 0), intParam(p, "end", 0))
+		},
+	},
+	{
+		def: ToolDefinition{
+			Name:        "git_branch",
+			Description: "List local branches with their upstream and ahead/behind state, the current branch and remote branches as JSON",
+			Parameters:  []string{},
+		},
+		indicator: ToolIndicator{Icon: "🌿", Action: "Branching", Description: "Git branch", Category: "git"},
+		readOnly:  true,
+		run: func(ctx context.Context, p map[string]string) ToolResult {
+			return GitBranch(ctx, ".")
+		},
+	},
+	{
+		def: ToolDefinition{
+			Name:        "git_checkout_branch",
+			Description: "Switch to a branch; create=true creates it first, from start_point if given (default HEAD)",
+			Parameters:  []string{"name", "create", "start_point"},
+			Optional:    []string{"create", "start_point"},
+		},
+		indicator: ToolIndicator{Icon: "🔀", Action: "Switching", Description: "Git checkout", Category: "git", Detail: "name"},
+		run: func(ctx context.Context, p map[string]string) ToolResult {
+			if p["name"] == "" {
+				return ToolResult{Success: false, Error: "name parameter required"}
+			}
+			return GitCheckoutBranch(ctx, ".", p["name"], p["create"] == "true", p["start_point"])
+		},
+	},
+	{
+		def: ToolDefinition{
+			Name:        "git_stash",
+			Description: "Stash work in progress. action is push (default), pop, apply, drop, list or show; message labels a push, include_untracked=true stashes new files too, ref picks an entry (e.g. 0)",
+			Parameters:  []string{"action", "message", "include_untracked", "ref"},
+			Optional:    []string{"action", "message", "include_untracked", "ref"},
+		},
+		indicator: ToolIndicator{Icon: "🗃️", Action: "Stashing", Description: "Git stash", Category: "git", Detail: "action"},
+		run: func(ctx context.Context, p map[string]string) ToolResult {
+			return GitStash(ctx, ".", p["action"], p["message"], p["ref"], p["include_untracked"] == "true")
+		},
+	},
+	{
+		def: ToolDefinition{
+			Name:        "git_add",
+			Description: "Stage files for commit (JSON array or one path per line; default everything) and return the new status",
+			Parameters:  []string{"files"},
+			Optional:    []string{"files"},
+		},
+		indicator: ToolIndicator{Icon: "➕", Action: "Staging", Description: "Git add", Category: "git", Detail: "files"},
+		run: func(ctx context.Context, p map[string]string) ToolResult {
+			return GitAdd(ctx, ".", fileList(p["files"])...)
+		},
+	},
+	{
+		def: ToolDefinition{
+			Name:        "git_commit",
+			Description: "Commit the staged changes. The message needs a subject line of at most 72 characters, then a blank line before any body",
+			Parameters:  []string{"message"},
+		},
+		indicator: ToolIndicator{Icon: "💾", Action: "Committing", Description: "Git commit", Category: "git", Detail: "message"},
+		run: func(ctx context.Context, p map[string]string) ToolResult {
+			if p["message"] == "" {
+				return ToolResult{Success: false, Error: "message parameter required"}
+			}
+			return GitCommit(ctx, ".", p["message"])
+		},
+	},
+	{
+		def: ToolDefinition{
+			Name:        "git_push",
+			Description: "Push the current branch to the upstream it tracks. set_upstream=true publishes a branch without one. Protected branches and force=true are refused unless the user's config allows them",
+			Parameters:  []string{"set_upstream", "force"},
+			Optional:    []string{"set_upstream", "force"},
+		},
+		indicator: ToolIndicator{Icon: "🚀", Action: "Pushing", Description: "Git push", Category: "git"},
+		run: func(ctx context.Context, p map[string]string) ToolResult {
+			return GitPush(ctx, ".", p["force"] == "true", p["set_upstream"] == "true")
+		},
+	},
+	{
+		def: ToolDefinition{
+			Name:        "git_pull",
+			Description: "Update the current branch from its upstream, fast-forward only unless rebase=true",
+			Parameters:  []string{"rebase"},
+			Optional:    []string{"rebase"},
+		},
+		indicator: ToolIndicator{Icon: "⬇️", Action: "Pulling", Description: "Git pull", Category: "git"},
+		run: func(ctx context.Context, p map[string]string) ToolResult {
+			return GitPull(ctx, ".", p["rebase"] == "true")
+		},
+	},
+	{
+		def: ToolDefinition{
+			Name:        "git_clone",
+			Description: "Clone a git repository into dest (defaults to the repository name)",
+			Parameters:  []string{"url", "dest"},
+			Optional:    []string{"dest"},
+		},
+		indicator: ToolIndicator{Icon: "🧬", Action: "Cloning", Description: "Git clone", Category: "git", Detail: "url"},
+		run: func(ctx context.Context, p map[string]string) ToolResult {
+			if p["url"] == "" {
+				return ToolResult{Success: false, Error: "url parameter required"}
+			}
+			return GitClone(ctx, p["url"], p["dest"])
+		},
+	},
+
+	// Web & Network
+	{
+		def: ToolDefinition{
+			Name:        "web_search",
+			Description: "Search the web using DuckDuckGo",
+			Parameters:  []string{"query"},
+		},
+		indicator: ToolIndicator{Icon: "🌐", Action: "Searching", Description: "Searching the web", Category: "web", Detail: "query"},
+		readOnly:  true,
+		run: func(ctx context.Context, p map[string]string) ToolResult {
+			if p["query"] == "" {
+				return ToolResult{Success: false, Error: "query parameter required"}
+			}
+			return WebSearch(ctx, p["query"], PolicyFor("web_search").Timeout)
+		},
+	},
+	{
+		def: ToolDefinition{
+			Name:        "fetch_url",
+			Description: "Fetch content from a URL",
+			Parameters:  []string{"url", "timeout"},
+			Optional:    []string{"timeout"},
+		},
+		indicator: ToolIndicator{Icon: "🌐", Action: "Fetching", Description: "Fetching URL", Category: "web", Detail: "url"},
+		readOnly:  true,
+		run: func(ctx context.Context, p map[string]string) ToolResult {
+			if p["url"] == "" {
+				return ToolResult{Success: false, Error: "url parameter required"}
+			}
+			timeout, note, err := requestTimeout(PolicyFor("fetch_url"), p["timeout"])
+			if err != nil {
+				return ToolResult{Success: false, Error: err.Error()}
+			}
+			return withTimeoutNote(FetchURL(ctx, p["url"], timeout), note)
+		},
+	},
+
+	// System
+	{
+		def: ToolDefinition{
+			Name:        "get_cwd",
+			Description: "Get current working directory",
+			Parameters:  []string{},
+		},
+		indicator: ToolIndicator{Icon: "📍", Action: "Getting", Description: "Current directory", Category: "system"},
+		readOnly:  true,
+		run: func(ctx context.Context, p map[string]string) ToolResult {
+			return GetWorkingDirectory()
+		},
+	},
+	{
+		def: ToolDefinition{
+			Name:        "change_directory",
+			Description: "Change current working directory",
+			Parameters:  []string{"path"},
+		},
+		indicator: ToolIndicator{Icon: "🚶", Action: "Changing", Description: "Changing directory", Category: "system", Detail: "path"},
+		// Not read-only: later relative paths and the persistent shell
+		// resolve against the new directory
+		run: func(ctx context.Context, p map[string]string) ToolResult {
+			if p["path"] == "" {
+				return ToolResult{Success: false, Error: "path parameter required"}
+			}
+			return ChangeDirectory(p["path"])
+		},
+	},
+	{
+		def: ToolDefinition{
+			Name:        "system_info",
+			Description: "Get system information",
+			Parameters:  []string{},
+		},
+		indicator: ToolIndicator{Icon: "💻", Action: "Checking", Description: "System info", Category: "system"},
+		readOnly:  true,
+		run: func(ctx context.Context, p map[string]string) ToolResult {
+			return GetSystemInfo()
+		},
+	},
+}
@@ -0,0 +1,170 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/zesbe/zesbe-go/internal/config"
+)
+
+// placeholder matches a {{param}} reference in a command template
+var placeholder = regexp.MustCompile(`\{\{\s*([a-zA-Z0-9_-]+)\s*\}\}`)
+
+// validToolName matches names the model APIs accept
+var validToolName = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// customTool runs a user-declared command template without a shell
+type customTool struct {
+	def      ToolDefinition
+	command  []string
+	dir      string
+	timeout  time.Duration // 0 uses the tool policy
+	readOnly bool
+}
+
+func (t *customTool) Name() string           { return t.def.Name }
+func (t *customTool) Schema() ToolDefinition { return t.def }
+func (t *customTool) IsReadOnly() bool       { return t.readOnly }
+
+// Indicator implements Tool
+func (t *customTool) Indicator() ToolIndicator {
+	return ToolIndicator{Icon: "🧩", Action: "Running", Description: t.def.Name, Category: "command"}
+}
+
+// Execute implements Tool
+func (t *customTool) Execute(ctx context.Context, params map[string]string) ToolResult {
+	for _, p := range t.def.Parameters {
+		if !t.def.IsOptional(p) && params[p] == "" {
+			return ToolResult{Success: false, Error: fmt.Sprintf("%s parameter required", p)}
+		}
+	}
+
+	timeout := t.timeout
+	if timeout == 0 {
+		timeout = PolicyFor(t.def.Name).Timeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	argv := expandCommand(t.command, params)
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Dir = t.dir
+	return runCmd(ctx, cmd, timeout)
+}
+
+// expandCommand substitutes params into each element of the template. The
+// program name is always kept; any other element whose placeholders all
+// expand to empty is dropped.
+func expandCommand(template []string, params map[string]string) []string {
+	argv := make([]string, 0, len(template))
+	for i, arg := range template {
+		refs := placeholder.FindAllStringSubmatch(arg, -1)
+		empty := len(refs) > 0
+		for _, ref := range refs {
+			if params[ref[1]] != "" {
+				empty = false
+			}
+		}
+		if empty && i > 0 {
+			continue
+		}
+		argv = append(argv, placeholder.ReplaceAllStringFunc(arg, func(m string) string {
+			return params[placeholder.FindStringSubmatch(m)[1]]
+		}))
+	}
+	return argv
+}
+
+// newCustomTool validates a declaration and builds the tool
+func newCustomTool(c config.CustomTool) (*customTool, error) {
+	if !validToolName.MatchString(c.Name) {
+		return nil, fmt.Errorf("name must be 1-64 letters, digits, _ or -")
+	}
+	if strings.HasPrefix(c.Name, mcpPrefix) {
+		return nil, fmt.Errorf("names starting with %s are reserved for MCP tools", mcpPrefix)
+	}
+	if _, exists := DefaultRegistry.Get(c.Name); exists {
+		return nil, fmt.Errorf("a built-in tool has this name")
+	}
+	if len(c.Command) == 0 || strings.TrimSpace(c.Command[0]) == "" {
+		return nil, fmt.Errorf("command is required")
+	}
+
+	schema := c.Parameters
+	if len(schema) == 0 {
+		schema = json.RawMessage(`{"type":"object","properties":{}}`)
+	}
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(schema, &obj); err != nil {
+		return nil, fmt.Errorf("parameters must be a JSON schema object: %v", err)
+	}
+	params, optional := schemaParameters(schema)
+
+	known := make(map[string]bool, len(params))
+	for _, p := range params {
+		known[p] = true
+	}
+	for _, arg := range c.Command {
+		for _, ref := range placeholder.FindAllStringSubmatch(arg, -1) {
+			if !known[ref[1]] {
+				return nil, fmt.Errorf("command uses {{%s}}, which is not in parameters", ref[1])
+			}
+		}
+	}
+
+	t := &customTool{
+		def: ToolDefinition{
+			Name:        c.Name,
+			Description: c.Description,
+			Parameters:  params,
+			Optional:    optional,
+			Schema:      schema,
+		},
+		command:  c.Command,
+		dir:      c.Dir,
+		readOnly: c.ReadOnly,
+	}
+	if c.Timeout != "" {
+		d, err := ParseTimeout(c.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("timeout: %w", err)
+		}
+		t.timeout = d
+	}
+	return t, nil
+}
+
+// SetCustomTools replaces the registered custom tools. When two declarations
+// share a name the later one wins, so project tools listed after user tools
+// override them. Invalid declarations are skipped and reported.
+func SetCustomTools(decls []config.CustomTool) error {
+	DefaultRegistry.SetSource("custom", nil)
+
+	var errs []error
+	byName := make(map[string]int)
+	var list []Tool
+	for _, c := range decls {
+		t, err := newCustomTool(c)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("custom_tools[%s]: %w", c.Name, err))
+			continue
+		}
+		if i, ok := byName[t.Name()]; ok {
+			list[i] = t
+			continue
+		}
+		byName[t.Name()] = len(list)
+		list = append(list, t)
+	}
+
+	if len(list) > 0 {
+		DefaultRegistry.SetSource("custom", func() []Tool { return list })
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,103 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/zesbe/zesbe-go/internal/config"
+)
+
+func TestExpandCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		template []string
+		params   map[string]string
+		want     []string
+	}{
+		{"plain", []string{"make", "test"}, nil, []string{"make", "test"}},
+		{"whole argument", []string{"deploy", "{{env}}"}, map[string]string{"env": "preview"}, []string{"deploy", "preview"}},
+		{"inside an argument", []string{"deploy", "--env={{ env }}"}, map[string]string{"env": "prod"}, []string{"deploy", "--env=prod"}},
+		{"empty optional dropped", []string{"migrate", "--target={{target}}", "--dry-run"}, nil, []string{"migrate", "--dry-run"}},
+		{"one of two set", []string{"x", "{{a}}{{b}}"}, map[string]string{"b": "B"}, []string{"x", "B"}},
+		{"no shell splitting", []string{"echo", "{{msg}}"}, map[string]string{"msg": "a b; rm -rf / $(id)"}, []string{"echo", "a b; rm -rf / $(id)"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := expandCommand(tt.template, tt.params); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expandCommand = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewCustomToolInvalid(t *testing.T) {
+	schema := json.RawMessage(`{"type":"object","properties":{"env":{"type":"string"}},"required":["env"]}`)
+	tests := []struct {
+		name string
+		tool config.CustomTool
+		err  string
+	}{
+		{"bad name", config.CustomTool{Name: "deploy preview", Command: []string{"x"}}, "name must be"},
+		{"mcp prefix", config.CustomTool{Name: mcpPrefix + "x", Command: []string{"x"}}, "reserved for MCP"},
+		{"built-in name", config.CustomTool{Name: "read_file", Command: []string{"x"}}, "built-in tool"},
+		{"no command", config.CustomTool{Name: "deploy"}, "command is required"},
+		{"blank program", config.CustomTool{Name: "deploy", Command: []string{" "}}, "command is required"},
+		{"bad schema", config.CustomTool{Name: "deploy", Command: []string{"x"}, Parameters: json.RawMessage(`[]`)}, "JSON schema object"},
+		{"unknown placeholder", config.CustomTool{Name: "deploy", Command: []string{"x", "{{region}}"}, Parameters: schema}, "{{region}}"},
+		{"bad timeout", config.CustomTool{Name: "deploy", Command: []string{"x"}, Timeout: "soon"}, "timeout"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newCustomTool(tt.tool); err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("newCustomTool = %v, want an error about %s", err, tt.err)
+			}
+		})
+	}
+
+	tool, err := newCustomTool(config.CustomTool{Name: "deploy", Command: []string{"deploy", "{{env}}"}, Parameters: schema, ReadOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if def := tool.Schema(); !reflect.DeepEqual(def.Parameters, []string{"env"}) || def.IsOptional("env") || !tool.IsReadOnly() {
+		t.Errorf("tool = %+v, read-only %v, want env required and read-only", def, tool.IsReadOnly())
+	}
+}
+
+func TestSetCustomTools(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs printf")
+	}
+	defer SetCustomTools(nil)
+
+	schema := json.RawMessage(`{"type":"object","properties":{"msg":{"type":"string"}},"required":["msg"]}`)
+	err := SetCustomTools([]config.CustomTool{
+		{Name: "say", Command: []string{"printf", "user:%s", "{{msg}}"}, Parameters: schema},
+		{Name: "bad"},
+		// A later declaration, as from the project config, replaces the first
+		{Name: "say", Command: []string{"printf", "project:%s", "{{msg}}"}, Parameters: schema},
+	})
+	if err == nil || !strings.Contains(err.Error(), "custom_tools[bad]") {
+		t.Errorf("SetCustomTools = %v, want the bad declaration reported", err)
+	}
+	if _, ok := DefaultRegistry.Get("bad"); ok {
+		t.Error("the invalid tool was registered")
+	}
+
+	msg := `it's "quoted"; $(id) | cat`
+	r := ExecuteToolContext(context.Background(), ToolCall{Name: "say", Params: map[string]string{"msg": msg}})
+	if !r.Success || strings.TrimSpace(r.Output) != "project:"+msg {
+		t.Errorf("say = %+v, want the argument passed through untouched by the project tool", r)
+	}
+	if r := ExecuteToolContext(context.Background(), ToolCall{Name: "say", Params: map[string]string{}}); r.Success || !strings.Contains(r.Error, "msg parameter required") {
+		t.Errorf("say without msg = %+v, want a required parameter error", r)
+	}
+
+	SetCustomTools(nil)
+	if _, ok := DefaultRegistry.Get("say"); ok {
+		t.Error("custom tool still registered after SetCustomTools(nil)")
+	}
+}
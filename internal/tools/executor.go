@@ -1,11 +1,11 @@
 package tools
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"regexp"
 	"strings"
-	"time"
 )
 
 // ToolCall represents a tool invocation from the AI
@@ -19,174 +19,67 @@ type ToolDefinition struct {
 	Name        string   `json:"name"`
 	Description string   `json:"description"`
 	Parameters  []string `json:"parameters"`
+	Optional    []string `json:"optional,omitempty"` // Subset of Parameters that may be omitted
+	// Schema is the full JSON schema of the input when the tool has one,
+	// as MCP tools do; otherwise every parameter is a string
+	Schema json.RawMessage `json:"schema,omitempty"`
 }
 
-// GetToolDefinitions returns all available tool definitions
-func GetToolDefinitions() []ToolDefinition {
-	return []ToolDefinition{
-		// File Operations
-		{
-			Name:        "read_file",
-			Description: "Read the contents of a file",
-			Parameters:  []string{"path"},
-		},
-		{
-			Name:        "write_file",
-			Description: "Write content to a file (creates or overwrites)",
-			Parameters:  []string{"path", "content"},
-		},
-		{
-			Name:        "edit_file",
-			Description: "Replace specific text in a file",
-			Parameters:  []string{"path", "old_content", "new_content"},
-		},
-		{
-			Name:        "list_directory",
-			Description: "List files and folders in a directory",
-			Parameters:  []string{"path"},
-		},
-		{
-			Name:        "create_directory",
-			Description: "Create a new directory",
-			Parameters:  []string{"path"},
-		},
-		{
-			Name:        "delete_file",
-			Description: "Delete a file or empty directory",
-			Parameters:  []string{"path"},
-		},
-		{
-			Name:        "copy_file",
-			Description: "Copy a file to a new location",
-			Parameters:  []string{"source", "destination"},
-		},
-		{
-			Name:        "move_file",
-			Description: "Move a file to a new location",
-			Parameters:  []string{"source", "destination"},
-		},
-
-		// Search & Analysis
-		{
-			Name:        "find_files",
-			Description: "Search for files matching a pattern",
-			Parameters:  []string{"path", "pattern"},
-		},
-		{
-			Name:        "grep_files",
-			Description: "Search for content in files",
-			Parameters:  []string{"path", "pattern", "file_pattern"},
-		},
-		{
-			Name:        "code_search",
-			Description: "Search code with language filter (go, python, js, ts, rust, etc)",
-			Parameters:  []string{"path", "pattern", "language"},
-		},
-		{
-			Name:        "find_todos",
-			Description: "Find TODO, FIXME, HACK comments in code",
-			Parameters:  []string{"path"},
-		},
-		{
-			Name:        "count_lines",
-			Description: "Count lines of code in project",
-			Parameters:  []string{"path"},
-		},
-		{
-			Name:        "analyze_code",
-			Description: "Analyze code structure and statistics",
-			Parameters:  []string{"path"},
-		},
-		{
-			Name:        "project_tree",
-			Description: "Show project structure as tree",
-			Parameters:  []string{"path", "depth"},
-		},
+// IsOptional reports whether a parameter may be omitted
+func (d ToolDefinition) IsOptional(param string) bool {
+	for _, p := range d.Optional {
+		if p == param {
+			return true
+		}
+	}
+	return false
+}
 
-		// Command Execution
-		{
-			Name:        "run_command",
-			Description: "Execute a shell command",
-			Parameters:  []string{"command"},
-		},
+// InputSchema returns the JSON schema for the tool's parameters. Tools
+// without their own schema take every parameter as a string.
+func (d ToolDefinition) InputSchema() json.RawMessage {
+	if len(d.Schema) > 0 {
+		return d.Schema
+	}
 
-		// Git Operations
-		{
-			Name:        "git_status",
-			Description: "Show git repository status",
-			Parameters:  []string{},
-		},
-		{
-			Name:        "git_diff",
-			Description: "Show git diff (use staged=true for staged changes)",
-			Parameters:  []string{"staged"},
-		},
-		{
-			Name:        "git_log",
-			Description: "Show recent git commits",
-			Parameters:  []string{"count"},
-		},
-		{
-			Name:        "git_branch",
-			Description: "Show git branches",
-			Parameters:  []string{},
-		},
-		{
-			Name:        "git_add",
-			Description: "Stage files for commit",
-			Parameters:  []string{"files"},
-		},
-		{
-			Name:        "git_commit",
-			Description: "Create a git commit",
-			Parameters:  []string{"message"},
-		},
-		{
-			Name:        "git_push",
-			Description: "Push to remote repository",
-			Parameters:  []string{"remote", "branch"},
-		},
-		{
-			Name:        "git_pull",
-			Description: "Pull from remote repository",
-			Parameters:  []string{"remote", "branch"},
-		},
+	properties := make(map[string]map[string]string)
+	required := []string{}
+	for _, param := range d.Parameters {
+		properties[param] = map[string]string{
+			"type":        "string",
+			"description": fmt.Sprintf("The %s parameter", param),
+		}
+		if !d.IsOptional(param) {
+			required = append(required, param)
+		}
+	}
 
-		// Web & Network
-		{
-			Name:        "web_search",
-			Description: "Search the web using DuckDuckGo",
-			Parameters:  []string{"query"},
-		},
-		{
-			Name:        "fetch_url",
-			Description: "Fetch content from a URL",
-			Parameters:  []string{"url"},
-		},
+	schema, _ := json.Marshal(map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	})
+	return schema
+}
 
-		// System
-		{
-			Name:        "get_cwd",
-			Description: "Get current working directory",
-			Parameters:  []string{},
-		},
-		{
-			Name:        "change_directory",
-			Description: "Change current working directory",
-			Parameters:  []string{"path"},
-		},
-		{
-			Name:        "system_info",
-			Description: "Get system information",
-			Parameters:  []string{},
-		},
+// GetToolDefinitions returns the definitions of the tools the model may use
+func GetToolDefinitions() []ToolDefinition {
+	tools := Available()
+	defs := make([]ToolDefinition, len(tools))
+	for i, t := range tools {
+		defs[i] = t.Schema()
 	}
+	return defs
 }
 
-// GetToolsPrompt generates the system prompt section describing available tools
+// GetToolsPrompt generates the system prompt section describing available
+// tools, or "" when the model may use none
 func GetToolsPrompt() string {
+	if len(Available()) == 0 {
+		return ""
+	}
 	var sb strings.Builder
-	sb.WriteString("\n\n## Available Tools\n\n")
+	sb.WriteString("## Available Tools\n\n")
 	sb.WriteString("You can use tools by outputting a <tool_call> block. Format:\n\n")
 	sb.WriteString("```\n<tool_call>\n{\"name\": \"tool_name\", \"params\": {\"param1\": \"value1\"}}\n</tool_call>\n```\n\n")
 	sb.WriteString("Available tools:\n\n")
@@ -194,7 +87,14 @@ func GetToolsPrompt() string {
 	for _, tool := range GetToolDefinitions() {
 		params := "none"
 		if len(tool.Parameters) > 0 {
-			params = strings.Join(tool.Parameters, ", ")
+			names := make([]string, 0, len(tool.Parameters))
+			for _, p := range tool.Parameters {
+				if tool.IsOptional(p) {
+					p += " (optional)"
+				}
+				names = append(names, p)
+			}
+			params = strings.Join(names, ", ")
 		}
 		sb.WriteString(fmt.Sprintf("- **%s**: %s\n  Parameters: %s\n\n", tool.Name, tool.Description, params))
 	}
@@ -219,9 +119,12 @@ func ParseToolCalls(response string) []ToolCall {
 
 	for _, match := range matches {
 		if len(match) > 1 {
-			var call ToolCall
-			if err := json.Unmarshal([]byte(match[1]), &call); err == nil {
-				calls = append(calls, call)
+			var raw struct {
+				Name   string                     `json:"name"`
+				Params map[string]json.RawMessage `json:"params"`
+			}
+			if err := json.Unmarshal([]byte(match[1]), &raw); err == nil {
+				calls = append(calls, ToolCall{Name: raw.Name, Params: StringParams(raw.Params)})
 			}
 		}
 	}
@@ -229,6 +132,21 @@ func ParseToolCalls(response string) []ToolCall {
 	return calls
 }
 
+// StringParams flattens JSON arguments to strings. Strings are unquoted;
+// numbers, booleans, arrays and objects keep their JSON text.
+func StringParams(raw map[string]json.RawMessage) map[string]string {
+	params := make(map[string]string, len(raw))
+	for k, v := range raw {
+		var str string
+		if err := json.Unmarshal(v, &str); err == nil {
+			params[k] = str
+		} else {
+			params[k] = string(v)
+		}
+	}
+	return params
+}
+
 // RemoveToolCalls removes tool_call blocks from response for display
 func RemoveToolCalls(response string) string {
 	re := regexp.MustCompile(`(?s)<tool_call>\s*\{.*?\}\s*</tool_call>`)
@@ -238,221 +156,20 @@ func RemoveToolCalls(response string) string {
 	return strings.TrimSpace(cleaned)
 }
 
-// ExecuteTool executes a tool call and returns the result
+// ExecuteTool executes a tool call and applies the tool's output policy
 func ExecuteTool(call ToolCall) ToolResult {
-	switch call.Name {
-	// File Operations
-	case "read_file":
-		path := call.Params["path"]
-		if path == "" {
-			return ToolResult{Success: false, Error: "path parameter required"}
-		}
-		return ReadFile(path)
-
-	case "write_file":
-		path := call.Params["path"]
-		content := call.Params["content"]
-		if path == "" {
-			return ToolResult{Success: false, Error: "path parameter required"}
-		}
-		return WriteFile(path, content)
-
-	case "edit_file":
-		path := call.Params["path"]
-		oldContent := call.Params["old_content"]
-		newContent := call.Params["new_content"]
-		if path == "" || oldContent == "" {
-			return ToolResult{Success: false, Error: "path and old_content parameters required"}
-		}
-		return EditFile(path, oldContent, newContent)
-
-	case "list_directory":
-		path := call.Params["path"]
-		if path == "" {
-			path = "."
-		}
-		return ListDirectory(path)
-
-	case "create_directory":
-		path := call.Params["path"]
-		if path == "" {
-			return ToolResult{Success: false, Error: "path parameter required"}
-		}
-		return CreateDirectory(path)
-
-	case "delete_file":
-		path := call.Params["path"]
-		if path == "" {
-			return ToolResult{Success: false, Error: "path parameter required"}
-		}
-		return DeleteFile(path)
-
-	case "copy_file":
-		src := call.Params["source"]
-		dst := call.Params["destination"]
-		if src == "" || dst == "" {
-			return ToolResult{Success: false, Error: "source and destination parameters required"}
-		}
-		return CopyFile(src, dst)
-
-	case "move_file":
-		src := call.Params["source"]
-		dst := call.Params["destination"]
-		if src == "" || dst == "" {
-			return ToolResult{Success: false, Error: "source and destination parameters required"}
-		}
-		return MoveFile(src, dst)
-
-	// Search & Analysis
-	case "find_files":
-		path := call.Params["path"]
-		pattern := call.Params["pattern"]
-		if path == "" {
-			path = "."
-		}
-		if pattern == "" {
-			pattern = "*"
-		}
-		return FindFiles(path, pattern)
-
-	case "grep_files":
-		path := call.Params["path"]
-		pattern := call.Params["pattern"]
-		filePattern := call.Params["file_pattern"]
-		if path == "" {
-			path = "."
-		}
-		if pattern == "" {
-			return ToolResult{Success: false, Error: "pattern parameter required"}
-		}
-		return GrepFiles(path, pattern, filePattern)
-
-	case "code_search":
-		path := call.Params["path"]
-		pattern := call.Params["pattern"]
-		language := call.Params["language"]
-		if path == "" {
-			path = "."
-		}
-		if pattern == "" {
-			return ToolResult{Success: false, Error: "pattern parameter required"}
-		}
-		return CodeSearch(path, pattern, language)
-
-	case "find_todos":
-		path := call.Params["path"]
-		if path == "" {
-			path = "."
-		}
-		return FindTodos(path)
-
-	case "count_lines":
-		path := call.Params["path"]
-		if path == "" {
-			path = "."
-		}
-		return CountLines(path)
-
-	case "analyze_code":
-		path := call.Params["path"]
-		if path == "" {
-			path = "."
-		}
-		return AnalyzeCode(path)
-
-	case "project_tree":
-		path := call.Params["path"]
-		if path == "" {
-			path = "."
-		}
-		depth := 3
-		if d := call.Params["depth"]; d != "" {
-			fmt.Sscanf(d, "%d", &depth)
-		}
-		return ProjectTree(path, depth)
-
-	// Command Execution
-	case "run_command":
-		command := call.Params["command"]
-		if command == "" {
-			return ToolResult{Success: false, Error: "command parameter required"}
-		}
-		return ExecuteCommand(command, 30*time.Second)
-
-	// Git Operations
-	case "git_status":
-		return GitStatus(".")
-
-	case "git_diff":
-		staged := call.Params["staged"] == "true"
-		return GitDiff(".", staged)
-
-	case "git_log":
-		count := 10
-		if c := call.Params["count"]; c != "" {
-			fmt.Sscanf(c, "%d", &count)
-		}
-		return GitLog(".", count)
-
-	case "git_branch":
-		return GitBranch(".")
-
-	case "git_add":
-		files := call.Params["files"]
-		if files == "" {
-			files = "."
-		}
-		return GitAdd(".", strings.Fields(files)...)
-
-	case "git_commit":
-		message := call.Params["message"]
-		if message == "" {
-			return ToolResult{Success: false, Error: "message parameter required"}
-		}
-		return GitCommit(".", message)
-
-	case "git_push":
-		remote := call.Params["remote"]
-		branch := call.Params["branch"]
-		return GitPush(".", remote, branch)
-
-	case "git_pull":
-		remote := call.Params["remote"]
-		branch := call.Params["branch"]
-		return GitPull(".", remote, branch)
-
-	// Web & Network
-	case "web_search":
-		query := call.Params["query"]
-		if query == "" {
-			return ToolResult{Success: false, Error: "query parameter required"}
-		}
-		return WebSearch(query)
-
-	case "fetch_url":
-		url := call.Params["url"]
-		if url == "" {
-			return ToolResult{Success: false, Error: "url parameter required"}
-		}
-		return FetchURL(url)
-
-	// System
-	case "get_cwd":
-		return GetWorkingDirectory()
-
-	case "change_directory":
-		path := call.Params["path"]
-		if path == "" {
-			return ToolResult{Success: false, Error: "path parameter required"}
-		}
-		return ChangeDirectory(path)
-
-	case "system_info":
-		return GetSystemInfo()
+	return ExecuteToolContext(context.Background(), call)
+}
 
-	default:
-		return ToolResult{Success: false, Error: fmt.Sprintf("unknown tool: %s", call.Name)}
+// ExecuteToolContext is ExecuteTool with a context that cancels the call
+// where the tool supports it
+func ExecuteToolContext(ctx context.Context, call ToolCall) ToolResult {
+	if refused, ok := checkAccess(call.Name); !ok {
+		return refused
 	}
+	result := DefaultRegistry.Execute(ctx, call)
+	result = reportDiagnostics(ctx, call, result)
+	return applyOutputPolicy(call.Name, result)
 }
 
 // FormatToolResult formats a tool result for the AI
@@ -0,0 +1,204 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zesbe/zesbe-go/internal/config"
+)
+
+// fileHookTimeout is the default limit for one file hook
+const fileHookTimeout = 30 * time.Second
+
+// maxHookChangedLines caps the reformatted lines shown to the model
+const maxHookChangedLines = 60
+
+// maxHookOutputBytes caps the output of a failing hook
+const maxHookOutputBytes = 4000
+
+// fileHook is a validated formatter or linter command
+type fileHook struct {
+	name       string
+	command    []string
+	appendPath bool // The template has no {{file}}
+	timeout    time.Duration
+}
+
+var (
+	fileHookMu sync.RWMutex
+	fileHooks  map[string][]fileHook // By lowercase extension
+)
+
+// SetFileHooks replaces the hooks run after write_file and edit_file.
+// Invalid hooks are skipped and reported.
+func SetFileHooks(decls map[string][]config.FileHook) error {
+	exts := make([]string, 0, len(decls))
+	for ext := range decls {
+		exts = append(exts, ext)
+	}
+	sort.Strings(exts)
+
+	var errs []error
+	hooks := make(map[string][]fileHook)
+	for _, ext := range exts {
+		key := strings.ToLower(ext)
+		if !strings.HasPrefix(key, ".") {
+			key = "." + key
+		}
+		for i, h := range decls[ext] {
+			if h.Disabled {
+				continue
+			}
+			fh, err := newFileHook(h)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("file_hooks[%s][%d]: %w", ext, i, err))
+				continue
+			}
+			hooks[key] = append(hooks[key], fh)
+		}
+	}
+
+	fileHookMu.Lock()
+	fileHooks = hooks
+	fileHookMu.Unlock()
+	return errors.Join(errs...)
+}
+
+// newFileHook validates a hook declaration
+func newFileHook(h config.FileHook) (fileHook, error) {
+	if len(h.Command) == 0 || strings.TrimSpace(h.Command[0]) == "" {
+		return fileHook{}, fmt.Errorf("command is required")
+	}
+	fh := fileHook{name: h.Name, command: h.Command, appendPath: true, timeout: fileHookTimeout}
+	if fh.name == "" {
+		fh.name = filepath.Base(h.Command[0])
+	}
+	for _, arg := range h.Command {
+		for _, ref := range placeholder.FindAllStringSubmatch(arg, -1) {
+			if ref[1] != "file" {
+				return fileHook{}, fmt.Errorf("command uses {{%s}}; only {{file}} is available", ref[1])
+			}
+			fh.appendPath = false
+		}
+	}
+	if h.Timeout != "" {
+		d, err := ParseTimeout(h.Timeout)
+		if err != nil {
+			return fileHook{}, fmt.Errorf("timeout: %w", err)
+		}
+		fh.timeout = d
+	}
+	return fh, nil
+}
+
+// hooksFor returns the hooks for a file's extension
+func hooksFor(path string) []fileHook {
+	fileHookMu.RLock()
+	defer fileHookMu.RUnlock()
+	return fileHooks[strings.ToLower(filepath.Ext(path))]
+}
+
+// run runs the hook on the file at absPath
+func (h fileHook) run(ctx context.Context, absPath string) ToolResult {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	argv := expandCommand(h.command, map[string]string{"file": absPath})
+	if h.appendPath {
+		argv = append(argv, absPath)
+	}
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	return runCmd(ctx, cmd, h.timeout)
+}
+
+// runFileHooks runs the formatters and linters for a file write_file or
+// edit_file just wrote. The reformatted lines and any hook failures are
+// appended to the result, which stays successful since the write happened.
+func runFileHooks(ctx context.Context, p map[string]string, result ToolResult) ToolResult {
+	if !result.Success || p["skip_hooks"] == "true" {
+		return result
+	}
+	hooks := hooksFor(p["path"])
+	if len(hooks) == 0 {
+		return result
+	}
+	absPath, err := filepath.Abs(p["path"])
+	if err != nil {
+		return result
+	}
+	original, err := os.ReadFile(absPath)
+	if err != nil {
+		return result
+	}
+
+	var formatters, failures []string
+	current := original
+	for _, h := range hooks {
+		r := h.run(ctx, absPath)
+		if !r.Success {
+			failure := fmt.Sprintf("Hook %s failed: %s", h.name, r.Error)
+			if out := strings.TrimSpace(r.Output); out != "" {
+				failure += "\n" + TruncateLines(out, maxHookOutputBytes)
+			}
+			failures = append(failures, failure)
+		}
+		data, err := os.ReadFile(absPath)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("Hook %s left the file unreadable: %v", h.name, err))
+			break
+		}
+		if !bytes.Equal(data, current) {
+			formatters = append(formatters, h.name)
+			current = data
+		}
+	}
+
+	if len(formatters) > 0 {
+		result.Output += fmt.Sprintf("\n\nFormatted by %s. %s", strings.Join(formatters, ", "),
+			changedLines(string(original), string(current)))
+	}
+	if len(failures) > 0 {
+		result.Output += "\n\n" + strings.Join(failures, "\n\n")
+	}
+	return result
+}
+
+// changedLines describes how a hook rewrote a file, listing the lines that
+// differ so later edits can match the formatted text
+func changedLines(before, after string) string {
+	old := strings.Split(before, "\n")
+	cur := strings.Split(after, "\n")
+
+	start := 0
+	for start < len(old) && start < len(cur) && old[start] == cur[start] {
+		start++
+	}
+	end := 0
+	for end < len(old)-start && end < len(cur)-start && old[len(old)-1-end] == cur[len(cur)-1-end] {
+		end++
+	}
+
+	region := cur[start : len(cur)-end]
+	switch {
+	case len(region) == 0:
+		return fmt.Sprintf("Lines were removed after line %d.", start)
+	case len(region) > maxHookChangedLines:
+		return fmt.Sprintf("Lines %d-%d changed; read the file before editing it again.", start+1, len(cur)-end)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Lines %d-%d now read:\n", start+1, len(cur)-end))
+	for i, line := range region {
+		sb.WriteString(fmt.Sprintf("%6d\t%s\n", start+1+i, line))
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
@@ -0,0 +1,785 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zesbe/zesbe-go/internal/config"
+)
+
+// defaultProtectedBranches are protected when the config names none
+var defaultProtectedBranches = []string{"main", "master"}
+
+// maxSubjectLength is the longest commit subject git_commit accepts
+const maxSubjectLength = 72
+
+var (
+	gitMu       sync.RWMutex
+	gitSettings config.GitSettings
+)
+
+// SetGitSettings applies the git section of the config
+func SetGitSettings(s config.GitSettings) {
+	gitMu.Lock()
+	gitSettings = s
+	gitMu.Unlock()
+}
+
+// currentGitSettings returns the settings in effect
+func currentGitSettings() config.GitSettings {
+	gitMu.RLock()
+	defer gitMu.RUnlock()
+	return gitSettings
+}
+
+// isProtectedBranch reports whether pushes to branch need explicit permission
+func isProtectedBranch(branch string) bool {
+	patterns := currentGitSettings().ProtectedBranches
+	if patterns == nil {
+		patterns = defaultProtectedBranches
+	}
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, branch); ok || p == branch {
+			return true
+		}
+	}
+	return false
+}
+
+// GitFileChange is one changed path in a status report
+type GitFileChange struct {
+	Path   string `json:"path"`
+	Status string `json:"status"`         // modified, added, deleted, renamed, copied or type_changed
+	From   string `json:"from,omitempty"` // Original path of a rename or copy
+}
+
+// GitStatusInfo is the parsed form of git status --porcelain=v2
+type GitStatusInfo struct {
+	Branch     string          `json:"branch"` // Empty when HEAD is detached
+	Commit     string          `json:"commit,omitempty"`
+	Upstream   string          `json:"upstream,omitempty"`
+	Ahead      int             `json:"ahead"`
+	Behind     int             `json:"behind"`
+	Staged     []GitFileChange `json:"staged"`
+	Unstaged   []GitFileChange `json:"unstaged"`
+	Untracked  []string        `json:"untracked"`
+	Conflicted []string        `json:"conflicted"`
+	Clean      bool            `json:"clean"`
+}
+
+// GitCommitInfo summarises a commit in log output
+type GitCommitInfo struct {
+	Hash    string `json:"hash"`
+	Author  string `json:"author"`
+	Date    string `json:"date"`
+	Subject string `json:"subject"`
+}
+
+// GitBranchInfo describes a local branch
+type GitBranchInfo struct {
+	Name     string `json:"name"`
+	Upstream string `json:"upstream,omitempty"`
+	Track    string `json:"track,omitempty"` // e.g. "ahead 1, behind 2" or "gone"
+	Current  bool   `json:"current,omitempty"`
+}
+
+// GitLogOptions filters git_log output
+type GitLogOptions struct {
+	Count int    // Commits to show (default 10)
+	Ref   string // Revision or range to start from (default HEAD)
+	Path  string // Only commits touching this path
+}
+
+// GitStatus returns the parsed status of a repository
+func GitStatus(ctx context.Context, path string) ToolResult {
+	info, err := ParseGitStatus(ctx, path)
+	if err != nil {
+		return ToolResult{Success: false, Error: err.Error()}
+	}
+	return jsonResult(info)
+}
+
+// ParseGitStatus reads the branch and file states of a repository
+func ParseGitStatus(ctx context.Context, path string) (*GitStatusInfo, error) {
+	out, err := gitOutput(ctx, path, "status", "--porcelain=v2", "--branch", "-z")
+	if err != nil {
+		return nil, err
+	}
+
+	info := &GitStatusInfo{
+		Staged:     []GitFileChange{},
+		Unstaged:   []GitFileChange{},
+		Untracked:  []string{},
+		Conflicted: []string{},
+	}
+	entries := strings.Split(out, "\x00")
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		switch {
+		case strings.HasPrefix(entry, "# branch.oid "):
+			if oid := strings.TrimPrefix(entry, "# branch.oid "); oid != "(initial)" && len(oid) >= 7 {
+				info.Commit = oid[:7]
+			}
+		case strings.HasPrefix(entry, "# branch.head "):
+			if head := strings.TrimPrefix(entry, "# branch.head "); head != "(detached)" {
+				info.Branch = head
+			}
+		case strings.HasPrefix(entry, "# branch.upstream "):
+			info.Upstream = strings.TrimPrefix(entry, "# branch.upstream ")
+		case strings.HasPrefix(entry, "# branch.ab "):
+			fmt.Sscanf(strings.TrimPrefix(entry, "# branch.ab "), "+%d -%d", &info.Ahead, &info.Behind)
+		case strings.HasPrefix(entry, "1 "):
+			if f := strings.SplitN(entry, " ", 9); len(f) == 9 {
+				info.addChange(f[1], GitFileChange{Path: f[8]})
+			}
+		case strings.HasPrefix(entry, "2 "):
+			// Renames and copies are followed by the original path
+			if f := strings.SplitN(entry, " ", 10); len(f) == 10 && i+1 < len(entries) {
+				i++
+				info.addChange(f[1], GitFileChange{Path: f[9], From: entries[i]})
+			}
+		case strings.HasPrefix(entry, "u "):
+			if f := strings.SplitN(entry, " ", 11); len(f) == 11 {
+				info.Conflicted = append(info.Conflicted, f[10])
+			}
+		case strings.HasPrefix(entry, "? "):
+			info.Untracked = append(info.Untracked, strings.TrimPrefix(entry, "? "))
+		}
+	}
+	info.Clean = len(info.Staged)+len(info.Unstaged)+len(info.Untracked)+len(info.Conflicted) == 0
+	return info, nil
+}
+
+// addChange records the index (X) and worktree (Y) sides of an entry
+func (info *GitStatusInfo) addChange(xy string, change GitFileChange) {
+	if len(xy) != 2 {
+		return
+	}
+	if xy[0] != '.' {
+		c := change
+		c.Status = gitChangeStatus(xy[0])
+		info.Staged = append(info.Staged, c)
+	}
+	if xy[1] != '.' {
+		c := change
+		c.Status = gitChangeStatus(xy[1])
+		c.From = ""
+		info.Unstaged = append(info.Unstaged, c)
+	}
+}
+
+// gitChangeStatus names a porcelain status letter
+func gitChangeStatus(code byte) string {
+	switch code {
+	case 'A':
+		return "added"
+	case 'D':
+		return "deleted"
+	case 'R':
+		return "renamed"
+	case 'C':
+		return "copied"
+	case 'T':
+		return "type_changed"
+	default:
+		return "modified"
+	}
+}
+
+// GitDiff returns the git diff, optionally limited to some paths
+func GitDiff(ctx context.Context, path string, staged bool, files ...string) ToolResult {
+	args := []string{"diff"}
+	if staged {
+		args = append(args, "--cached")
+	}
+	args = append(args, "--")
+	return executeGitCommand(ctx, path, append(args, files...)...)
+}
+
+// GitDiffRange shows the changes in a revision range such as main..HEAD or
+// main...feature. A single revision is compared with the working tree, and
+// an empty range shows all uncommitted changes against HEAD.
+func GitDiffRange(ctx context.Context, path, rng string, files ...string) ToolResult {
+	if rng == "" {
+		rng = "HEAD"
+	}
+	for _, ref := range strings.Split(strings.Replace(rng, "...", "..", 1), "..") {
+		if err := checkRef(ref); err != nil {
+			return ToolResult{Success: false, Error: err.Error()}
+		}
+	}
+	out, err := gitOutput(ctx, path, append([]string{"diff", rng, "--"}, files...)...)
+	if err != nil {
+		return ToolResult{Success: false, Error: err.Error()}
+	}
+	return ToolResult{Success: true, Output: out}
+}
+
+// GitLog returns recent commits as structured entries
+func GitLog(ctx context.Context, path string, opts GitLogOptions) ToolResult {
+	if opts.Count <= 0 {
+		opts.Count = 10
+	}
+	args := []string{"log", fmt.Sprintf("-%d", opts.Count), "--format=%h%x1f%an%x1f%ad%x1f%s", "--date=short"}
+	if opts.Ref != "" {
+		if err := checkRef(opts.Ref); err != nil {
+			return ToolResult{Success: false, Error: err.Error()}
+		}
+		args = append(args, opts.Ref)
+	}
+	args = append(args, "--")
+	if opts.Path != "" {
+		args = append(args, opts.Path)
+	}
+
+	out, err := gitOutput(ctx, path, args...)
+	if err != nil {
+		return ToolResult{Success: false, Error: err.Error()}
+	}
+	commits := []GitCommitInfo{}
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		if f := strings.SplitN(line, "\x1f", 4); len(f) == 4 {
+			commits = append(commits, GitCommitInfo{Hash: f[0], Author: f[1], Date: f[2], Subject: f[3]})
+		}
+	}
+	return jsonResult(commits)
+}
+
+// GitBranch lists local and remote branches and marks the current one
+func GitBranch(ctx context.Context, path string) ToolResult {
+	out, err := gitOutput(ctx, path, "for-each-ref", "--format=%(refname:short)%1f%(upstream:short)%1f%(upstream:track,nobracket)%1f%(HEAD)", "refs/heads")
+	if err != nil {
+		return ToolResult{Success: false, Error: err.Error()}
+	}
+	result := struct {
+		Current  string          `json:"current"`
+		Branches []GitBranchInfo `json:"branches"`
+		Remotes  []string        `json:"remotes"`
+	}{Branches: []GitBranchInfo{}, Remotes: []string{}}
+
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		f := strings.Split(line, "\x1f")
+		if len(f) != 4 {
+			continue
+		}
+		b := GitBranchInfo{Name: f[0], Upstream: f[1], Track: f[2], Current: f[3] == "*"}
+		if b.Current {
+			result.Current = b.Name
+		}
+		result.Branches = append(result.Branches, b)
+	}
+
+	if out, err := gitOutput(ctx, path, "for-each-ref", "--format=%(refname:short)", "refs/remotes"); err == nil {
+		for _, name := range strings.Fields(out) {
+			// Skip the remote HEAD symrefs
+			if strings.Contains(name, "/") && !strings.HasSuffix(name, "/HEAD") {
+				result.Remotes = append(result.Remotes, name)
+			}
+		}
+	}
+	return jsonResult(result)
+}
+
+// GitAdd stages files for commit and returns the resulting status
+func GitAdd(ctx context.Context, path string, files ...string) ToolResult {
+	if len(files) == 0 {
+		files = []string{"."}
+	}
+	args := append([]string{"add", "--"}, files...)
+	if _, err := gitOutput(ctx, path, args...); err != nil {
+		return ToolResult{Success: false, Error: err.Error()}
+	}
+	return GitStatus(ctx, path)
+}
+
+// GitCommitOptions adjusts a commit made by GitCommitWith
+type GitCommitOptions struct {
+	Amend bool // Replace the last commit instead of adding one
+}
+
+// GitCommit commits the staged changes after checking the message
+func GitCommit(ctx context.Context, path, message string) ToolResult {
+	return GitCommitWith(ctx, path, message, GitCommitOptions{})
+}
+
+// GitCommitWith commits with options. The configured co-author, if any, is
+// added as a trailer.
+func GitCommitWith(ctx context.Context, path, message string, opts GitCommitOptions) ToolResult {
+	message, err := checkCommitMessage(message)
+	if err != nil {
+		return ToolResult{Success: false, Error: err.Error()}
+	}
+	if !opts.Amend {
+		if _, err := gitOutput(ctx, path, "diff", "--cached", "--quiet"); err == nil {
+			return ToolResult{Success: false, Error: "nothing is staged; stage changes with git_add first"}
+		}
+	}
+
+	args := []string{"commit", "-m", message}
+	if opts.Amend {
+		args = append(args, "--amend")
+	}
+	if coAuthor := currentGitSettings().CoAuthor; coAuthor != "" {
+		args = append(args, "--trailer", "Co-authored-by: "+coAuthor)
+	}
+	if out, err := gitOutput(ctx, path, args...); err != nil {
+		return ToolResult{Success: false, Output: out, Error: err.Error()}
+	}
+	out, err := gitOutput(ctx, path, "log", "-1", "--format=%h%x1f%s")
+	if err != nil {
+		return ToolResult{Success: false, Error: err.Error()}
+	}
+	f := strings.SplitN(strings.TrimSpace(out), "\x1f", 2)
+	result := map[string]string{"commit": f[0], "branch": currentBranch(ctx, path)}
+	if len(f) == 2 {
+		result["subject"] = f[1]
+	}
+	return jsonResult(result)
+}
+
+// GitCommitDiff returns the changes the next commit would contain: the
+// staged diff, or with amend the last commit's changes plus the staged ones
+func GitCommitDiff(ctx context.Context, path string, amend bool) (string, error) {
+	base := ""
+	if amend {
+		base = "HEAD~1"
+		if _, err := gitOutput(ctx, path, "rev-parse", "--verify", "--quiet", "HEAD~1"); err != nil {
+			base = emptyTree // Amending the root commit
+		}
+	}
+	args := []string{"diff", "--cached"}
+	if base != "" {
+		args = append(args, base)
+	}
+	return gitOutput(ctx, path, args...)
+}
+
+// emptyTree is the hash of git's empty tree, for diffs against nothing
+const emptyTree = "4b825dc642cb6eb9a060e54bf8d69288fbee4904"
+
+// GitRecentSubjects returns the subject lines of the last n commits
+func GitRecentSubjects(ctx context.Context, path string, n int) ([]string, error) {
+	out, err := gitOutput(ctx, path, "log", fmt.Sprintf("-%d", n), "--format=%s")
+	if err != nil {
+		return nil, err
+	}
+	var subjects []string
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		if line != "" {
+			subjects = append(subjects, line)
+		}
+	}
+	return subjects, nil
+}
+
+// checkCommitMessage enforces a non-empty subject of reasonable length,
+// separated from any body by a blank line
+func checkCommitMessage(message string) (string, error) {
+	message = strings.TrimSpace(message)
+	if message == "" {
+		return "", fmt.Errorf("commit message is empty")
+	}
+	lines := strings.Split(message, "\n")
+	subject := strings.TrimSpace(lines[0])
+	if len([]rune(subject)) > maxSubjectLength {
+		return "", fmt.Errorf("subject line is %d characters; keep it to %d and put details in the body", len([]rune(subject)), maxSubjectLength)
+	}
+	if len(lines) > 1 && strings.TrimSpace(lines[1]) != "" {
+		return "", fmt.Errorf("separate the subject line from the body with a blank line")
+	}
+	return message, nil
+}
+
+// GitPush pushes the current branch to its tracked upstream. Without an
+// upstream, setUpstream pushes to the same name on the default remote and
+// starts tracking it. Protected branches and force-pushes are refused unless
+// the config allows them.
+func GitPush(ctx context.Context, path string, force, setUpstream bool) ToolResult {
+	branch := currentBranch(ctx, path)
+	if branch == "" {
+		return ToolResult{Success: false, Error: "HEAD is detached; check out a branch before pushing"}
+	}
+
+	remote, target := trackedUpstream(ctx, path, branch)
+	if remote == "" {
+		remote = defaultRemote(ctx, path)
+		if remote == "" {
+			return ToolResult{Success: false, Error: "repository has no remotes"}
+		}
+		if !setUpstream {
+			return ToolResult{Success: false, Error: fmt.Sprintf("branch %s has no upstream; pass set_upstream=true to push it to %s/%s", branch, remote, branch)}
+		}
+		target = branch
+	}
+
+	settings := currentGitSettings()
+	for _, b := range []string{branch, target} {
+		if isProtectedBranch(b) && !settings.AllowProtectedPush {
+			return ToolResult{Success: false, Error: fmt.Sprintf("refusing to push to protected branch %s; set git.allow_protected_push in the config to allow it", b)}
+		}
+	}
+	if force && !settings.AllowForcePush {
+		return ToolResult{Success: false, Error: "force-push is disabled; set git.allow_force_push in the config to allow it"}
+	}
+
+	args := []string{"push"}
+	if force {
+		args = append(args, "--force-with-lease")
+	}
+	if setUpstream {
+		args = append(args, "--set-upstream")
+	}
+	args = append(args, remote, "HEAD:refs/heads/"+target)
+
+	result := executeGitCommand(ctx, path, args...)
+	if result.Success {
+		result.Output = fmt.Sprintf("Pushed %s to %s/%s\n%s", branch, remote, target, result.Output)
+	}
+	return result
+}
+
+// GitPull updates the current branch from its upstream, fast-forward only
+// unless rebase is set
+func GitPull(ctx context.Context, path string, rebase bool) ToolResult {
+	branch := currentBranch(ctx, path)
+	if branch == "" {
+		return ToolResult{Success: false, Error: "HEAD is detached; check out a branch before pulling"}
+	}
+	if remote, _ := trackedUpstream(ctx, path, branch); remote == "" {
+		return ToolResult{Success: false, Error: fmt.Sprintf("branch %s has no upstream to pull from", branch)}
+	}
+	if rebase {
+		return executeGitCommand(ctx, path, "pull", "--rebase")
+	}
+	return executeGitCommand(ctx, path, "pull", "--ff-only")
+}
+
+// GitShow shows a commit, or a file as it was at a commit
+func GitShow(ctx context.Context, path, ref, file string, stat bool) ToolResult {
+	if ref == "" {
+		ref = "HEAD"
+	}
+	if err := checkRef(ref); err != nil {
+		return ToolResult{Success: false, Error: err.Error()}
+	}
+	if file != "" {
+		// ./ makes the path relative to the working directory, not the repo root
+		if !filepath.IsAbs(file) && !strings.HasPrefix(file, "./") && !strings.HasPrefix(file, "../") {
+			file = "./" + file
+		}
+		return executeGitCommand(ctx, path, "show", ref+":"+file)
+	}
+	args := []string{"show", "--format=fuller"}
+	if stat {
+		args = append(args, "--stat")
+	}
+	return executeGitCommand(ctx, path, append(args, ref, "--")...)
+}
+
+// GitBlame shows who last changed each line of a file, optionally within a
+// 1-based line range
+func GitBlame(ctx context.Context, path, file string, start, end int) ToolResult {
+	args := []string{"blame", "--line-porcelain"}
+	if start > 0 {
+		if end < start {
+			end = start + DefaultReadLimit - 1
+		}
+		args = append(args, "-L", fmt.Sprintf("%d,%d", start, end))
+	}
+	out, err := gitOutput(ctx, path, append(args, "--", file)...)
+	if err != nil {
+		return ToolResult{Success: false, Error: err.Error()}
+	}
+
+	var sb strings.Builder
+	var hash, author, date string
+	var line int
+	for _, l := range strings.Split(out, "\n") {
+		switch {
+		case strings.HasPrefix(l, "\t"):
+			sb.WriteString(fmt.Sprintf("%s %-20s %s %5d| %s\n", hash, truncateString(author, 20), date, line, l[1:]))
+		case strings.HasPrefix(l, "author "):
+			author = strings.TrimPrefix(l, "author ")
+		case strings.HasPrefix(l, "author-time "):
+			if secs, err := strconv.ParseInt(strings.TrimPrefix(l, "author-time "), 10, 64); err == nil {
+				date = time.Unix(secs, 0).Format("2006-01-02")
+			}
+		default:
+			// A header line: <hash> <orig line> <final line> [<group size>]
+			if f := strings.Fields(l); len(f) >= 3 && len(f[0]) == 40 {
+				hash = f[0][:8]
+				line, _ = strconv.Atoi(f[2])
+			}
+		}
+	}
+	return ToolResult{Success: true, Output: sb.String()}
+}
+
+// GitStash manages the stash. action is push (default), pop, apply, drop,
+// list or show; ref selects an entry by index or stash@{n}.
+func GitStash(ctx context.Context, path, action, message, ref string, includeUntracked bool) ToolResult {
+	if ref != "" {
+		if _, err := strconv.Atoi(ref); err == nil {
+			ref = "stash@{" + ref + "}"
+		}
+		if err := checkRef(ref); err != nil {
+			return ToolResult{Success: false, Error: err.Error()}
+		}
+	}
+
+	switch action {
+	case "", "push":
+		args := []string{"stash", "push"}
+		if includeUntracked {
+			args = append(args, "--include-untracked")
+		}
+		if message != "" {
+			args = append(args, "-m", message)
+		}
+		return executeGitCommand(ctx, path, args...)
+	case "list":
+		return executeGitCommand(ctx, path, "stash", "list")
+	case "pop", "apply", "drop":
+		args := []string{"stash", action}
+		if ref != "" {
+			args = append(args, ref)
+		}
+		return executeGitCommand(ctx, path, args...)
+	case "show":
+		args := []string{"stash", "show", "-p"}
+		if ref != "" {
+			args = append(args, ref)
+		}
+		return executeGitCommand(ctx, path, args...)
+	default:
+		return ToolResult{Success: false, Error: fmt.Sprintf("unknown stash action %q; use push, pop, apply, drop, list or show", action)}
+	}
+}
+
+// GitCheckoutBranch switches to a branch, creating it from startPoint (or
+// HEAD) when create is set
+func GitCheckoutBranch(ctx context.Context, path, name string, create bool, startPoint string) ToolResult {
+	if strings.HasPrefix(name, "-") {
+		return ToolResult{Success: false, Error: fmt.Sprintf("invalid branch name %q", name)}
+	}
+	if _, err := gitOutput(ctx, path, "check-ref-format", "--branch", name); err != nil {
+		return ToolResult{Success: false, Error: fmt.Sprintf("invalid branch name %q", name)}
+	}
+
+	args := []string{"switch"}
+	if create {
+		args = append(args, "-c", name)
+		if startPoint != "" {
+			if err := checkRef(startPoint); err != nil {
+				return ToolResult{Success: false, Error: err.Error()}
+			}
+			args = append(args, startPoint)
+		}
+	} else {
+		args = append(args, name)
+	}
+	return executeGitCommand(ctx, path, args...)
+}
+
+// GitClone clones a repository. An empty dest lets git name the directory.
+func GitClone(ctx context.Context, url, dest string) ToolResult {
+	args := []string{"clone", "--", url}
+	if dest != "" {
+		args = append(args, dest)
+	} else {
+		dest = strings.TrimSuffix(filepath.Base(strings.TrimSuffix(url, "/")), ".git")
+	}
+	cmd := gitCommand(ctx, "", args...)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if failure := gitFailure(ctx, args, stderr.String()); failure != nil {
+			err = failure
+		}
+		return ToolResult{Success: false, Output: stderr.String(), Error: err.Error()}
+	}
+
+	return ToolResult{Success: true, Output: fmt.Sprintf("Cloned %s to %s", url, dest)}
+}
+
+// currentBranch returns the checked-out branch, or "" when detached
+func currentBranch(ctx context.Context, path string) string {
+	out, err := gitOutput(ctx, path, "symbolic-ref", "--quiet", "--short", "HEAD")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(out)
+}
+
+// trackedUpstream returns the remote and remote branch a branch tracks
+func trackedUpstream(ctx context.Context, path, branch string) (string, string) {
+	remote, err := gitOutput(ctx, path, "config", "--get", "branch."+branch+".remote")
+	if err != nil {
+		return "", ""
+	}
+	merge, err := gitOutput(ctx, path, "config", "--get", "branch."+branch+".merge")
+	if err != nil {
+		return "", ""
+	}
+	return strings.TrimSpace(remote), strings.TrimPrefix(strings.TrimSpace(merge), "refs/heads/")
+}
+
+// defaultRemote returns origin, or the only remote when there is one other
+func defaultRemote(ctx context.Context, path string) string {
+	out, err := gitOutput(ctx, path, "remote")
+	if err != nil {
+		return ""
+	}
+	remotes := strings.Fields(out)
+	for _, r := range remotes {
+		if r == "origin" {
+			return r
+		}
+	}
+	if len(remotes) == 1 {
+		return remotes[0]
+	}
+	return ""
+}
+
+// checkRef rejects revisions that git would parse as options
+func checkRef(ref string) error {
+	if strings.HasPrefix(ref, "-") {
+		return fmt.Errorf("invalid revision %q", ref)
+	}
+	return nil
+}
+
+// absDir resolves path for use as a git working directory
+func absDir(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// gitEnv keeps git from waiting on a terminal or askpass program for
+// credentials nobody can type; it fails with an auth error instead
+var gitEnv = []string{"GIT_TERMINAL_PROMPT=0", "GIT_ASKPASS=", "SSH_ASKPASS=", "GCM_INTERACTIVE=never"}
+
+// gitCommand builds a non-interactive git command that runs in dir, or the
+// current directory when dir is empty
+func gitCommand(ctx context.Context, dir string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), gitEnv...)
+	return cmd
+}
+
+// authFailures are git and ssh messages for credentials that are missing
+// or rejected
+var authFailures = []string{
+	"terminal prompts disabled",
+	"could not read Username",
+	"could not read Password",
+	"Authentication failed",
+	"Permission denied (publickey",
+	"Host key verification failed",
+}
+
+// gitFailure returns why a git command failed when it was a timeout or
+// missing credentials, and nil for git's ordinary errors
+func gitFailure(ctx context.Context, args []string, stderr string) error {
+	switch ctx.Err() {
+	case context.DeadlineExceeded:
+		return fmt.Errorf("git %s timed out", args[0])
+	case context.Canceled:
+		return fmt.Errorf("git %s cancelled", args[0])
+	}
+	for _, msg := range authFailures {
+		if strings.Contains(stderr, msg) {
+			return fmt.Errorf("git %s needs credentials it can't prompt for; set up a credential helper or SSH key and try again: %s", args[0], firstLine(stderr, msg))
+		}
+	}
+	return nil
+}
+
+// firstLine returns the line of s that contains substr
+func firstLine(s, substr string) string {
+	for _, line := range strings.Split(s, "\n") {
+		if strings.Contains(line, substr) {
+			return strings.TrimSpace(line)
+		}
+	}
+	return strings.TrimSpace(s)
+}
+
+// gitOutput runs git and returns its stdout. Errors carry git's message.
+func gitOutput(ctx context.Context, path string, args ...string) (string, error) {
+	cmd := gitCommand(ctx, absDir(path), args...)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if failure := gitFailure(ctx, args, stderr.String()); failure != nil {
+			return stdout.String(), failure
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return stdout.String(), fmt.Errorf("%s", msg)
+		}
+		return stdout.String(), err
+	}
+	return stdout.String(), nil
+}
+
+// executeGitCommand is a helper to run git commands
+func executeGitCommand(ctx context.Context, path string, args ...string) ToolResult {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return ToolResult{Success: false, Error: fmt.Sprintf("invalid path: %v", err)}
+	}
+
+	cmd := gitCommand(ctx, absPath, args...)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err = cmd.Run()
+
+	output := stdout.String()
+	if stderr.Len() > 0 {
+		if output != "" {
+			output += "\n"
+		}
+		output += stderr.String()
+	}
+
+	if err != nil {
+		if failure := gitFailure(ctx, args, stderr.String()); failure != nil {
+			err = failure
+		}
+		return ToolResult{Success: false, Output: output, Error: err.Error()}
+	}
+
+	return ToolResult{Success: true, Output: output}
+}
+
+// jsonResult returns v as indented JSON
+func jsonResult(v interface{}) ToolResult {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return ToolResult{Success: false, Error: err.Error()}
+	}
+	return ToolResult{Success: true, Output: string(data)}
+}
@@ -0,0 +1,263 @@
+package tools
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zesbe/zesbe-go/internal/config"
+)
+
+// gitRepo creates a repository on main with one commit, cloned from a bare
+// origin it tracks, and returns its directory
+func gitRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_AUTHOR_NAME", "Test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "Test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+
+	origin := filepath.Join(t.TempDir(), "origin.git")
+	dir := filepath.Join(t.TempDir(), "repo")
+	git(t, "", "init", "--bare", "-b", "main", origin)
+	git(t, "", "init", "-b", "main", dir)
+	writeFile(t, dir, "README.md", "hello\n")
+	git(t, dir, "add", ".")
+	git(t, dir, "commit", "-m", "Initial commit")
+	git(t, dir, "remote", "add", "origin", origin)
+	git(t, dir, "push", "-u", "origin", "main")
+	return dir
+}
+
+// git runs a git command for test setup
+func git(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return string(out)
+}
+
+// writeFile writes a file in dir
+func writeFile(t *testing.T, dir, name, data string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// setGitSettings applies git settings for the length of a test
+func setGitSettings(t *testing.T, s config.GitSettings) {
+	SetGitSettings(s)
+	t.Cleanup(func() { SetGitSettings(config.GitSettings{}) })
+}
+
+func TestGitCloneNeedsCredentials(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	// Keep the user's credential helpers out of it
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="test"`)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+	result := GitClone(ctx, server.URL+"/repo.git", filepath.Join(t.TempDir(), "repo"))
+	if result.Success {
+		t.Fatal("clone of a repository behind auth succeeded")
+	}
+	if ctx.Err() != nil {
+		t.Fatal("clone waited for credentials until the test timed out")
+	}
+	if !strings.Contains(result.Error, "credentials") {
+		t.Errorf("Error = %q, want an authentication failure", result.Error)
+	}
+}
+
+func TestGitCancelled(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	result := executeGitCommand(ctx, t.TempDir(), "init")
+	if result.Success || result.Error != "git init cancelled" {
+		t.Errorf("result = %+v, want git init cancelled", result)
+	}
+}
+
+func TestParseGitStatus(t *testing.T) {
+	dir := gitRepo(t)
+	writeFile(t, dir, "old name.txt", "a\n")
+	writeFile(t, dir, "both.txt", "a\n")
+	git(t, dir, "add", ".")
+	git(t, dir, "commit", "-m", "Add files")
+
+	git(t, dir, "mv", "old name.txt", "new name.txt")
+	writeFile(t, dir, "both.txt", "b\n")
+	git(t, dir, "add", "both.txt")
+	writeFile(t, dir, "both.txt", "c\n")
+	writeFile(t, dir, "README.md", "changed\n")
+	writeFile(t, dir, "added.txt", "new\n")
+	git(t, dir, "add", "added.txt")
+	writeFile(t, dir, "untracked file.txt", "?\n")
+
+	info, err := ParseGitStatus(context.Background(), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &GitStatusInfo{
+		Branch:   "main",
+		Commit:   info.Commit,
+		Upstream: "origin/main",
+		Ahead:    1,
+		Staged: []GitFileChange{
+			{Path: "added.txt", Status: "added"},
+			{Path: "both.txt", Status: "modified"},
+			{Path: "new name.txt", Status: "renamed", From: "old name.txt"},
+		},
+		Unstaged: []GitFileChange{
+			{Path: "README.md", Status: "modified"},
+			{Path: "both.txt", Status: "modified"},
+		},
+		Untracked:  []string{"untracked file.txt"},
+		Conflicted: []string{},
+	}
+	if len(info.Commit) != 7 {
+		t.Errorf("commit = %q, want a short hash", info.Commit)
+	}
+	if !reflect.DeepEqual(info, want) {
+		t.Errorf("status = %+v\nwant %+v", info, want)
+	}
+}
+
+func TestParseGitStatusStates(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("clean", func(t *testing.T) {
+		info, err := ParseGitStatus(ctx, gitRepo(t))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !info.Clean || info.Ahead != 0 || info.Behind != 0 {
+			t.Errorf("status = %+v, want clean and level with origin", info)
+		}
+	})
+
+	t.Run("behind", func(t *testing.T) {
+		dir := gitRepo(t)
+		writeFile(t, dir, "a.txt", "a\n")
+		git(t, dir, "add", ".")
+		git(t, dir, "commit", "-m", "Add a")
+		git(t, dir, "push")
+		git(t, dir, "reset", "--hard", "HEAD~1")
+		info, err := ParseGitStatus(ctx, dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Ahead != 0 || info.Behind != 1 {
+			t.Errorf("ahead %d, behind %d, want 0 and 1", info.Ahead, info.Behind)
+		}
+	})
+
+	t.Run("detached", func(t *testing.T) {
+		dir := gitRepo(t)
+		git(t, dir, "checkout", "--detach")
+		info, err := ParseGitStatus(ctx, dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Branch != "" || info.Commit == "" {
+			t.Errorf("branch %q at %q, want no branch and the commit", info.Branch, info.Commit)
+		}
+	})
+
+	t.Run("conflict", func(t *testing.T) {
+		dir := gitRepo(t)
+		git(t, dir, "checkout", "-b", "other")
+		writeFile(t, dir, "README.md", "theirs\n")
+		git(t, dir, "commit", "-am", "Theirs")
+		git(t, dir, "checkout", "main")
+		writeFile(t, dir, "README.md", "ours\n")
+		git(t, dir, "commit", "-am", "Ours")
+		exec.Command("git", "-C", dir, "merge", "other").Run() // Fails with the conflict
+		info, err := ParseGitStatus(ctx, dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(info.Conflicted, []string{"README.md"}) || info.Clean {
+			t.Errorf("conflicted = %q, want README.md", info.Conflicted)
+		}
+	})
+}
+
+func TestGitPushGuard(t *testing.T) {
+	tests := []struct {
+		name        string
+		settings    config.GitSettings
+		setup       []string // git commands run first
+		force       bool
+		setUpstream bool
+		err         string // "" when the push should succeed
+	}{
+		{name: "protected main", err: "refusing to push to protected branch main"},
+		{name: "protected main allowed", settings: config.GitSettings{AllowProtectedPush: true}},
+		{name: "no upstream", setup: []string{"checkout -b feature"}, err: "has no upstream; pass set_upstream=true"},
+		{name: "set upstream", setup: []string{"checkout -b feature"}, setUpstream: true},
+		{name: "branch tracking main", setup: []string{"checkout -b feature --track origin/main"}, err: "protected branch main"},
+		{name: "force refused", setup: []string{"checkout -b feature", "push -u origin feature"}, force: true, err: "force-push is disabled"},
+		{name: "force allowed", settings: config.GitSettings{AllowForcePush: true}, setup: []string{"checkout -b feature", "push -u origin feature"}, force: true},
+		{name: "configured patterns", settings: config.GitSettings{ProtectedBranches: []string{"release/*"}}},
+		{name: "configured pattern match", settings: config.GitSettings{ProtectedBranches: []string{"release/*"}}, setup: []string{"checkout -b release/1.0"}, setUpstream: true, err: "protected branch release/1.0"},
+		{name: "detached", setup: []string{"checkout --detach"}, err: "HEAD is detached"},
+		{name: "no remotes", setup: []string{"checkout -b feature", "remote remove origin"}, setUpstream: true, err: "no remotes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := gitRepo(t)
+			setGitSettings(t, tt.settings)
+			for _, c := range tt.setup {
+				git(t, dir, strings.Fields(c)...)
+			}
+			writeFile(t, dir, "change.txt", tt.name)
+			git(t, dir, "add", ".")
+			git(t, dir, "commit", "-m", "Change")
+
+			result := GitPush(context.Background(), dir, tt.force, tt.setUpstream)
+			if tt.err != "" {
+				if result.Success || !strings.Contains(result.Error, tt.err) {
+					t.Errorf("push = %+v, want error %q", result, tt.err)
+				}
+				return
+			}
+			if !result.Success {
+				t.Fatalf("push failed: %s", result.Error)
+			}
+			branch := currentBranch(context.Background(), dir)
+			if local, remote := git(t, dir, "rev-parse", "HEAD"), git(t, dir, "rev-parse", "origin/"+branch); local != remote {
+				t.Errorf("origin/%s is at %s, want HEAD %s", branch, remote, local)
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package tools
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultGlobLimit caps the number of paths returned by Glob
+const DefaultGlobLimit = 100
+
+// GlobOptions configures a file glob
+type GlobOptions struct {
+	Pattern string // Doublestar glob, e.g. internal/**/*_test.go or *.{go,mod}
+	Sort    string // "mtime" (newest first, default) or "name"
+	Limit   int    // Maximum paths returned (0 = DefaultGlobLimit)
+	Hidden  bool   // Include dotfiles
+}
+
+// globMatch is a file found by Glob
+type globMatch struct {
+	rel     string
+	modTime time.Time
+}
+
+// Glob finds files under root matching a doublestar pattern with brace
+// expansion. Patterns without a slash match file names at any depth.
+// Results are relative to root and filtered by .gitignore.
+func Glob(root string, opts GlobOptions) ToolResult {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return ToolResult{Success: false, Error: fmt.Sprintf("invalid path: %v", err)}
+	}
+	if opts.Pattern == "" {
+		opts.Pattern = "**"
+	}
+	if opts.Limit <= 0 {
+		opts.Limit = DefaultGlobLimit
+	}
+
+	matchers, err := compileGlob(opts.Pattern)
+	if err != nil {
+		return ToolResult{Success: false, Error: fmt.Sprintf("invalid pattern: %v", err)}
+	}
+
+	// Only walk the part of the tree the pattern can reach
+	start := absRoot
+	if prefix := globStaticPrefix(opts.Pattern); prefix != "" {
+		start = filepath.Join(absRoot, filepath.FromSlash(prefix))
+	}
+	if _, err := os.Stat(start); err != nil {
+		return ToolResult{Success: true, Output: "No files found"}
+	}
+
+	var matches []globMatch
+	err = walkFiles(start, WalkOptions{Hidden: opts.Hidden}, func(path string, info fs.FileInfo) error {
+		rel, err := filepath.Rel(absRoot, path)
+		if err != nil {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		for _, m := range matchers {
+			if m.MatchString(rel) {
+				matches = append(matches, globMatch{rel: rel, modTime: info.ModTime()})
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return ToolResult{Success: false, Error: fmt.Sprintf("failed to search: %v", err)}
+	}
+
+	if len(matches) == 0 {
+		return ToolResult{Success: true, Output: "No files found"}
+	}
+
+	if opts.Sort == "name" {
+		sort.Slice(matches, func(i, j int) bool { return matches[i].rel < matches[j].rel })
+	} else {
+		sort.Slice(matches, func(i, j int) bool {
+			if matches[i].modTime.Equal(matches[j].modTime) {
+				return matches[i].rel < matches[j].rel
+			}
+			return matches[i].modTime.After(matches[j].modTime)
+		})
+	}
+
+	total := len(matches)
+	if total > opts.Limit {
+		matches = matches[:opts.Limit]
+	}
+
+	var sb strings.Builder
+	for _, m := range matches {
+		sb.WriteString(m.rel)
+		sb.WriteString("\n")
+	}
+	if total > opts.Limit {
+		sb.WriteString(fmt.Sprintf("... (%d of %d files shown; narrow the pattern or raise limit)\n", opts.Limit, total))
+	}
+
+	return ToolResult{Success: true, Output: sb.String()}
+}
+
+// compileGlob expands braces and compiles each alternative into an
+// anchored regular expression over slash-separated relative paths
+func compileGlob(pattern string) ([]*regexp.Regexp, error) {
+	var result []*regexp.Regexp
+	for _, alt := range expandBraces(pattern) {
+		// A leading ./ anchors the pattern at the root like any other slash
+		anchored := strings.Contains(alt, "/")
+		alt = strings.TrimPrefix(alt, "./")
+		if !anchored {
+			alt = "**/" + alt
+		}
+		re, err := regexp.Compile("^" + globToRegexp(alt) + "$")
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, re)
+	}
+	return result, nil
+}
+
+// expandBraces expands the first {a,b,...} group in pattern recursively,
+// so "*.{go,mod}" yields "*.go" and "*.mod"
+func expandBraces(pattern string) []string {
+	open := -1
+	depth := 0
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			i++
+		case '{':
+			if depth == 0 {
+				open = i
+			}
+			depth++
+		case '}':
+			if depth == 0 {
+				continue
+			}
+			depth--
+			if depth > 0 {
+				continue
+			}
+
+			var out []string
+			prefix, suffix := pattern[:open], pattern[i+1:]
+			for _, option := range splitBraceOptions(pattern[open+1 : i]) {
+				out = append(out, expandBraces(prefix+option+suffix)...)
+			}
+			return out
+		}
+	}
+	return []string{pattern}
+}
+
+// splitBraceOptions splits a brace body on top-level commas
+func splitBraceOptions(body string) []string {
+	var options []string
+	depth, last := 0, 0
+	for i := 0; i < len(body); i++ {
+		switch body[i] {
+		case '\\':
+			i++
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case ',':
+			if depth == 0 {
+				options = append(options, body[last:i])
+				last = i + 1
+			}
+		}
+	}
+	return append(options, body[last:])
+}
+
+// globStaticPrefix returns the leading directories of pattern that contain
+// no wildcards, e.g. "internal/tools" for "internal/tools/**/*.go"
+func globStaticPrefix(pattern string) string {
+	pattern = strings.TrimPrefix(pattern, "./")
+	parts := strings.Split(pattern, "/")
+	var static []string
+	for _, part := range parts[:len(parts)-1] {
+		if strings.ContainsAny(part, "*?[{\\") || part == ".." {
+			break
+		}
+		static = append(static, part)
+	}
+	return strings.Join(static, "/")
+}
@@ -0,0 +1,141 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// globLines runs Glob sorted by name and returns the paths it lists
+func globLines(t *testing.T, root string, opts GlobOptions) []string {
+	t.Helper()
+	result := Glob(root, opts)
+	if !result.Success {
+		t.Fatalf("Glob(%q): %s", opts.Pattern, result.Error)
+	}
+	if result.Output == "No files found" {
+		return nil
+	}
+	return strings.Split(strings.TrimSpace(result.Output), "\n")
+}
+
+func TestGlobPatterns(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod":                        "",
+		"main.go":                       "",
+		"README.md":                     "",
+		".env":                          "",
+		"internal/tools/glob.go":        "",
+		"internal/tools/glob_test.go":   "",
+		"internal/config/config.go":     "",
+		"internal/config/schema.json":   "",
+		"cmd/app/main.go":               "",
+		"build/out.go":                  "",
+		"docs/a b.md":                   "",
+		"docs/{literal}.md":             "",
+		".gitignore":                    "build/\n",
+		"internal/tools/testdata/x.txt": "",
+	})
+
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		// Without a slash the pattern matches names at any depth
+		{"*.go", []string{"cmd/app/main.go", "internal/config/config.go", "internal/tools/glob.go", "internal/tools/glob_test.go", "main.go"}},
+		{"main.go", []string{"cmd/app/main.go", "main.go"}},
+		// With a slash it is anchored at the root
+		{"./main.go", []string{"main.go"}},
+		{"internal/*/config.go", []string{"internal/config/config.go"}},
+		{"internal/*.go", nil},
+		// ** spans zero or more directories
+		{"internal/**/*_test.go", []string{"internal/tools/glob_test.go"}},
+		{"**/testdata/**", []string{"internal/tools/testdata/x.txt"}},
+		{"cmd/**/main.go", []string{"cmd/app/main.go"}},
+		// Braces expand, nested ones too
+		{"*.{mod,md}", []string{"README.md", "docs/a b.md", "docs/{literal}.md", "go.mod"}},
+		{"internal/{tools/glob,config/{config,schema}}.*", []string{"internal/config/config.go", "internal/config/schema.json", "internal/tools/glob.go"}},
+		{"docs/\\{literal\\}.md", []string{"docs/{literal}.md"}},
+		// Character classes and single characters
+		{"[A-Z]*.md", []string{"README.md"}},
+		{"docs/???.md", []string{"docs/a b.md"}},
+		{"*.[!g]*", []string{"README.md", "docs/a b.md", "docs/{literal}.md", "go.mod", "internal/config/schema.json", "internal/tools/testdata/x.txt"}},
+		// Dotfiles are skipped and .gitignore is honoured
+		{"**", []string{"README.md", "cmd/app/main.go", "docs/a b.md", "docs/{literal}.md", "go.mod", "internal/config/config.go", "internal/config/schema.json", "internal/tools/glob.go", "internal/tools/glob_test.go", "internal/tools/testdata/x.txt", "main.go"}},
+		{"missing/**/*.go", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			got := globLines(t, root, GlobOptions{Pattern: tt.pattern, Sort: "name"})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Glob(%q) = %q, want %q", tt.pattern, got, tt.want)
+			}
+		})
+	}
+
+	if got := globLines(t, root, GlobOptions{Pattern: ".*", Sort: "name", Hidden: true}); !reflect.DeepEqual(got, []string{".env", ".gitignore"}) {
+		t.Errorf("hidden dotfiles = %q, want .env and .gitignore", got)
+	}
+}
+
+func TestExpandBraces(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{"*.go", []string{"*.go"}},
+		{"*.{go,mod}", []string{"*.go", "*.mod"}},
+		{"{a,b}/{c,d}", []string{"a/c", "a/d", "b/c", "b/d"}},
+		{"x{a,{b,c}}y", []string{"xay", "xby", "xcy"}},
+		{"{,test_}main.go", []string{"main.go", "test_main.go"}},
+		{`\{a,b}`, []string{`\{a,b}`}},
+		{"{unclosed", []string{"{unclosed"}},
+	}
+	for _, tt := range tests {
+		if got := expandBraces(tt.pattern); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("expandBraces(%q) = %q, want %q", tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestGlobStaticPrefix(t *testing.T) {
+	tests := map[string]string{
+		"*.go":                   "",
+		"main.go":                "",
+		"./internal/tools/*.go":  "internal/tools",
+		"internal/**/*.go":       "internal",
+		"internal/{a,b}/x.go":    "internal",
+		"../other/*.go":          "",
+		"internal/tools/glob.go": "internal/tools",
+	}
+	for pattern, want := range tests {
+		if got := globStaticPrefix(pattern); got != want {
+			t.Errorf("globStaticPrefix(%q) = %q, want %q", pattern, got, want)
+		}
+	}
+}
+
+func TestGlobSortAndLimit(t *testing.T) {
+	root := writeTree(t, map[string]string{"a.txt": "", "b.txt": "", "c.txt": "", "d.txt": ""})
+	now := time.Now()
+	for i, name := range []string{"c.txt", "a.txt", "d.txt", "b.txt"} {
+		mtime := now.Add(-time.Duration(i) * time.Hour)
+		if err := os.Chtimes(filepath.Join(root, name), mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Newest first by default
+	if got := globLines(t, root, GlobOptions{Pattern: "*.txt"}); !reflect.DeepEqual(got, []string{"c.txt", "a.txt", "d.txt", "b.txt"}) {
+		t.Errorf("mtime order = %q", got)
+	}
+
+	got := globLines(t, root, GlobOptions{Pattern: "*.txt", Sort: "name", Limit: 2})
+	want := []string{"a.txt", "b.txt", "... (2 of 4 files shown; narrow the pattern or raise limit)"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("limited = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,973 @@
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/importer"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"go/types"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// maxReferences caps the references find_references lists
+const maxReferences = 300
+
+// maxDefinitionLines caps the source shown for one definition
+const maxDefinitionLines = 60
+
+// goFset holds the positions of every Go file the code tools parse. It is
+// shared so standard library packages, which are type-checked from source
+// once and cached, stay valid across calls.
+var (
+	goMu     sync.Mutex
+	goFset   = token.NewFileSet()
+	goStdlib types.Importer
+)
+
+// maxGoFileSetBytes is how much source goFset may cover before it is
+// replaced. Every call parses the module again, so it would otherwise keep
+// growing for as long as the session runs.
+var maxGoFileSetBytes = 64 << 20
+
+// lockGo takes goMu for a code tool call, first starting a fresh goFset if
+// the old one has grown too large. The cached standard library packages
+// refer to the old FileSet, so they are dropped with it.
+func lockGo() {
+	goMu.Lock()
+	if goFset.Base() > maxGoFileSetBytes {
+		goFset = token.NewFileSet()
+		goStdlib = nil
+	}
+}
+
+// modulePattern matches the module directive in go.mod
+var modulePattern = regexp.MustCompile(`(?m)^module\s+"?([^"\s]+)"?`)
+
+// goPackage is one parsed and type-checked package
+type goPackage struct {
+	path  string // import path
+	dir   string
+	files []*ast.File
+	pkg   *types.Package
+	info  *types.Info
+}
+
+// goDir holds the parsed files of one package directory
+type goDir struct {
+	dir    string
+	files  []*ast.File // non-test files
+	tests  []*ast.File // _test.go files in the same package
+	xtests []*ast.File // _test.go files in the external _test package
+}
+
+// goLoader parses and type-checks the packages of one module. Packages of
+// the module are checked from the working tree; everything else goes
+// through the standard library source importer.
+type goLoader struct {
+	root    string            // module root
+	modPath string            // module path, or "" outside a module
+	dirs    map[string]string // import path -> directory
+	parsed  map[string]*goDir
+	pkgs    map[string]*goPackage
+	loading map[string]bool
+}
+
+// newGoLoader finds the module containing path and indexes its packages
+func newGoLoader(path string) (*goLoader, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid path: %v", err)
+	}
+	if info, err := os.Stat(abs); err != nil {
+		return nil, fmt.Errorf("path not found: %s", path)
+	} else if !info.IsDir() {
+		abs = filepath.Dir(abs)
+	}
+
+	l := &goLoader{
+		root:    abs,
+		dirs:    make(map[string]string),
+		parsed:  make(map[string]*goDir),
+		pkgs:    make(map[string]*goPackage),
+		loading: make(map[string]bool),
+	}
+	for dir := abs; ; dir = filepath.Dir(dir) {
+		if data, err := os.ReadFile(filepath.Join(dir, "go.mod")); err == nil {
+			l.root = dir
+			if m := modulePattern.FindSubmatch(data); m != nil {
+				l.modPath = string(m[1])
+			}
+			break
+		}
+		if filepath.Dir(dir) == dir {
+			break
+		}
+	}
+
+	// Index package directories, leaving out nested modules
+	var nested []string
+	seen := make(map[string]bool)
+	err = walkFiles(l.root, WalkOptions{SkipLarge: true}, func(file string, info fs.FileInfo) error {
+		dir := filepath.Dir(file)
+		rel, _ := filepath.Rel(l.root, dir)
+		for _, part := range strings.Split(filepath.ToSlash(rel), "/") {
+			if part == "vendor" || part == "testdata" {
+				return nil
+			}
+		}
+		if info.Name() == "go.mod" && dir != l.root {
+			nested = append(nested, dir+string(filepath.Separator))
+		}
+		if strings.HasSuffix(file, ".go") && !seen[dir] {
+			seen[dir] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for dir := range seen {
+		inNested := false
+		for _, n := range nested {
+			if strings.HasPrefix(dir+string(filepath.Separator), n) {
+				inNested = true
+			}
+		}
+		if !inNested {
+			l.dirs[l.importPath(dir)] = dir
+		}
+	}
+	return l, nil
+}
+
+// importPath returns the import path of a directory in the module
+func (l *goLoader) importPath(dir string) string {
+	rel, err := filepath.Rel(l.root, dir)
+	if err != nil || rel == "." {
+		if l.modPath == "" {
+			return "."
+		}
+		return l.modPath
+	}
+	if l.modPath == "" {
+		return filepath.ToSlash(rel)
+	}
+	return l.modPath + "/" + filepath.ToSlash(rel)
+}
+
+// shortPath drops the module path from an import path for display
+func (l *goLoader) shortPath(path string) string {
+	if path == l.modPath {
+		return "."
+	}
+	if l.modPath != "" && strings.HasPrefix(path, l.modPath+"/") {
+		return strings.TrimPrefix(path, l.modPath+"/")
+	}
+	return path
+}
+
+// sortedPaths returns the module's import paths in order
+func (l *goLoader) sortedPaths() []string {
+	paths := make([]string, 0, len(l.dirs))
+	for path := range l.dirs {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// parseDir parses the files of a module directory once
+func (l *goLoader) parseDir(dir string) *goDir {
+	if d, ok := l.parsed[dir]; ok {
+		return d
+	}
+	d := parseGoDir(dir, parser.ParseComments)
+	l.parsed[dir] = d
+	return d
+}
+
+// parseGoDir parses the Go files of dir that match the current build context
+func parseGoDir(dir string, mode parser.Mode) *goDir {
+	d := &goDir{dir: dir}
+	entries, _ := os.ReadDir(dir)
+	pkgName := ""
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, ".go") {
+			continue
+		}
+		if ok, err := build.Default.MatchFile(dir, name); err != nil || !ok {
+			continue
+		}
+		// Files with syntax errors are kept; the type checker uses what it can
+		f, _ := parser.ParseFile(goFset, filepath.Join(dir, name), nil, mode)
+		if f == nil {
+			continue
+		}
+
+		switch {
+		case strings.HasSuffix(f.Name.Name, "_test"):
+			d.xtests = append(d.xtests, f)
+		case strings.HasSuffix(name, "_test.go"):
+			d.tests = append(d.tests, f)
+		default:
+			if pkgName == "" {
+				pkgName = f.Name.Name
+			}
+			if f.Name.Name == pkgName {
+				d.files = append(d.files, f)
+			}
+		}
+	}
+	return d
+}
+
+// Import implements types.Importer
+func (l *goLoader) Import(path string) (*types.Package, error) {
+	if _, ok := l.dirs[path]; ok {
+		p, err := l.load(path)
+		if err != nil {
+			return nil, err
+		}
+		return p.pkg, nil
+	}
+	if !isStdImport(path) {
+		// Type-checking third-party code from source is slow, so other
+		// modules get an empty stand-in and their uses stay unresolved
+		pkg := types.NewPackage(path, guessPackageName(path))
+		pkg.MarkComplete()
+		return pkg, nil
+	}
+	if goStdlib == nil {
+		goStdlib = importer.ForCompiler(goFset, "source", nil)
+	}
+	return goStdlib.Import(path)
+}
+
+// guessPackageName returns the usual name of the package at an import path:
+// its last element, skipping a major version suffix
+func guessPackageName(path string) string {
+	parts := strings.Split(path, "/")
+	name := parts[len(parts)-1]
+	if len(parts) > 1 && regexp.MustCompile(`^v[0-9]+$`).MatchString(name) {
+		name = parts[len(parts)-2]
+	}
+	name = strings.TrimPrefix(name, "go-")
+	return strings.NewReplacer("-", "_", ".", "_").Replace(name)
+}
+
+// load type-checks the non-test files of a module package
+func (l *goLoader) load(path string) (*goPackage, error) {
+	if p, ok := l.pkgs[path]; ok {
+		return p, nil
+	}
+	if l.loading[path] {
+		return nil, fmt.Errorf("import cycle through %s", path)
+	}
+	l.loading[path] = true
+	defer delete(l.loading, path)
+
+	d := l.parseDir(l.dirs[path])
+	p := l.check(path, d.dir, d.files)
+	l.pkgs[path] = p
+	return p, nil
+}
+
+// check type-checks files as one package, tolerating errors so broken code
+// still yields as much information as possible
+func (l *goLoader) check(path, dir string, files []*ast.File) *goPackage {
+	info := &types.Info{
+		Defs: make(map[*ast.Ident]types.Object),
+		Uses: make(map[*ast.Ident]types.Object),
+	}
+	conf := types.Config{Importer: l, Error: func(error) {}, FakeImportC: true}
+	pkg, _ := conf.Check(path, goFset, files, info)
+	return &goPackage{path: path, dir: dir, files: files, pkg: pkg, info: info}
+}
+
+// loadAll type-checks every package of the module
+func (l *goLoader) loadAll() []*goPackage {
+	var pkgs []*goPackage
+	for _, path := range l.sortedPaths() {
+		if p, err := l.load(path); err == nil && p.pkg != nil {
+			pkgs = append(pkgs, p)
+		}
+	}
+	return pkgs
+}
+
+// withTests returns the variants of a package that include its test files.
+// They have their own objects, so callers match objects by position.
+func (l *goLoader) withTests(p *goPackage) []*goPackage {
+	d := l.parseDir(p.dir)
+	variants := []*goPackage{p}
+	if len(d.tests) > 0 {
+		files := append(append([]*ast.File{}, d.files...), d.tests...)
+		variants[0] = l.check(p.path, p.dir, files)
+	}
+	if len(d.xtests) > 0 {
+		variants = append(variants, l.check(p.path+"_test", p.dir, d.xtests))
+	}
+	return variants
+}
+
+// GoSymbolQuery names the identifier the code tools work on, either by name
+// (Name, Type.Method, pkg.Name or pkg.Type.Method) or by its position
+type GoSymbolQuery struct {
+	Symbol string
+	File   string
+	Line   int
+	Column int
+}
+
+// resolve finds the objects a query refers to
+func (l *goLoader) resolve(q GoSymbolQuery) ([]types.Object, error) {
+	if q.File != "" && q.Line > 0 {
+		return l.resolveAt(q)
+	}
+	if q.Symbol == "" {
+		return nil, fmt.Errorf("symbol, or file and line, required")
+	}
+
+	pkgs := l.loadAll()
+	parts := strings.Split(q.Symbol, ".")
+	var objs []types.Object
+	for _, p := range pkgs {
+		scope := p.pkg.Scope()
+		switch len(parts) {
+		case 1:
+			if obj := scope.Lookup(parts[0]); obj != nil {
+				objs = append(objs, obj)
+			}
+		case 2:
+			if l.matchesPackage(p, parts[0]) {
+				if obj := scope.Lookup(parts[1]); obj != nil {
+					objs = append(objs, obj)
+				}
+			}
+			objs = append(objs, lookupMember(p.pkg, parts[0], parts[1])...)
+		case 3:
+			if l.matchesPackage(p, parts[0]) {
+				objs = append(objs, lookupMember(p.pkg, parts[1], parts[2])...)
+			}
+		}
+	}
+
+	// A bare name may be a method or field
+	if len(objs) == 0 && len(parts) == 1 {
+		for _, p := range pkgs {
+			for _, name := range p.pkg.Scope().Names() {
+				objs = append(objs, lookupMember(p.pkg, name, parts[0])...)
+			}
+		}
+	}
+	if len(objs) == 0 {
+		return nil, fmt.Errorf("no Go symbol named %s in %s", q.Symbol, l.root)
+	}
+	return objs, nil
+}
+
+// matchesPackage reports whether name is a package's name or import path
+func (l *goLoader) matchesPackage(p *goPackage, name string) bool {
+	return p.pkg.Name() == name || p.path == name || l.shortPath(p.path) == name ||
+		strings.HasSuffix(p.path, "/"+name)
+}
+
+// lookupMember finds a method or field of a named type declared in pkg
+func lookupMember(pkg *types.Package, typeName, member string) []types.Object {
+	tn, ok := pkg.Scope().Lookup(typeName).(*types.TypeName)
+	if !ok {
+		return nil
+	}
+	obj, _, _ := types.LookupFieldOrMethod(tn.Type(), true, pkg, member)
+	if obj == nil {
+		return nil
+	}
+	return []types.Object{obj}
+}
+
+// resolveAt finds the object for the identifier at a file position
+func (l *goLoader) resolveAt(q GoSymbolQuery) ([]types.Object, error) {
+	abs, err := filepath.Abs(q.File)
+	if err != nil {
+		return nil, fmt.Errorf("invalid file: %v", err)
+	}
+	p, err := l.load(l.importPath(filepath.Dir(abs)))
+	if err != nil || p.pkg == nil {
+		return nil, fmt.Errorf("cannot load the package of %s", q.File)
+	}
+
+	for _, v := range l.withTests(p) {
+		for _, f := range v.files {
+			if goFset.Position(f.Pos()).Filename != abs {
+				continue
+			}
+			var found types.Object
+			ast.Inspect(f, func(n ast.Node) bool {
+				id, ok := n.(*ast.Ident)
+				if !ok || found != nil {
+					return found == nil
+				}
+				pos := goFset.Position(id.Pos())
+				if pos.Line != q.Line || (q.Symbol != "" && id.Name != q.Symbol) {
+					return true
+				}
+				if q.Column > 0 && (q.Column < pos.Column || q.Column > pos.Column+len(id.Name)) {
+					return true
+				}
+				if obj := v.info.Defs[id]; obj != nil {
+					found = obj
+				} else if obj := v.info.Uses[id]; obj != nil {
+					found = obj
+				}
+				return true
+			})
+			if found == nil {
+				return nil, fmt.Errorf("no Go identifier found at %s:%d", q.File, q.Line)
+			}
+			return []types.Object{found}, nil
+		}
+	}
+	return nil, fmt.Errorf("%s is not part of a Go package in %s", q.File, l.root)
+}
+
+// objectKey identifies an object by where it is declared, which stays the
+// same across the test and non-test variants of a package
+func objectKey(obj types.Object) string {
+	pos := goFset.Position(obj.Pos())
+	return fmt.Sprintf("%s:%d:%s", pos.Filename, pos.Offset, obj.Name())
+}
+
+// describeObject renders an object's kind and signature
+func (l *goLoader) describeObject(obj types.Object) string {
+	qualifier := func(p *types.Package) string {
+		if p == obj.Pkg() {
+			return ""
+		}
+		return p.Name()
+	}
+	s := types.ObjectString(obj, qualifier)
+	if tn, ok := obj.(*types.TypeName); ok && !tn.IsAlias() {
+		// Keep struct and interface types to one line
+		switch tn.Type().Underlying().(type) {
+		case *types.Struct:
+			s = "type " + tn.Name() + " struct"
+		case *types.Interface:
+			s = "type " + tn.Name() + " interface"
+		}
+	}
+	if strings.Contains(s, "invalid type") {
+		// Types from other modules are not loaded, so fall back to the source
+		if text := astSignature(obj, l.declNode(obj)); text != "" {
+			s = text
+		}
+	}
+	if obj.Pkg() != nil && obj.Parent() == obj.Pkg().Scope() {
+		s += "  [" + l.shortPath(obj.Pkg().Path()) + "]"
+	}
+	return s
+}
+
+// goRelPath makes a path relative to the working directory when it is
+// inside it
+func goRelPath(path string) string {
+	if cwd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(cwd, path); err == nil && !strings.HasPrefix(rel, "..") {
+			return rel
+		}
+	}
+	return path
+}
+
+// goLocation formats a position as file:line:column
+func goLocation(pos token.Pos) string {
+	p := goFset.Position(pos)
+	if !p.IsValid() {
+		return "(no position)"
+	}
+	return fmt.Sprintf("%s:%d:%d", goRelPath(p.Filename), p.Line, p.Column)
+}
+
+// ListSymbols lists the top-level declarations of a Go file or package
+// directory with their signatures and line numbers
+func ListSymbols(path string, exportedOnly bool) ToolResult {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return ToolResult{Success: false, Error: fmt.Sprintf("invalid path: %v", err)}
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return ToolResult{Success: false, Error: fmt.Sprintf("path not found: %s", path)}
+	}
+
+	lockGo()
+	defer goMu.Unlock()
+
+	var files []*ast.File
+	if info.IsDir() {
+		files = parseGoDir(abs, parser.ParseComments).files
+	} else {
+		f, err := parser.ParseFile(goFset, abs, nil, parser.ParseComments)
+		if f == nil {
+			return ToolResult{Success: false, Error: fmt.Sprintf("cannot parse %s: %v", path, err)}
+		}
+		files = []*ast.File{f}
+	}
+	if len(files) == 0 {
+		return ToolResult{Success: false, Error: fmt.Sprintf("no Go files in %s", path)}
+	}
+
+	var b strings.Builder
+	count := 0
+	for _, f := range files {
+		fmt.Fprintf(&b, "%s (package %s)\n", goRelPath(goFset.Position(f.Pos()).Filename), f.Name.Name)
+		for _, decl := range f.Decls {
+			for _, sym := range declSymbols(decl) {
+				if exportedOnly && !ast.IsExported(sym.name) {
+					continue
+				}
+				fmt.Fprintf(&b, "  %4d  %s\n", goFset.Position(sym.pos).Line, sym.text)
+				count++
+			}
+		}
+	}
+	fmt.Fprintf(&b, "\n%d symbol(s)", count)
+	return ToolResult{Success: true, Output: b.String()}
+}
+
+// goSymbol is one entry in the list_symbols output
+type goSymbol struct {
+	name string
+	pos  token.Pos
+	text string
+}
+
+// declSymbols describes the symbols a top-level declaration introduces
+func declSymbols(decl ast.Decl) []goSymbol {
+	switch d := decl.(type) {
+	case *ast.FuncDecl:
+		sig := nodeString(&ast.FuncDecl{Recv: d.Recv, Name: d.Name, Type: d.Type})
+		return []goSymbol{{name: d.Name.Name, pos: d.Pos(), text: sig}}
+
+	case *ast.GenDecl:
+		var syms []goSymbol
+		for _, spec := range d.Specs {
+			switch s := spec.(type) {
+			case *ast.TypeSpec:
+				text := "type " + s.Name.Name
+				if s.TypeParams != nil {
+					text += nodeString(s.TypeParams)
+				}
+				if s.Assign.IsValid() {
+					text += " ="
+				}
+				switch s.Type.(type) {
+				case *ast.StructType:
+					text += " struct"
+				case *ast.InterfaceType:
+					text += " interface"
+				default:
+					text += " " + nodeString(s.Type)
+				}
+				syms = append(syms, goSymbol{name: s.Name.Name, pos: s.Pos(), text: text})
+			case *ast.ValueSpec:
+				for _, name := range s.Names {
+					if name.Name == "_" {
+						continue
+					}
+					text := d.Tok.String() + " " + name.Name
+					if s.Type != nil {
+						text += " " + nodeString(s.Type)
+					}
+					syms = append(syms, goSymbol{name: name.Name, pos: name.Pos(), text: text})
+				}
+			}
+		}
+		return syms
+	}
+	return nil
+}
+
+// nodeString prints a syntax node on one line
+func nodeString(n ast.Node) string {
+	var buf bytes.Buffer
+	printer.Fprint(&buf, token.NewFileSet(), n)
+	s := strings.Join(strings.Fields(buf.String()), " ")
+	if len(s) > 200 {
+		s = s[:197] + "..."
+	}
+	return s
+}
+
+// FindDefinition shows where a Go identifier is declared, with its
+// signature and source
+func FindDefinition(path string, q GoSymbolQuery) ToolResult {
+	lockGo()
+	defer goMu.Unlock()
+
+	l, err := newGoLoader(path)
+	if err != nil {
+		return ToolResult{Success: false, Error: err.Error()}
+	}
+	objs, err := l.resolve(q)
+	if err != nil {
+		return ToolResult{Success: false, Error: err.Error()}
+	}
+
+	var b strings.Builder
+	for i, obj := range objs {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "%s\n%s\n", goLocation(obj.Pos()), l.describeObject(obj))
+		if src := l.declSource(obj); src != "" {
+			fmt.Fprintf(&b, "\n%s\n", src)
+		}
+	}
+	return ToolResult{Success: true, Output: strings.TrimRight(b.String(), "\n")}
+}
+
+// declSource returns the source of the declaration of a module object,
+// including its doc comment
+func (l *goLoader) declSource(obj types.Object) string {
+	n := l.declNode(obj)
+	if n == nil {
+		return ""
+	}
+	start, end := n.Pos(), n.End()
+	if doc := declDoc(n); doc != nil {
+		start = doc.Pos()
+	}
+
+	first, last := goFset.Position(start).Line, goFset.Position(end).Line
+	lines := readLines(goFset.Position(obj.Pos()).Filename, first, last)
+	if len(lines) > maxDefinitionLines {
+		lines = append(lines[:maxDefinitionLines], fmt.Sprintf("... (%d more lines)", len(lines)-maxDefinitionLines))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// declNode returns the innermost declaration of a module object: its func,
+// spec or field, or the whole function for a local
+func (l *goLoader) declNode(obj types.Object) ast.Node {
+	pos := goFset.Position(obj.Pos())
+	if !pos.IsValid() || !strings.HasPrefix(pos.Filename, l.root) {
+		return nil
+	}
+
+	var file *ast.File
+	for _, d := range l.parsed {
+		for _, f := range append(append(append([]*ast.File{}, d.files...), d.tests...), d.xtests...) {
+			if goFset.Position(f.Pos()).Filename == pos.Filename {
+				file = f
+			}
+		}
+	}
+	if file == nil {
+		return nil
+	}
+
+	var decl ast.Node
+	grouped := false
+	ast.Inspect(file, func(n ast.Node) bool {
+		if n == nil || n.Pos() > obj.Pos() || n.End() <= obj.Pos() {
+			return n == nil
+		}
+		switch n := n.(type) {
+		case *ast.FuncDecl:
+			decl = n
+			return false
+		case *ast.GenDecl:
+			decl = n
+			grouped = n.Lparen.IsValid()
+		case *ast.TypeSpec, *ast.ValueSpec:
+			// A lone spec is shown with its keyword and doc comment
+			if grouped {
+				decl = n
+			}
+		case *ast.Field:
+			decl = n
+		}
+		return true
+	})
+	return decl
+}
+
+// astSignature describes a declaration from its syntax
+func astSignature(obj types.Object, n ast.Node) string {
+	switch n := n.(type) {
+	case *ast.FuncDecl:
+		if n.Name.Pos() == obj.Pos() {
+			return nodeString(&ast.FuncDecl{Recv: n.Recv, Name: n.Name, Type: n.Type})
+		}
+	case *ast.Field:
+		return "field " + obj.Name() + " " + nodeString(n.Type)
+	case *ast.ValueSpec:
+		if n.Type != nil {
+			return "var " + obj.Name() + " " + nodeString(n.Type)
+		}
+	case *ast.GenDecl:
+		for _, spec := range n.Specs {
+			if spec.Pos() <= obj.Pos() && obj.Pos() < spec.End() {
+				return astSignature(obj, spec)
+			}
+		}
+	}
+	return ""
+}
+
+// declDoc returns the doc comment of a declaration
+func declDoc(n ast.Node) *ast.CommentGroup {
+	switch n := n.(type) {
+	case *ast.FuncDecl:
+		return n.Doc
+	case *ast.GenDecl:
+		return n.Doc
+	case *ast.TypeSpec:
+		return n.Doc
+	case *ast.ValueSpec:
+		return n.Doc
+	case *ast.Field:
+		return n.Doc
+	}
+	return nil
+}
+
+// readLines returns lines first..last (1-based, inclusive) of a file
+func readLines(path string, first, last int) []string {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxScanSize)
+	for n := 1; scanner.Scan() && n <= last; n++ {
+		if n >= first {
+			lines = append(lines, scanner.Text())
+		}
+	}
+	return lines
+}
+
+// FindReferences lists every use of a Go identifier across the module,
+// including test files
+func FindReferences(path string, q GoSymbolQuery) ToolResult {
+	lockGo()
+	defer goMu.Unlock()
+
+	l, err := newGoLoader(path)
+	if err != nil {
+		return ToolResult{Success: false, Error: err.Error()}
+	}
+	objs, err := l.resolve(q)
+	if err != nil {
+		return ToolResult{Success: false, Error: err.Error()}
+	}
+	targets := make(map[string]bool, len(objs))
+	for _, obj := range objs {
+		targets[objectKey(obj)] = true
+	}
+
+	type ref struct {
+		pos  token.Position
+		text string
+	}
+	var refs []ref
+	seen := make(map[string]bool)
+	lineCache := make(map[string][]string)
+	for _, p := range l.loadAll() {
+		for _, v := range l.withTests(p) {
+			for id, obj := range v.info.Uses {
+				if obj == nil || !targets[objectKey(obj)] {
+					continue
+				}
+				pos := goFset.Position(id.Pos())
+				key := fmt.Sprintf("%s:%d:%d", pos.Filename, pos.Line, pos.Column)
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+
+				lines, ok := lineCache[pos.Filename]
+				if !ok {
+					lines = readLines(pos.Filename, 1, 1<<30)
+					lineCache[pos.Filename] = lines
+				}
+				text := ""
+				if pos.Line <= len(lines) {
+					text = strings.TrimSpace(lines[pos.Line-1])
+				}
+				refs = append(refs, ref{pos: pos, text: text})
+			}
+		}
+	}
+
+	sort.Slice(refs, func(i, j int) bool {
+		a, b := refs[i].pos, refs[j].pos
+		if a.Filename != b.Filename {
+			return a.Filename < b.Filename
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+
+	var b strings.Builder
+	for _, obj := range objs {
+		fmt.Fprintf(&b, "%s\n  defined at %s\n", l.describeObject(obj), goLocation(obj.Pos()))
+	}
+	fmt.Fprintf(&b, "\n%d reference(s)\n", len(refs))
+	for i, r := range refs {
+		if i == maxReferences {
+			fmt.Fprintf(&b, "... %d more\n", len(refs)-maxReferences)
+			break
+		}
+		fmt.Fprintf(&b, "%s:%d:%d  %s\n", goRelPath(r.pos.Filename), r.pos.Line, r.pos.Column, truncateString(r.text, 120))
+	}
+	return ToolResult{Success: true, Output: strings.TrimRight(b.String(), "\n")}
+}
+
+// PackageDeps shows the import graph of the module containing path. With
+// pkg set (an import path or a directory relative to the module root) it
+// shows that package's direct and indirect module dependencies and the
+// packages that import it.
+func PackageDeps(path, pkg string) ToolResult {
+	lockGo()
+	defer goMu.Unlock()
+
+	l, err := newGoLoader(path)
+	if err != nil {
+		return ToolResult{Success: false, Error: err.Error()}
+	}
+
+	// Only imports are needed, so skip type checking
+	internal := make(map[string][]string)
+	external := make(map[string][]string)
+	std := make(map[string][]string)
+	importedBy := make(map[string][]string)
+	for _, p := range l.sortedPaths() {
+		seen := make(map[string]bool)
+		for _, f := range parseGoDir(l.dirs[p], parser.ImportsOnly).files {
+			for _, spec := range f.Imports {
+				imp := strings.Trim(spec.Path.Value, `"`)
+				if seen[imp] {
+					continue
+				}
+				seen[imp] = true
+				switch {
+				case l.dirs[imp] != "":
+					internal[p] = append(internal[p], imp)
+					importedBy[imp] = append(importedBy[imp], p)
+				case isStdImport(imp):
+					std[p] = append(std[p], imp)
+				default:
+					external[p] = append(external[p], imp)
+				}
+			}
+		}
+	}
+
+	short := func(paths []string) string {
+		out := make([]string, len(paths))
+		for i, p := range paths {
+			out[i] = l.shortPath(p)
+		}
+		sort.Strings(out)
+		return strings.Join(out, ", ")
+	}
+
+	var b strings.Builder
+	if pkg != "" {
+		target := l.findPackage(pkg)
+		if target == "" {
+			return ToolResult{Success: false, Error: fmt.Sprintf("no package %s in %s", pkg, l.root)}
+		}
+
+		// Walk the module imports for the indirect dependencies
+		var indirect []string
+		visited := map[string]bool{target: true}
+		queue := append([]string{}, internal[target]...)
+		for _, p := range internal[target] {
+			visited[p] = true
+		}
+		for len(queue) > 0 {
+			p := queue[0]
+			queue = queue[1:]
+			for _, imp := range internal[p] {
+				if !visited[imp] {
+					visited[imp] = true
+					indirect = append(indirect, imp)
+					queue = append(queue, imp)
+				}
+			}
+		}
+
+		fmt.Fprintf(&b, "%s\n", target)
+		writeDeps(&b, "imports", short(internal[target]))
+		writeDeps(&b, "indirectly", short(indirect))
+		writeDeps(&b, "imported by", short(importedBy[target]))
+		writeDeps(&b, "external", strings.Join(external[target], ", "))
+		writeDeps(&b, "std", strings.Join(std[target], ", "))
+		return ToolResult{Success: true, Output: strings.TrimRight(b.String(), "\n")}
+	}
+
+	module := l.modPath
+	if module == "" {
+		module = l.root
+	}
+	fmt.Fprintf(&b, "module %s (%d packages)\n", module, len(l.dirs))
+	for _, p := range l.sortedPaths() {
+		fmt.Fprintf(&b, "\n%s\n", l.shortPath(p))
+		writeDeps(&b, "imports", short(internal[p]))
+		writeDeps(&b, "imported by", short(importedBy[p]))
+		writeDeps(&b, "external", strings.Join(external[p], ", "))
+		if len(std[p]) > 0 {
+			writeDeps(&b, "std", fmt.Sprintf("%d packages", len(std[p])))
+		}
+	}
+	return ToolResult{Success: true, Output: strings.TrimRight(b.String(), "\n")}
+}
+
+// findPackage resolves an import path or module-relative directory
+func (l *goLoader) findPackage(pkg string) string {
+	if _, ok := l.dirs[pkg]; ok {
+		return pkg
+	}
+	dir := pkg
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(l.root, dir)
+	}
+	if path := l.importPath(filepath.Clean(dir)); l.dirs[path] != "" {
+		return path
+	}
+	return ""
+}
+
+// writeDeps writes one labelled line of the dependency report
+func writeDeps(b *strings.Builder, label, list string) {
+	if list != "" {
+		fmt.Fprintf(b, "  %-12s %s\n", label+":", list)
+	}
+}
+
+// isStdImport reports whether an import path belongs to the standard
+// library, whose first element never contains a dot
+func isStdImport(path string) bool {
+	first, _, _ := strings.Cut(path, "/")
+	return !strings.Contains(first, ".")
+}
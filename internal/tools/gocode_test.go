@@ -0,0 +1,36 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGoFileSetReplacedWhenLarge(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":  "module example.com/m\n",
+		"main.go": "package m\n\nimport \"strings\"\n\n// Upper shouts\nfunc Upper(s string) string { return strings.ToUpper(s) }\n",
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	old := maxGoFileSetBytes
+	defer func() { maxGoFileSetBytes = old }()
+	maxGoFileSetBytes = 1
+
+	for i := 0; i < 2; i++ {
+		before := goFset
+		result := FindDefinition(dir, GoSymbolQuery{Symbol: "Upper"})
+		if !result.Success || !strings.Contains(result.Output, "main.go:6") {
+			t.Fatalf("call %d: FindDefinition = %+v, want Upper at main.go:6", i, result)
+		}
+		if i > 0 && goFset == before {
+			t.Error("goFset kept after growing past the limit")
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package tools
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ignoreRule is a single line from a .gitignore file
+type ignoreRule struct {
+	base     string // Directory containing the .gitignore
+	re       *regexp.Regexp
+	negate   bool
+	dirOnly  bool
+	anchored bool // Pattern contains a slash and is matched against the path from base
+}
+
+// ignoreMatcher evaluates .gitignore rules collected while walking a tree
+type ignoreMatcher struct {
+	rules map[string][]ignoreRule // Keyed by the directory that declared them
+}
+
+// newIgnoreMatcher creates a matcher for root, preloading the rules of any
+// enclosing git repository between root and the repository top level.
+func newIgnoreMatcher(root string) *ignoreMatcher {
+	m := &ignoreMatcher{rules: make(map[string][]ignoreRule)}
+
+	top := findGitRoot(root)
+	if top == "" {
+		return m
+	}
+
+	m.load(top, filepath.Join(top, ".git", "info", "exclude"))
+	for dir := root; ; dir = filepath.Dir(dir) {
+		if dir != root {
+			m.loadDir(dir)
+		}
+		if dir == top || dir == filepath.Dir(dir) {
+			break
+		}
+	}
+
+	return m
+}
+
+// findGitRoot returns the nearest directory at or above dir containing .git
+func findGitRoot(dir string) string {
+	for {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// loadDir reads the .gitignore in dir, if any
+func (m *ignoreMatcher) loadDir(dir string) {
+	m.load(dir, filepath.Join(dir, ".gitignore"))
+}
+
+// load parses an ignore file whose patterns are relative to base
+func (m *ignoreMatcher) load(base, file string) {
+	f, err := os.Open(file)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if rule, ok := parseIgnoreRule(base, scanner.Text()); ok {
+			m.rules[base] = append(m.rules[base], rule)
+		}
+	}
+}
+
+// parseIgnoreRule converts a .gitignore line into a rule
+func parseIgnoreRule(base, line string) (ignoreRule, bool) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignoreRule{}, false
+	}
+
+	rule := ignoreRule{base: base}
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\`) {
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimSuffix(line, "/")
+	}
+
+	if strings.Contains(line, "/") {
+		rule.anchored = true
+		line = strings.TrimPrefix(line, "/")
+	}
+	if line == "" {
+		return ignoreRule{}, false
+	}
+
+	re, err := regexp.Compile("^" + globToRegexp(line) + "$")
+	if err != nil {
+		return ignoreRule{}, false
+	}
+	rule.re = re
+
+	return rule, true
+}
+
+// Ignored reports whether path (absolute) is excluded by the loaded rules.
+// Rules from deeper directories and later lines take precedence.
+func (m *ignoreMatcher) Ignored(path string, isDir bool) bool {
+	var chain []string
+	for dir := filepath.Dir(path); ; dir = filepath.Dir(dir) {
+		if _, ok := m.rules[dir]; ok {
+			chain = append(chain, dir)
+		}
+		if dir == filepath.Dir(dir) {
+			break
+		}
+	}
+
+	ignored := false
+	for i := len(chain) - 1; i >= 0; i-- {
+		for _, rule := range m.rules[chain[i]] {
+			if rule.dirOnly && !isDir {
+				continue
+			}
+			if rule.matches(path) {
+				ignored = !rule.negate
+			}
+		}
+	}
+
+	return ignored
+}
+
+// matches reports whether the rule applies to path
+func (r ignoreRule) matches(path string) bool {
+	if r.anchored {
+		rel, err := filepath.Rel(r.base, path)
+		if err != nil {
+			return false
+		}
+		return r.re.MatchString(filepath.ToSlash(rel))
+	}
+	return r.re.MatchString(filepath.Base(path))
+}
+
+// globToRegexp translates a glob with `*`, `?`, `[...]` and `**` path
+// segments into an unanchored regular expression.
+func globToRegexp(glob string) string {
+	var sb strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				atStart := i == 0 || glob[i-1] == '/'
+				i++
+				if atStart && i+1 < len(glob) && glob[i+1] == '/' {
+					// "**/" matches zero or more directories
+					i++
+					sb.WriteString("(?:.*/)?")
+				} else {
+					sb.WriteString(".*")
+				}
+			} else {
+				sb.WriteString("[^/]*")
+			}
+		case '?':
+			sb.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				sb.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + class + "]")
+			i += end + 1
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+				sb.WriteString(regexp.QuoteMeta(string(glob[i])))
+			}
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return sb.String()
+}
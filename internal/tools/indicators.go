@@ -11,214 +11,13 @@ type ToolIndicator struct {
 	Action      string
 	Description string
 	Category    string
+	Detail      string // Parameter shown next to the action, if any
 }
 
 // GetToolIndicator returns the indicator for a tool
 func GetToolIndicator(toolName string) ToolIndicator {
-	indicators := map[string]ToolIndicator{
-		// File Operations
-		"read_file": {
-			Icon:        "📖",
-			Action:      "Reading",
-			Description: "Reading file contents",
-			Category:    "file",
-		},
-		"write_file": {
-			Icon:        "✍️",
-			Action:      "Writing",
-			Description: "Writing file",
-			Category:    "file",
-		},
-		"edit_file": {
-			Icon:        "📝",
-			Action:      "Editing",
-			Description: "Editing file",
-			Category:    "file",
-		},
-		"list_directory": {
-			Icon:        "📁",
-			Action:      "Listing",
-			Description: "Listing directory",
-			Category:    "file",
-		},
-		"create_directory": {
-			Icon:        "📂",
-			Action:      "Creating",
-			Description: "Creating directory",
-			Category:    "file",
-		},
-		"delete_file": {
-			Icon:        "🗑️",
-			Action:      "Deleting",
-			Description: "Deleting file",
-			Category:    "file",
-		},
-		"copy_file": {
-			Icon:        "📋",
-			Action:      "Copying",
-			Description: "Copying file",
-			Category:    "file",
-		},
-		"move_file": {
-			Icon:        "📦",
-			Action:      "Moving",
-			Description: "Moving file",
-			Category:    "file",
-		},
-
-		// Search Operations
-		"find_files": {
-			Icon:        "🔍",
-			Action:      "Searching",
-			Description: "Finding files",
-			Category:    "search",
-		},
-		"grep_files": {
-			Icon:        "🔎",
-			Action:      "Searching",
-			Description: "Searching in files",
-			Category:    "search",
-		},
-		"code_search": {
-			Icon:        "🔬",
-			Action:      "Analyzing",
-			Description: "Searching code",
-			Category:    "search",
-		},
-		"web_search": {
-			Icon:        "🌐",
-			Action:      "Searching",
-			Description: "Searching the web",
-			Category:    "web",
-		},
-
-		// Git Operations
-		"git_status": {
-			Icon:        "📊",
-			Action:      "Checking",
-			Description: "Git status",
-			Category:    "git",
-		},
-		"git_diff": {
-			Icon:        "📃",
-			Action:      "Diffing",
-			Description: "Git diff",
-			Category:    "git",
-		},
-		"git_log": {
-			Icon:        "📜",
-			Action:      "Viewing",
-			Description: "Git history",
-			Category:    "git",
-		},
-		"git_add": {
-			Icon:        "➕",
-			Action:      "Staging",
-			Description: "Git add",
-			Category:    "git",
-		},
-		"git_commit": {
-			Icon:        "💾",
-			Action:      "Committing",
-			Description: "Git commit",
-			Category:    "git",
-		},
-		"git_branch": {
-			Icon:        "🌿",
-			Action:      "Branching",
-			Description: "Git branch",
-			Category:    "git",
-		},
-		"git_push": {
-			Icon:        "🚀",
-			Action:      "Pushing",
-			Description: "Git push",
-			Category:    "git",
-		},
-		"git_pull": {
-			Icon:        "⬇️",
-			Action:      "Pulling",
-			Description: "Git pull",
-			Category:    "git",
-		},
-
-		// Command Execution
-		"run_command": {
-			Icon:        "⚡",
-			Action:      "Running",
-			Description: "Executing command",
-			Category:    "command",
-		},
-		"run_script": {
-			Icon:        "📜",
-			Action:      "Running",
-			Description: "Executing script",
-			Category:    "command",
-		},
-
-		// Code Analysis
-		"analyze_code": {
-			Icon:        "🔬",
-			Action:      "Analyzing",
-			Description: "Analyzing code",
-			Category:    "analysis",
-		},
-		"project_tree": {
-			Icon:        "🌳",
-			Action:      "Mapping",
-			Description: "Building project tree",
-			Category:    "analysis",
-		},
-		"count_lines": {
-			Icon:        "📏",
-			Action:      "Counting",
-			Description: "Counting lines",
-			Category:    "analysis",
-		},
-		"find_todos": {
-			Icon:        "📋",
-			Action:      "Finding",
-			Description: "Finding TODOs",
-			Category:    "analysis",
-		},
-
-		// System
-		"get_cwd": {
-			Icon:        "📍",
-			Action:      "Getting",
-			Description: "Current directory",
-			Category:    "system",
-		},
-		"change_directory": {
-			Icon:        "🚶",
-			Action:      "Changing",
-			Description: "Changing directory",
-			Category:    "system",
-		},
-		"system_info": {
-			Icon:        "💻",
-			Action:      "Checking",
-			Description: "System info",
-			Category:    "system",
-		},
-
-		// Web/Network
-		"fetch_url": {
-			Icon:        "🌐",
-			Action:      "Fetching",
-			Description: "Fetching URL",
-			Category:    "web",
-		},
-		"download_file": {
-			Icon:        "⬇️",
-			Action:      "Downloading",
-			Description: "Downloading file",
-			Category:    "web",
-		},
-	}
-
-	if indicator, ok := indicators[toolName]; ok {
-		return indicator
+	if t, ok := DefaultRegistry.Get(toolName); ok {
+		return t.Indicator()
 	}
 
 	return ToolIndicator{
@@ -234,30 +33,18 @@ func FormatToolStart(call ToolCall) string {
 	indicator := GetToolIndicator(call.Name)
 
 	var details string
-	switch call.Name {
-	case "read_file", "write_file", "edit_file", "delete_file":
-		if path := call.Params["path"]; path != "" {
-			details = fmt.Sprintf("`%s`", truncatePath(path, 40))
-		}
-	case "list_directory":
-		if path := call.Params["path"]; path != "" && path != "." {
-			details = fmt.Sprintf("`%s`", truncatePath(path, 40))
-		}
-	case "run_command":
-		if cmd := call.Params["command"]; cmd != "" {
-			details = fmt.Sprintf("`%s`", truncateString(cmd, 50))
-		}
-	case "grep_files", "find_files", "code_search":
-		if pattern := call.Params["pattern"]; pattern != "" {
-			details = fmt.Sprintf("for `%s`", pattern)
-		}
-	case "web_search":
-		if query := call.Params["query"]; query != "" {
-			details = fmt.Sprintf("`%s`", truncateString(query, 40))
-		}
-	case "git_commit":
-		if msg := call.Params["message"]; msg != "" {
-			details = fmt.Sprintf("`%s`", truncateString(msg, 40))
+	if value := call.Params[indicator.Detail]; indicator.Detail != "" && value != "" {
+		switch indicator.Detail {
+		case "path":
+			if value != "." {
+				details = fmt.Sprintf("`%s`", truncatePath(value, 40))
+			}
+		case "pattern":
+			details = fmt.Sprintf("for `%s`", value)
+		case "command":
+			details = fmt.Sprintf("`%s`", truncateString(value, 50))
+		default:
+			details = fmt.Sprintf("`%s`", truncateString(value, 40))
 		}
 	}
 
@@ -281,10 +68,7 @@ func FormatToolResultDisplay(call ToolCall, result ToolResult, duration string)
 		sb.WriteString("\n")
 
 		// Format output based on tool type
-		output := result.Output
-		if len(output) > 1000 {
-			output = output[:1000] + "\n... (truncated)"
-		}
+		output := DisplayOutput(call.Name, result.Output)
 
 		if output != "" {
 			switch indicator.Category {
@@ -365,17 +149,19 @@ func FormatFileTree(files []string, root string) string {
 
 // Helper functions
 func truncatePath(s string, maxLen int) string {
-	if len(s) <= maxLen {
+	r := []rune(s)
+	if len(r) <= maxLen {
 		return s
 	}
-	return "..." + s[len(s)-maxLen+3:]
+	return "..." + string(r[len(r)-maxLen+3:])
 }
 
 func truncateString(s string, maxLen int) string {
-	if len(s) <= maxLen {
+	r := []rune(s)
+	if len(r) <= maxLen {
 		return s
 	}
-	return s[:maxLen-3] + "..."
+	return string(r[:maxLen-3]) + "..."
 }
 
 func contains(slice []string, item string) bool {
@@ -0,0 +1,264 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zesbe/zesbe-go/internal/config"
+	"github.com/zesbe/zesbe-go/internal/logger"
+	"github.com/zesbe/zesbe-go/internal/lsp"
+)
+
+// lspWaitTimeout is how long a file change waits for fresh diagnostics
+const lspWaitTimeout = 3 * time.Second
+
+// maxReportedDiagnostics caps the diagnostics listed per file
+const maxReportedDiagnostics = 20
+
+var (
+	lspMu      sync.RWMutex
+	lspServers *lsp.Manager
+)
+
+// StartLSP sets up language servers for the workspace, rooted at the git
+// root of the current directory. Each server starts the first time a file
+// it handles changes.
+func StartLSP(servers map[string]config.LSPServer) {
+	root, _ := os.Getwd()
+	if gitRoot := findGitRoot(root); gitRoot != "" {
+		root = gitRoot
+	}
+	m := lsp.NewManager(servers, root)
+
+	lspMu.Lock()
+	old := lspServers
+	lspServers = m
+	lspMu.Unlock()
+	if old != nil {
+		old.Close()
+	}
+}
+
+// StopLSP shuts down all language servers
+func StopLSP() {
+	lspMu.Lock()
+	m := lspServers
+	lspServers = nil
+	lspMu.Unlock()
+	if m != nil {
+		m.Close()
+	}
+}
+
+// LSPStatus returns the state of each configured language server
+func LSPStatus() []lsp.ServerStatus {
+	lspMu.RLock()
+	defer lspMu.RUnlock()
+	if lspServers == nil {
+		return nil
+	}
+	return lspServers.Status()
+}
+
+// currentLSP returns the language server manager, or nil
+func currentLSP() *lsp.Manager {
+	lspMu.RLock()
+	defer lspMu.RUnlock()
+	return lspServers
+}
+
+// fileChanges lists the files a tool call writes and removes
+func fileChanges(call ToolCall) (written, removed []string) {
+	p := call.Params
+	switch call.Name {
+	case "write_file", "edit_file":
+		written = []string{p["path"]}
+	case "copy_file":
+		written = []string{p["destination"]}
+	case "move_file":
+		removed, written = []string{p["source"]}, []string{p["destination"]}
+	case "delete_file":
+		removed = []string{p["path"]}
+	}
+	return written, removed
+}
+
+// absPaths makes paths absolute so they compare with the servers' paths
+func absPaths(paths []string) []string {
+	abs := make([]string, 0, len(paths))
+	for _, path := range paths {
+		if a, err := filepath.Abs(path); err == nil && path != "" {
+			path = a
+		}
+		abs = append(abs, path)
+	}
+	return abs
+}
+
+// reportDiagnostics syncs the files a successful call changed with their
+// language servers and appends the errors and warnings they report
+func reportDiagnostics(ctx context.Context, call ToolCall, result ToolResult) ToolResult {
+	m := currentLSP()
+	if m == nil || !result.Success {
+		return result
+	}
+
+	written, removed := fileChanges(call)
+	for _, path := range removed {
+		m.Forget(path)
+	}
+
+	// Commands, git and custom tools change files too, so bring the open
+	// documents back in line with the disk before anything is reported
+	if t, ok := DefaultRegistry.Get(call.Name); ok && !t.IsReadOnly() {
+		touched := absPaths(written)
+		changed, gone := m.Outdated()
+		for _, path := range gone {
+			m.Forget(path)
+		}
+		for _, path := range changed {
+			if slices.Contains(touched, path) {
+				continue
+			}
+			if err := m.Resync(path); err != nil {
+				logger.Warnf("Resyncing %s with its language server failed: %v", path, err)
+			}
+		}
+	}
+
+	for _, path := range written {
+		if path == "" || !m.Handles(path) {
+			continue
+		}
+		diags, _, err := m.Touch(ctx, path, lspWaitTimeout)
+		if err != nil {
+			logger.Warnf("Diagnostics for %s unavailable: %v", path, err)
+			continue
+		}
+		result.Output += "\n\n" + formatDiagnostics(path, filterDiagnostics(diags, lsp.SeverityWarning))
+	}
+	return result
+}
+
+// filterDiagnostics keeps diagnostics at or above minSeverity, most severe
+// first
+func filterDiagnostics(diags []lsp.Diagnostic, minSeverity int) []lsp.Diagnostic {
+	var shown []lsp.Diagnostic
+	for _, d := range diags {
+		if d.Severity == 0 {
+			d.Severity = lsp.SeverityError // Unset means error per the spec
+		}
+		if d.Severity <= minSeverity {
+			shown = append(shown, d)
+		}
+	}
+	sort.SliceStable(shown, func(i, j int) bool {
+		if shown[i].Severity != shown[j].Severity {
+			return shown[i].Severity < shown[j].Severity
+		}
+		return shown[i].Range.Start.Line < shown[j].Range.Start.Line
+	})
+	return shown
+}
+
+// formatDiagnostics lists a file's diagnostics
+func formatDiagnostics(path string, diags []lsp.Diagnostic) string {
+	if len(diags) == 0 {
+		return fmt.Sprintf("Diagnostics for %s: no problems reported", path)
+	}
+	errors, warnings := 0, 0
+	for _, d := range diags {
+		switch d.Severity {
+		case lsp.SeverityError:
+			errors++
+		case lsp.SeverityWarning:
+			warnings++
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Diagnostics for %s: %d error(s), %d warning(s)", path, errors, warnings))
+	if other := len(diags) - errors - warnings; other > 0 {
+		sb.WriteString(fmt.Sprintf(", %d other", other))
+	}
+	sb.WriteString("\n")
+	for i, d := range diags {
+		if i == maxReportedDiagnostics {
+			sb.WriteString(fmt.Sprintf("  ... %d more\n", len(diags)-maxReportedDiagnostics))
+			break
+		}
+		source := ""
+		if d.Source != "" {
+			source = " (" + d.Source + ")"
+		}
+		message := strings.ReplaceAll(strings.TrimSpace(d.Message), "\n", " ")
+		sb.WriteString(fmt.Sprintf("  %s:%d:%d: %s: %s%s\n",
+			path, d.Range.Start.Line+1, d.Range.Start.Character+1, d.SeverityName(), message, source))
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// severityLevel parses the severity filter of the diagnostics tool
+func severityLevel(s string) (int, error) {
+	switch strings.ToLower(s) {
+	case "error", "errors":
+		return lsp.SeverityError, nil
+	case "", "warning", "warnings":
+		return lsp.SeverityWarning, nil
+	case "info":
+		return lsp.SeverityInformation, nil
+	case "all", "hint":
+		return lsp.SeverityHint, nil
+	}
+	return 0, fmt.Errorf("severity must be error, warning, info or all")
+}
+
+// Diagnostics asks the language server for a file's current diagnostics.
+// Without a path it lists what the servers last reported for every file
+// they have open.
+func Diagnostics(ctx context.Context, path, severity string) ToolResult {
+	minSeverity, err := severityLevel(severity)
+	if err != nil {
+		return ToolResult{Success: false, Error: err.Error()}
+	}
+	m := currentLSP()
+	if m == nil {
+		return ToolResult{Success: false, Error: "language servers are not enabled"}
+	}
+
+	if path != "" {
+		if !m.Handles(path) {
+			return ToolResult{Success: false, Error: fmt.Sprintf("no language server is configured for %s", filepath.Base(path))}
+		}
+		diags, _, err := m.Touch(ctx, path, lspWaitTimeout)
+		if err != nil {
+			return ToolResult{Success: false, Error: err.Error()}
+		}
+		return ToolResult{Success: true, Output: formatDiagnostics(path, filterDiagnostics(diags, minSeverity))}
+	}
+
+	all := m.AllDiagnostics()
+	paths := make([]string, 0, len(all))
+	for p := range all {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	var sections []string
+	for _, p := range paths {
+		if diags := filterDiagnostics(all[p], minSeverity); len(diags) > 0 {
+			sections = append(sections, formatDiagnostics(goRelPath(p), diags))
+		}
+	}
+	if len(sections) == 0 {
+		return ToolResult{Success: true, Output: "No problems reported for open files. Pass path to check a specific file."}
+	}
+	return ToolResult{Success: true, Output: strings.Join(sections, "\n\n")}
+}
@@ -0,0 +1,368 @@
+package tools
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/zesbe/zesbe-go/internal/config"
+	"github.com/zesbe/zesbe-go/internal/mcp"
+)
+
+// mcpPrefix starts the name of every tool proxied to an MCP server
+const mcpPrefix = "mcp__"
+
+// maxListedItems caps how many resources or prompts are named in a
+// synthetic tool description
+const maxListedItems = 20
+
+var (
+	mcpMu      sync.RWMutex
+	mcpServers *mcp.Manager
+)
+
+// StartMCPServers connects to the configured MCP servers in the background.
+// Each server's tools join the default registry once it is connected.
+func StartMCPServers(servers map[string]config.MCPServer) {
+	if len(servers) == 0 {
+		return
+	}
+	m := mcp.NewManager()
+	m.Start(servers)
+
+	mcpMu.Lock()
+	old := mcpServers
+	mcpServers = m
+	mcpMu.Unlock()
+	if old != nil {
+		old.Close()
+	}
+	DefaultRegistry.SetSource("mcp", mcpTools)
+}
+
+// StopMCPServers disconnects from all MCP servers
+func StopMCPServers() {
+	DefaultRegistry.SetSource("mcp", nil)
+	mcpMu.Lock()
+	m := mcpServers
+	mcpServers = nil
+	mcpMu.Unlock()
+	if m != nil {
+		m.Close()
+	}
+}
+
+// MCPStatus returns the state of each configured MCP server
+func MCPStatus() []mcp.ServerStatus {
+	mcpMu.RLock()
+	defer mcpMu.RUnlock()
+	if mcpServers == nil {
+		return nil
+	}
+	return mcpServers.Status()
+}
+
+// mcpTool proxies a namespaced tool to its server
+type mcpTool struct {
+	def      ToolDefinition
+	client   *mcp.Client
+	tool     string // Original tool name, or "" for the synthetic tools
+	readOnly bool
+}
+
+func (t *mcpTool) Name() string           { return t.def.Name }
+func (t *mcpTool) Schema() ToolDefinition { return t.def }
+func (t *mcpTool) IsReadOnly() bool       { return t.readOnly }
+
+// Indicator implements Tool
+func (t *mcpTool) Indicator() ToolIndicator {
+	return ToolIndicator{
+		Icon:        "🔌",
+		Action:      "Calling",
+		Description: fmt.Sprintf("%s (MCP)", strings.TrimPrefix(t.def.Name, mcpPrefix)),
+		Category:    "mcp",
+	}
+}
+
+// invalidToolChars matches characters not allowed in tool names by the APIs
+var invalidToolChars = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// maxToolName is the longest tool name the APIs accept
+const maxToolName = 64
+
+// mcpToolName builds the namespaced name for a server's tool. Names too
+// long for the APIs are cut and end in a hash of the full name, so two long
+// names sharing a prefix stay distinct.
+func mcpToolName(server, tool string) string {
+	name := mcpPrefix + invalidToolChars.ReplaceAllString(server, "_") + "__" + invalidToolChars.ReplaceAllString(tool, "_")
+	if len(name) > maxToolName {
+		sum := sha256.Sum256([]byte(server + "\x00" + tool))
+		suffix := "_" + hex.EncodeToString(sum[:4])
+		name = name[:maxToolName-len(suffix)] + suffix
+	}
+	return name
+}
+
+// mcpTools lists the tools of every connected server, plus read_resource
+// and get_prompt tools for servers that offer resources or prompts
+func mcpTools() []Tool {
+	mcpMu.RLock()
+	m := mcpServers
+	mcpMu.RUnlock()
+	if m == nil {
+		return nil
+	}
+
+	var result []Tool
+	for _, c := range m.Clients() {
+		offered := make(map[string]bool)
+		for _, t := range c.Tools() {
+			params, optional := schemaParameters(t.InputSchema)
+			def := ToolDefinition{
+				Name:        mcpToolName(c.Name, t.Name),
+				Description: strings.TrimSpace(fmt.Sprintf("[%s] %s", c.Name, t.Description)),
+				Parameters:  params,
+				Optional:    optional,
+				Schema:      t.InputSchema,
+			}
+			offered[def.Name] = true
+			result = append(result, &mcpTool{def: def, client: c, tool: t.Name, readOnly: c.ReadOnly(t.Name)})
+		}
+
+		if resources := c.Resources(); len(resources) > 0 {
+			var names []string
+			for i, r := range resources {
+				if i == maxListedItems {
+					names = append(names, fmt.Sprintf("and %d more", len(resources)-i))
+					break
+				}
+				names = append(names, fmt.Sprintf("%s (%s)", r.URI, r.Name))
+			}
+			def := ToolDefinition{
+				Name:        mcpToolName(c.Name, "read_resource"),
+				Description: fmt.Sprintf("[%s] Read a resource by URI. Available: %s", c.Name, strings.Join(names, ", ")),
+				Parameters:  []string{"uri"},
+			}
+			if !offered[def.Name] {
+				result = append(result, &mcpTool{def: def, client: c, readOnly: c.ReadOnly("read_resource")})
+			}
+		}
+
+		if prompts := c.Prompts(); len(prompts) > 0 {
+			var names []string
+			for i, p := range prompts {
+				if i == maxListedItems {
+					names = append(names, fmt.Sprintf("and %d more", len(prompts)-i))
+					break
+				}
+				var args []string
+				for _, a := range p.Arguments {
+					args = append(args, a.Name)
+				}
+				names = append(names, fmt.Sprintf("%s(%s)", p.Name, strings.Join(args, ", ")))
+			}
+			def := ToolDefinition{
+				Name:        mcpToolName(c.Name, "get_prompt"),
+				Description: fmt.Sprintf("[%s] Expand a prompt template; arguments is a JSON object of strings. Available: %s", c.Name, strings.Join(names, ", ")),
+				Parameters:  []string{"name", "arguments"},
+				Optional:    []string{"arguments"},
+			}
+			if !offered[def.Name] {
+				result = append(result, &mcpTool{def: def, client: c, readOnly: c.ReadOnly("get_prompt")})
+			}
+		}
+	}
+	return result
+}
+
+// inputSchema is the part of a JSON schema used to map string params
+type inputSchema struct {
+	Properties map[string]struct {
+		Type interface{} `json:"type"`
+	} `json:"properties"`
+	Required []string `json:"required"`
+}
+
+// schemaParameters lists a schema's properties, required ones first
+func schemaParameters(raw json.RawMessage) ([]string, []string) {
+	var schema inputSchema
+	if err := json.Unmarshal(raw, &schema); err != nil {
+		return nil, nil
+	}
+
+	required := make(map[string]bool)
+	var params []string
+	for _, name := range schema.Required {
+		if _, ok := schema.Properties[name]; ok && !required[name] {
+			required[name] = true
+			params = append(params, name)
+		}
+	}
+
+	var optional []string
+	for name := range schema.Properties {
+		if !required[name] {
+			optional = append(optional, name)
+		}
+	}
+	sort.Strings(optional)
+	return append(params, optional...), optional
+}
+
+// mcpArguments converts string params to the JSON types the schema expects.
+// Values for non-string properties are parsed as JSON when possible.
+func mcpArguments(raw json.RawMessage, params map[string]string) map[string]interface{} {
+	var schema inputSchema
+	json.Unmarshal(raw, &schema)
+
+	args := make(map[string]interface{}, len(params))
+	for name, value := range params {
+		prop, ok := schema.Properties[name]
+		if t, isString := prop.Type.(string); !ok || (isString && t == "string") {
+			args[name] = value
+			continue
+		}
+		var parsed interface{}
+		if err := json.Unmarshal([]byte(value), &parsed); err == nil {
+			args[name] = parsed
+		} else {
+			args[name] = value
+		}
+	}
+	return args
+}
+
+// Execute calls the tool on its server, within the tool's policy timeout
+func (t *mcpTool) Execute(ctx context.Context, params map[string]string) ToolResult {
+	ctx, cancel := context.WithTimeout(ctx, PolicyFor(t.def.Name).Timeout)
+	defer cancel()
+
+	c := t.client
+	switch {
+	case t.tool != "":
+		result, err := c.CallTool(ctx, t.tool, mcpArguments(t.def.Schema, params))
+		if err != nil {
+			return ToolResult{Success: false, Error: fmt.Sprintf("%s: %v", c.Name, err)}
+		}
+		text := formatMCPContent(result.Content)
+		if text == "" && len(result.StructuredContent) > 0 {
+			text = string(result.StructuredContent)
+		}
+		if result.IsError {
+			return ToolResult{Success: false, Error: text}
+		}
+		return ToolResult{Success: true, Output: text}
+
+	case strings.HasSuffix(t.def.Name, "__read_resource"):
+		uri := params["uri"]
+		if uri == "" {
+			return ToolResult{Success: false, Error: "uri parameter required"}
+		}
+		result, err := c.ReadResource(ctx, uri)
+		if err != nil {
+			return ToolResult{Success: false, Error: fmt.Sprintf("%s: %v", c.Name, err)}
+		}
+		var sb strings.Builder
+		for _, rc := range result.Contents {
+			sb.WriteString(formatResourceContents(rc))
+		}
+		return ToolResult{Success: true, Output: sb.String()}
+
+	default:
+		name := params["name"]
+		if name == "" {
+			return ToolResult{Success: false, Error: "name parameter required"}
+		}
+		var args map[string]string
+		if a := params["arguments"]; a != "" {
+			if err := json.Unmarshal([]byte(a), &args); err != nil {
+				return ToolResult{Success: false, Error: fmt.Sprintf("arguments must be a JSON object of strings: %v", err)}
+			}
+		}
+		result, err := c.GetPrompt(ctx, name, args)
+		if err != nil {
+			return ToolResult{Success: false, Error: fmt.Sprintf("%s: %v", c.Name, err)}
+		}
+		var sb strings.Builder
+		if result.Description != "" {
+			sb.WriteString(result.Description + "\n\n")
+		}
+		for _, msg := range result.Messages {
+			sb.WriteString(fmt.Sprintf("%s: %s\n", msg.Role, formatMCPContent([]mcp.Content{msg.Content})))
+		}
+		return ToolResult{Success: true, Output: sb.String()}
+	}
+}
+
+// formatMCPContent renders result content as text; binary items are
+// summarised
+func formatMCPContent(content []mcp.Content) string {
+	var parts []string
+	for _, item := range content {
+		switch item.Type {
+		case "text":
+			parts = append(parts, item.Text)
+		case "image", "audio":
+			parts = append(parts, fmt.Sprintf("[%s: %s, %s]", item.Type, item.MimeType, formatSize(int64(len(item.Data)*3/4))))
+		case "resource":
+			if item.Resource != nil {
+				parts = append(parts, formatResourceContents(*item.Resource))
+			}
+		case "resource_link":
+			parts = append(parts, fmt.Sprintf("[resource: %s]", item.URI))
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// formatResourceContents renders one resource body
+func formatResourceContents(rc mcp.ResourceContents) string {
+	if rc.Blob != "" {
+		return fmt.Sprintf("%s: binary %s, %s; contents not shown\n", rc.URI, rc.MimeType, formatSize(int64(len(rc.Blob)*3/4)))
+	}
+	return rc.Text
+}
+
+// NewMCPServer exposes the built-in tools as an MCP server. Calls go
+// through execute, which should run them as the chat does: through the
+// hooks and ExecuteToolContext, so hooks, tool policies and output spilling
+// all apply. Tools proxied from other MCP servers are not re-exported.
+func NewMCPServer(version string, execute func(context.Context, ToolCall) ToolResult) *mcp.Server {
+	cwd, _ := os.Getwd()
+	server := &mcp.Server{
+		Info:         mcp.Implementation{Name: "zesbe-go", Version: version},
+		Instructions: fmt.Sprintf("Tools operate on the workspace %s. Relative paths resolve against it.", cwd),
+		Tools:        []mcp.Tool{},
+		CallTool: func(ctx context.Context, name string, args map[string]json.RawMessage) mcp.CallToolResult {
+			result := execute(ctx, ToolCall{Name: name, Params: StringParams(args)})
+			if !result.Success {
+				text := "Error: " + result.Error
+				if result.Output != "" {
+					text += "\n" + result.Output
+				}
+				return mcp.CallToolResult{Content: []mcp.Content{{Type: "text", Text: text}}, IsError: true}
+			}
+			return mcp.CallToolResult{Content: []mcp.Content{{Type: "text", Text: result.Output}}}
+		},
+	}
+
+	for _, t := range Available() {
+		if _, proxied := t.(*mcpTool); proxied {
+			continue
+		}
+		def := t.Schema()
+		server.Tools = append(server.Tools, mcp.Tool{
+			Name:        def.Name,
+			Description: def.Description,
+			InputSchema: def.InputSchema(),
+		})
+	}
+	return server
+}
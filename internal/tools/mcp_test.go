@@ -0,0 +1,25 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMCPToolName(t *testing.T) {
+	if got := mcpToolName("git hub", "create.issue"); got != "mcp__git_hub__create_issue" {
+		t.Errorf("mcpToolName = %q, want invalid characters replaced", got)
+	}
+
+	long := strings.Repeat("x", 60)
+	a := mcpToolName("server", long+"_alpha")
+	b := mcpToolName("server", long+"_beta")
+	if len(a) != maxToolName || len(b) != maxToolName {
+		t.Errorf("lengths %d and %d, want %d", len(a), len(b), maxToolName)
+	}
+	if a == b {
+		t.Errorf("long names sharing a prefix both became %q", a)
+	}
+	if a != mcpToolName("server", long+"_alpha") {
+		t.Error("mcpToolName isn't stable")
+	}
+}
@@ -0,0 +1,206 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zesbe/zesbe-go/internal/config"
+)
+
+// Policy limits how long a tool may run and how much of its output is
+// returned to the model
+type Policy struct {
+	Timeout         time.Duration // Default timeout for tools that run external work
+	MaxTimeout      time.Duration // Ceiling for timeouts requested by the model
+	MaxOutputBytes  int           // Output sent back to the model (0 = unlimited)
+	MaxDisplayBytes int           // Output shown in the chat (0 = unlimited)
+	Truncate        string        // TruncateHead, TruncateTail or TruncateHeadTail
+}
+
+// defaultPolicies are the built-in limits; "*" applies to every other tool
+var defaultPolicies = map[string]Policy{
+	"*":           {Timeout: 30 * time.Second, MaxTimeout: 30 * time.Second, MaxOutputBytes: 16000, MaxDisplayBytes: MaxDisplayBytes, Truncate: TruncateHead},
+	"run_command": {Timeout: 30 * time.Second, MaxTimeout: 10 * time.Minute, MaxOutputBytes: 16000, MaxDisplayBytes: MaxDisplayBytes, Truncate: TruncateHeadTail},
+	"read_file":   {Timeout: 30 * time.Second, MaxTimeout: 30 * time.Second, MaxOutputBytes: 50000, MaxDisplayBytes: MaxDisplayBytes, Truncate: TruncateHead},
+	"fetch_url":   {Timeout: 30 * time.Second, MaxTimeout: 2 * time.Minute, MaxOutputBytes: 15000, MaxDisplayBytes: MaxDisplayBytes, Truncate: TruncateHead},
+	"git_clone":   {Timeout: 5 * time.Minute, MaxTimeout: 5 * time.Minute, MaxOutputBytes: 16000, MaxDisplayBytes: MaxDisplayBytes, Truncate: TruncateHead},
+	"git_push":    {Timeout: 2 * time.Minute, MaxTimeout: 2 * time.Minute, MaxOutputBytes: 16000, MaxDisplayBytes: MaxDisplayBytes, Truncate: TruncateHead},
+	"git_pull":    {Timeout: 2 * time.Minute, MaxTimeout: 2 * time.Minute, MaxOutputBytes: 16000, MaxDisplayBytes: MaxDisplayBytes, Truncate: TruncateHead},
+}
+
+var (
+	policyMu sync.RWMutex
+	policies = defaultPolicies
+)
+
+// Configure applies tool settings from the application config and starts
+// the language servers. The chat and mcp serve both set up the tools with
+// it; only the chat goes on to StartMCPServers, as mcp serve doesn't
+// re-export their tools. Shutdown undoes it.
+func Configure(cfg *config.Config) error {
+	if cfg.PersistentShell {
+		EnablePersistentShell()
+	}
+	SetGitSettings(cfg.Git)
+	StartLSP(cfg.LSPServers)
+	// A bad setting doesn't stop the ones after it from applying
+	return errors.Join(
+		SetToolAccess(cfg.ToolAccess),
+		SetPolicies(cfg.ToolPolicies),
+		SetCustomTools(cfg.AllCustomTools()),
+		SetFileHooks(cfg.FileHooks),
+	)
+}
+
+// Shutdown stops what the tools leave running: background jobs, the
+// persistent shell, and the MCP and language servers
+func Shutdown() {
+	Processes.KillAll()
+	ResetShellSession()
+	StopMCPServers()
+	StopLSP()
+}
+
+// SetPolicies merges per-tool overrides onto the built-in defaults. Fields
+// left empty keep the default for that tool, or the "*" entry otherwise.
+func SetPolicies(overrides map[string]config.ToolPolicy) error {
+	merged := make(map[string]Policy, len(defaultPolicies)+len(overrides))
+	for name, p := range defaultPolicies {
+		merged[name] = p
+	}
+
+	// The wildcard goes first so per-tool entries inherit from it
+	names := make([]string, 0, len(overrides))
+	if _, ok := overrides["*"]; ok {
+		names = append(names, "*")
+	}
+	for name := range overrides {
+		if name != "*" {
+			names = append(names, name)
+		}
+	}
+
+	for _, name := range names {
+		base, ok := merged[name]
+		if !ok {
+			base = merged["*"]
+		}
+		p, err := applyPolicy(base, overrides[name])
+		if err != nil {
+			return fmt.Errorf("tool_policies[%s]: %w", name, err)
+		}
+		merged[name] = p
+	}
+
+	policyMu.Lock()
+	policies = merged
+	policyMu.Unlock()
+	return nil
+}
+
+// applyPolicy overlays the non-empty fields of o onto base
+func applyPolicy(base Policy, o config.ToolPolicy) (Policy, error) {
+	p := base
+	if o.Timeout != "" {
+		d, err := ParseTimeout(o.Timeout)
+		if err != nil {
+			return p, fmt.Errorf("timeout: %w", err)
+		}
+		p.Timeout = d
+	}
+	if o.MaxTimeout != "" {
+		d, err := ParseTimeout(o.MaxTimeout)
+		if err != nil {
+			return p, fmt.Errorf("max_timeout: %w", err)
+		}
+		p.MaxTimeout = d
+	}
+	if p.MaxTimeout < p.Timeout {
+		// An explicit ceiling wins over an inherited default
+		if o.MaxTimeout != "" && o.Timeout == "" {
+			p.Timeout = p.MaxTimeout
+		} else {
+			p.MaxTimeout = p.Timeout
+		}
+	}
+	if o.MaxOutputBytes != 0 {
+		p.MaxOutputBytes = o.MaxOutputBytes
+	}
+	if o.MaxDisplayBytes != 0 {
+		p.MaxDisplayBytes = o.MaxDisplayBytes
+	}
+	switch o.Truncate {
+	case "":
+	case TruncateHead, TruncateTail, TruncateHeadTail:
+		p.Truncate = o.Truncate
+	default:
+		return p, fmt.Errorf("truncate must be head, tail or head_tail, got %q", o.Truncate)
+	}
+	return p, nil
+}
+
+// PolicyFor returns the effective policy for a tool
+func PolicyFor(name string) Policy {
+	policyMu.RLock()
+	defer policyMu.RUnlock()
+	if p, ok := policies[name]; ok {
+		return p
+	}
+	return policies["*"]
+}
+
+// ParseTimeout accepts a Go duration ("90s", "2m") or a bare number of seconds
+func ParseTimeout(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if secs, err := strconv.ParseFloat(s, 64); err == nil {
+		if secs <= 0 {
+			return 0, fmt.Errorf("must be positive")
+		}
+		return time.Duration(secs * float64(time.Second)), nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("must be positive")
+	}
+	return d, nil
+}
+
+// requestTimeout resolves a timeout asked for by the model against the tool
+// policy. The returned note is non-empty when the request was capped.
+func requestTimeout(p Policy, requested string) (time.Duration, string, error) {
+	if requested == "" {
+		return p.Timeout, "", nil
+	}
+	d, err := ParseTimeout(requested)
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid timeout: %v", err)
+	}
+	if d > p.MaxTimeout {
+		return p.MaxTimeout, fmt.Sprintf("(requested timeout %s exceeds the limit; using %s)", d, p.MaxTimeout), nil
+	}
+	return d, "", nil
+}
+
+// DisplayOutput shortens a tool result for the chat to the tool's
+// max_display_bytes, keeping the part its truncate setting names
+func DisplayOutput(name, output string) string {
+	p := PolicyFor(name)
+	return TruncateOutput(output, p.MaxDisplayBytes, p.Truncate)
+}
+
+// applyOutputPolicy truncates a tool result according to the tool policy.
+// The full output is spilled to disk so the model can retrieve the rest.
+func applyOutputPolicy(name string, result ToolResult) ToolResult {
+	p := PolicyFor(name)
+	if p.MaxOutputBytes > 0 && len(result.Output) > p.MaxOutputBytes {
+		result.Output = spillOutput(name, result.Output, p)
+	}
+	return result
+}
@@ -0,0 +1,90 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zesbe/zesbe-go/internal/config"
+)
+
+// setPolicies applies overrides for the length of a test
+func setPolicies(t *testing.T, overrides map[string]config.ToolPolicy) {
+	t.Helper()
+	if err := SetPolicies(overrides); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { SetPolicies(nil) })
+}
+
+func TestSetPolicies(t *testing.T) {
+	setPolicies(t, map[string]config.ToolPolicy{
+		"*":           {Timeout: "10s", MaxDisplayBytes: 100},
+		"grep_files":  {MaxOutputBytes: 500, Truncate: TruncateTail},
+		"run_command": {MaxTimeout: "5s"},
+		"fetch_url":   {Timeout: "3m"},
+	})
+
+	tests := []struct {
+		tool string
+		want Policy
+	}{
+		// The wildcard reaches tools without defaults of their own
+		{"list_directory", Policy{Timeout: 10 * time.Second, MaxTimeout: 30 * time.Second, MaxOutputBytes: 16000, MaxDisplayBytes: 100, Truncate: TruncateHead}},
+		{"grep_files", Policy{Timeout: 10 * time.Second, MaxTimeout: 30 * time.Second, MaxOutputBytes: 500, MaxDisplayBytes: 100, Truncate: TruncateTail}},
+		// An explicit ceiling lowers the default timeout under it
+		{"run_command", Policy{Timeout: 5 * time.Second, MaxTimeout: 5 * time.Second, MaxOutputBytes: 16000, MaxDisplayBytes: MaxDisplayBytes, Truncate: TruncateHeadTail}},
+		// A default above the built-in ceiling raises the ceiling
+		{"fetch_url", Policy{Timeout: 3 * time.Minute, MaxTimeout: 3 * time.Minute, MaxOutputBytes: 15000, MaxDisplayBytes: MaxDisplayBytes, Truncate: TruncateHead}},
+	}
+	for _, tt := range tests {
+		if got := PolicyFor(tt.tool); got != tt.want {
+			t.Errorf("PolicyFor(%s) = %+v, want %+v", tt.tool, got, tt.want)
+		}
+	}
+}
+
+func TestSetPoliciesInvalid(t *testing.T) {
+	defer SetPolicies(nil)
+	for _, o := range []config.ToolPolicy{{Timeout: "soon"}, {MaxTimeout: "-1"}, {Truncate: "middle"}} {
+		if err := SetPolicies(map[string]config.ToolPolicy{"read_file": o}); err == nil {
+			t.Errorf("SetPolicies(%+v) = nil, want an error", o)
+		}
+	}
+}
+
+func TestDisplayOutput(t *testing.T) {
+	setPolicies(t, map[string]config.ToolPolicy{
+		"run_command": {MaxDisplayBytes: 20, Truncate: TruncateTail},
+	})
+	output := strings.Repeat("line\n", 9) + "last line\n"
+
+	if got := DisplayOutput("run_command", output); !strings.HasSuffix(got, "last line") || len(got) > 60 {
+		t.Errorf("run_command display = %q, want the tail within its limit", got)
+	}
+	if got := DisplayOutput("read_file", output); got != output {
+		t.Errorf("read_file display = %q, want short output untouched", got)
+	}
+	long := strings.Repeat("x\n", MaxDisplayBytes)
+	if got := DisplayOutput("read_file", long); len(got) > MaxDisplayBytes+50 || !strings.HasPrefix(got, "x\n") {
+		t.Errorf("read_file display is %d bytes, want the head within the default limit", len(got))
+	}
+}
+
+func TestConfigureKeepsGoingAfterBadSetting(t *testing.T) {
+	defer SetPolicies(nil)
+	defer SetToolAccess("")
+	defer Shutdown()
+
+	cfg := &config.Config{
+		ToolAccess:   "everything",
+		ToolPolicies: map[string]config.ToolPolicy{"read_file": {MaxOutputBytes: 123}},
+	}
+	err := Configure(cfg)
+	if err == nil || !strings.Contains(err.Error(), "tool_access") {
+		t.Errorf("Configure = %v, want the tool_access error", err)
+	}
+	if got := PolicyFor("read_file").MaxOutputBytes; got != 123 {
+		t.Errorf("read_file max_output_bytes = %d, want the policy applied after the bad setting", got)
+	}
+}
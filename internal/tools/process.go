@@ -0,0 +1,403 @@
+package tools
+
+import (
+	"fmt"
+	"io"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Background process limits
+const (
+	// ProcessBufferSize is the number of bytes kept per output stream
+	ProcessBufferSize = 64 * 1024
+	// MaxBackgroundJobs caps the number of live background processes
+	MaxBackgroundJobs = 10
+	// MaxExitedJobs caps the finished jobs kept for their unread output
+	MaxExitedJobs = 20
+)
+
+// ringBuffer keeps the last size bytes written to it and counts everything
+// ever written, so readers can resume from an absolute offset
+type ringBuffer struct {
+	mu    sync.Mutex
+	data  []byte
+	size  int
+	total int64
+}
+
+func newRingBuffer(size int) *ringBuffer {
+	return &ringBuffer{size: size}
+}
+
+// Write implements io.Writer
+func (r *ringBuffer) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.total += int64(len(p))
+	r.data = append(r.data, p...)
+	if len(r.data) > r.size {
+		r.data = append([]byte(nil), r.data[len(r.data)-r.size:]...)
+	}
+	return len(p), nil
+}
+
+// ReadSince returns everything written since offset, the new offset, and how
+// many bytes were lost because they fell out of the buffer
+func (r *ringBuffer) ReadSince(offset int64) (string, int64, int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	start := r.total - int64(len(r.data))
+	var dropped int64
+	if offset < start {
+		dropped = start - offset
+		offset = start
+	}
+	return string(r.data[offset-start:]), r.total, dropped
+}
+
+// BackgroundJob is a shell command running detached from the agent loop
+type BackgroundJob struct {
+	ID        string
+	Command   string
+	StartedAt time.Time
+
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	stdout  *ringBuffer
+	stderr  *ringBuffer
+	readOut int64
+	readErr int64
+	done    chan struct{}
+
+	mu       sync.Mutex
+	exitCode int
+	exitErr  error
+	endedAt  time.Time
+}
+
+// JobInfo is a snapshot of a background job for display
+type JobInfo struct {
+	ID       string
+	Command  string
+	Running  bool
+	ExitCode int
+	Runtime  time.Duration
+}
+
+// Running reports whether the process has not exited yet
+func (j *BackgroundJob) Running() bool {
+	select {
+	case <-j.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// Info returns a snapshot of the job state
+func (j *BackgroundJob) Info() JobInfo {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	info := JobInfo{ID: j.ID, Command: j.Command, Running: j.Running(), ExitCode: j.exitCode}
+	if info.Running {
+		info.Runtime = time.Since(j.StartedAt)
+	} else {
+		info.Runtime = j.endedAt.Sub(j.StartedAt)
+	}
+	return info
+}
+
+// status describes the job state in one line
+func (j *BackgroundJob) status() string {
+	info := j.Info()
+	if info.Running {
+		return fmt.Sprintf("running for %s", info.Runtime.Round(time.Second))
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.exitErr != nil && j.exitCode < 0 {
+		return fmt.Sprintf("exited after %s: %v", info.Runtime.Round(time.Second), j.exitErr)
+	}
+	return fmt.Sprintf("exited with code %d after %s", j.exitCode, info.Runtime.Round(time.Second))
+}
+
+// ProcessManager tracks background jobs started by the agent
+type ProcessManager struct {
+	mu     sync.Mutex
+	jobs   map[string]*BackgroundJob
+	nextID int
+}
+
+// Processes is the process manager used by the background process tools
+var Processes = NewProcessManager()
+
+// NewProcessManager creates an empty process manager
+func NewProcessManager() *ProcessManager {
+	return &ProcessManager{jobs: make(map[string]*BackgroundJob)}
+}
+
+// Start launches command in the background. An empty id is generated.
+func (pm *ProcessManager) Start(id, command string) (*BackgroundJob, error) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	running := 0
+	var exited []*BackgroundJob
+	for _, j := range pm.jobs {
+		if j.Running() {
+			running++
+		} else {
+			exited = append(exited, j)
+		}
+	}
+	if running >= MaxBackgroundJobs {
+		return nil, fmt.Errorf("too many background jobs running (max %d), kill one first", MaxBackgroundJobs)
+	}
+
+	if id == "" {
+		pm.nextID++
+		id = fmt.Sprintf("job-%d", pm.nextID)
+	}
+	if existing, ok := pm.jobs[id]; ok && existing.Running() {
+		return nil, fmt.Errorf("job %s is already running", id)
+	}
+
+	cmd := exec.Command("sh", "-c", command)
+	setProcessGroup(cmd)
+
+	job := &BackgroundJob{
+		ID:        id,
+		Command:   command,
+		StartedAt: time.Now(),
+		cmd:       cmd,
+		stdout:    newRingBuffer(ProcessBufferSize),
+		stderr:    newRingBuffer(ProcessBufferSize),
+		done:      make(chan struct{}),
+	}
+	cmd.Stdout = job.stdout
+	cmd.Stderr = job.stderr
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stdin: %w", err)
+	}
+	job.stdin = stdin
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start: %w", err)
+	}
+
+	go func() {
+		err := cmd.Wait()
+		job.mu.Lock()
+		job.endedAt = time.Now()
+		job.exitErr = err
+		job.exitCode = cmd.ProcessState.ExitCode()
+		job.mu.Unlock()
+		close(job.done)
+	}()
+
+	// Forget the oldest finished jobs whose output was never collected
+	sort.Slice(exited, func(i, k int) bool { return exited[i].StartedAt.Before(exited[k].StartedAt) })
+	for len(exited) >= MaxExitedJobs {
+		if pm.jobs[exited[0].ID] == exited[0] {
+			delete(pm.jobs, exited[0].ID)
+		}
+		exited = exited[1:]
+	}
+
+	pm.jobs[id] = job
+	return job, nil
+}
+
+// forget drops job if it has exited and its id hasn't been reused since
+func (pm *ProcessManager) forget(job *BackgroundJob) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	if pm.jobs[job.ID] == job && !job.Running() {
+		delete(pm.jobs, job.ID)
+	}
+}
+
+// Prune forgets every job that has exited and returns how many there were
+func (pm *ProcessManager) Prune() int {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	n := 0
+	for id, j := range pm.jobs {
+		if !j.Running() {
+			delete(pm.jobs, id)
+			n++
+		}
+	}
+	return n
+}
+
+// Get returns a job by ID
+func (pm *ProcessManager) Get(id string) (*BackgroundJob, bool) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	job, ok := pm.jobs[id]
+	return job, ok
+}
+
+// List returns snapshots of all jobs ordered by start time
+func (pm *ProcessManager) List() []JobInfo {
+	pm.mu.Lock()
+	jobs := make([]*BackgroundJob, 0, len(pm.jobs))
+	for _, j := range pm.jobs {
+		jobs = append(jobs, j)
+	}
+	pm.mu.Unlock()
+
+	sort.Slice(jobs, func(i, k int) bool { return jobs[i].StartedAt.Before(jobs[k].StartedAt) })
+
+	infos := make([]JobInfo, 0, len(jobs))
+	for _, j := range jobs {
+		infos = append(infos, j.Info())
+	}
+	return infos
+}
+
+// Kill terminates a job and its children and returns the job, which may
+// be pruned from the manager as soon as it has exited
+func (pm *ProcessManager) Kill(id string) (*BackgroundJob, error) {
+	job, ok := pm.Get(id)
+	if !ok {
+		return nil, fmt.Errorf("no such job: %s", id)
+	}
+	if !job.Running() {
+		return job, nil
+	}
+
+	killProcessGroup(job.cmd)
+	select {
+	case <-job.done:
+	case <-time.After(2 * time.Second):
+		return job, fmt.Errorf("job %s did not exit after kill", id)
+	}
+	return job, nil
+}
+
+// KillAll terminates every running job. Called when the app exits.
+func (pm *ProcessManager) KillAll() {
+	for _, info := range pm.List() {
+		if info.Running {
+			pm.Kill(info.ID)
+		}
+	}
+}
+
+// StartBackgroundProcess starts a command and returns its job ID
+func StartBackgroundProcess(id, command string) ToolResult {
+	job, err := Processes.Start(id, command)
+	if err != nil {
+		return ToolResult{Success: false, Error: err.Error()}
+	}
+
+	// Give fast-failing commands a moment to report
+	select {
+	case <-job.done:
+	case <-time.After(300 * time.Millisecond):
+	}
+
+	return ToolResult{Success: true, Output: fmt.Sprintf("Started %s (%s): %s\nUse read_process_output with id=%s to check on it.",
+		job.ID, job.status(), command, job.ID)}
+}
+
+// ReadProcessOutput returns stdout and stderr produced since the last read
+func ReadProcessOutput(id string) ToolResult {
+	job, ok := Processes.Get(id)
+	if !ok {
+		return ToolResult{Success: false, Error: fmt.Sprintf("no such job: %s", id)}
+	}
+
+	// Checked before reading so nothing can be written after the last read
+	exited := !job.Running()
+
+	job.mu.Lock()
+	stdout, outOffset, outDropped := job.stdout.ReadSince(job.readOut)
+	stderr, errOffset, errDropped := job.stderr.ReadSince(job.readErr)
+	job.readOut, job.readErr = outOffset, errOffset
+	job.mu.Unlock()
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Job %s %s\n", job.ID, job.status()))
+	writeStream := func(name, text string, dropped int64) {
+		if dropped > 0 {
+			sb.WriteString(fmt.Sprintf("[%s: %d earlier bytes dropped from buffer]\n", name, dropped))
+		}
+		if text != "" {
+			sb.WriteString(fmt.Sprintf("--- %s ---\n%s", name, text))
+			if !strings.HasSuffix(text, "\n") {
+				sb.WriteString("\n")
+			}
+		}
+	}
+	writeStream("stdout", stdout, outDropped)
+	writeStream("stderr", stderr, errDropped)
+	if stdout == "" && stderr == "" {
+		sb.WriteString("(no new output)\n")
+	}
+
+	// All of a finished job's output has been seen, so it can go
+	if exited {
+		Processes.forget(job)
+	}
+
+	return ToolResult{Success: true, Output: sb.String()}
+}
+
+// SendProcessInput writes input to a job's stdin, adding a trailing newline
+func SendProcessInput(id, input string) ToolResult {
+	job, ok := Processes.Get(id)
+	if !ok {
+		return ToolResult{Success: false, Error: fmt.Sprintf("no such job: %s", id)}
+	}
+	if !job.Running() {
+		return ToolResult{Success: false, Error: fmt.Sprintf("job %s is not running", id)}
+	}
+
+	if !strings.HasSuffix(input, "\n") {
+		input += "\n"
+	}
+	if _, err := io.WriteString(job.stdin, input); err != nil {
+		return ToolResult{Success: false, Error: fmt.Sprintf("failed to write to stdin: %v", err)}
+	}
+
+	return ToolResult{Success: true, Output: fmt.Sprintf("Sent %d bytes to %s", len(input), id)}
+}
+
+// KillProcess terminates a background job
+func KillProcess(id string) ToolResult {
+	job, err := Processes.Kill(id)
+	if err != nil {
+		return ToolResult{Success: false, Error: err.Error()}
+	}
+	return ToolResult{Success: true, Output: fmt.Sprintf("Job %s %s", id, job.status())}
+}
+
+// ListProcesses lists background jobs and their state
+func ListProcesses() ToolResult {
+	infos := Processes.List()
+	if len(infos) == 0 {
+		return ToolResult{Success: true, Output: "No background jobs"}
+	}
+
+	var sb strings.Builder
+	for _, info := range infos {
+		state := "running"
+		if !info.Running {
+			state = fmt.Sprintf("exited %d", info.ExitCode)
+		}
+		sb.WriteString(fmt.Sprintf("%-10s %-10s %8s  %s\n", info.ID, state, info.Runtime.Round(time.Second), info.Command))
+	}
+	return ToolResult{Success: true, Output: sb.String()}
+}
@@ -0,0 +1,119 @@
+package tools
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// waitExited waits for a background job to finish
+func waitExited(t *testing.T, job *BackgroundJob) {
+	t.Helper()
+	select {
+	case <-job.done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("job %s never exited", job.ID)
+	}
+}
+
+func TestProcessPrunedAfterFinalRead(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a POSIX shell")
+	}
+	old := Processes
+	Processes = NewProcessManager()
+	defer func() { Processes = old }()
+
+	job, err := Processes.Start("", "echo done")
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitExited(t, job)
+
+	if _, ok := Processes.Get(job.ID); !ok {
+		t.Fatal("job dropped before its output was read")
+	}
+	if r := ReadProcessOutput(job.ID); !strings.Contains(r.Output, "done") {
+		t.Fatalf("output = %q, want the job's output", r.Output)
+	}
+	if _, ok := Processes.Get(job.ID); ok {
+		t.Error("job kept after its output was read")
+	}
+}
+
+func TestProcessManagerPrune(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a POSIX shell")
+	}
+	pm := NewProcessManager()
+	defer pm.KillAll()
+
+	finished, err := pm.Start("finished", "true")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pm.Start("running", "sleep 30"); err != nil {
+		t.Fatal(err)
+	}
+	waitExited(t, finished)
+
+	if n := pm.Prune(); n != 1 {
+		t.Errorf("Prune = %d, want 1", n)
+	}
+	if jobs := pm.List(); len(jobs) != 1 || jobs[0].ID != "running" {
+		t.Errorf("List after Prune = %+v, want only the running job", jobs)
+	}
+}
+
+func TestProcessManagerEvictsOldestExited(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a POSIX shell")
+	}
+	pm := NewProcessManager()
+	var first *BackgroundJob
+	for i := 0; i <= MaxExitedJobs; i++ {
+		job, err := pm.Start("", "true")
+		if err != nil {
+			t.Fatal(err)
+		}
+		waitExited(t, job)
+		if first == nil {
+			first = job
+		}
+	}
+
+	if n := len(pm.List()); n != MaxExitedJobs {
+		t.Errorf("%d jobs kept, want %d", n, MaxExitedJobs)
+	}
+	if _, ok := pm.Get(first.ID); ok {
+		t.Errorf("oldest job %s kept", first.ID)
+	}
+}
+
+func TestKillProcessAfterPrune(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a POSIX shell")
+	}
+	old := Processes
+	Processes = NewProcessManager()
+	defer func() { Processes = old }()
+
+	job, err := Processes.Start("", "sleep 30")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Prune straight after the job exits, as /jobs clear could
+	pm, pruned := Processes, make(chan struct{})
+	go func() {
+		<-job.done
+		pm.Prune()
+		close(pruned)
+	}()
+
+	result := KillProcess(job.ID)
+	<-pruned
+	if !result.Success || !strings.Contains(result.Output, job.ID) {
+		t.Errorf("KillProcess = %+v", result)
+	}
+}
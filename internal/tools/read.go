@@ -0,0 +1,152 @@
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// Read limits for read_file
+const (
+	// DefaultReadLimit is the number of lines returned when no limit is given
+	DefaultReadLimit = 2000
+	// MaxLineLength is the longest line returned before it is cut
+	MaxLineLength = 2000
+	// sniffSize is how many leading bytes are inspected to classify a file
+	sniffSize = 8192
+)
+
+// ReadFileRange reads lines [offset, offset+limit) of a file, 1-based, with
+// line-number prefixes. Binary, image and non-UTF-8 files are summarised
+// instead of being returned verbatim.
+func ReadFileRange(path string, offset, limit int) ToolResult {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return ToolResult{Success: false, Error: fmt.Sprintf("invalid path: %v", err)}
+	}
+
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return ToolResult{Success: false, Error: fmt.Sprintf("failed to read file: %v", err)}
+	}
+	if info.IsDir() {
+		return ToolResult{Success: false, Error: fmt.Sprintf("%s is a directory, use list_directory", path)}
+	}
+
+	f, err := os.Open(absPath)
+	if err != nil {
+		return ToolResult{Success: false, Error: fmt.Sprintf("failed to read file: %v", err)}
+	}
+	defer f.Close()
+
+	head := make([]byte, sniffSize)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return ToolResult{Success: false, Error: fmt.Sprintf("failed to read file: %v", err)}
+	}
+	head = head[:n]
+
+	if kind := classifyContent(head, n < sniffSize); kind != "" {
+		return ToolResult{Success: true, Output: fmt.Sprintf("%s: %s, %s; contents not shown",
+			path, kind, formatSize(info.Size()))}
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return ToolResult{Success: false, Error: fmt.Sprintf("failed to read file: %v", err)}
+	}
+
+	header := fmt.Sprintf("File: %s", path)
+	return readLineRange(f, header, info.Size(), offset, limit)
+}
+
+// readLineRange formats lines [offset, offset+limit) of r with line-number
+// prefixes under a header line describing the source
+func readLineRange(r io.Reader, header string, size int64, offset, limit int) ToolResult {
+	if offset <= 0 {
+		offset = 1
+	}
+	if limit <= 0 {
+		limit = DefaultReadLimit
+	}
+	end := offset + limit - 1
+
+	var body strings.Builder
+	total := 0
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			total++
+			if total >= offset && total <= end {
+				line = strings.TrimRight(line, "\r\n")
+				if len(line) > MaxLineLength {
+					line = TruncateRunes(line, MaxLineLength) + " ... (line truncated)"
+				}
+				body.WriteString(fmt.Sprintf("%6d\t%s\n", total, line))
+			}
+		}
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return ToolResult{Success: false, Error: fmt.Sprintf("failed to read file: %v", err)}
+		}
+	}
+
+	var out strings.Builder
+	out.WriteString(fmt.Sprintf("%s (%d lines, %s)\n", header, total, formatSize(size)))
+
+	switch {
+	case total == 0:
+		out.WriteString("(empty file)\n")
+		return ToolResult{Success: true, Output: out.String()}
+	case offset > total:
+		return ToolResult{Success: false, Output: out.String(),
+			Error: fmt.Sprintf("offset %d is past the end (%d lines)", offset, total)}
+	}
+
+	if end > total {
+		end = total
+	}
+	out.WriteString(fmt.Sprintf("Showing lines %d–%d of %d\n", offset, end, total))
+	out.WriteString(body.String())
+	if end < total {
+		out.WriteString(fmt.Sprintf("... (%d more lines; use offset=%d to continue)\n", total-end, end+1))
+	}
+
+	return ToolResult{Success: true, Output: out.String()}
+}
+
+// classifyContent returns a short description when data does not look like
+// UTF-8 text, or "" for text. complete reports whether data is the whole file.
+func classifyContent(data []byte, complete bool) string {
+	if len(data) == 0 {
+		return ""
+	}
+
+	mime := http.DetectContentType(data)
+	if strings.HasPrefix(mime, "image/") {
+		return "image file (" + mime + ")"
+	}
+	if bytes.IndexByte(data, 0) >= 0 {
+		return "binary file (" + mime + ")"
+	}
+
+	// Don't fail on a rune split by the sniff window
+	if !complete {
+		for i := 0; i < utf8.UTFMax && len(data) > 0 && !utf8.Valid(data); i++ {
+			data = data[:len(data)-1]
+		}
+	}
+	if !utf8.Valid(data) {
+		return "non-UTF-8 text file"
+	}
+
+	return ""
+}
@@ -0,0 +1,82 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// numberedFile writes a file of n lines, "line 1" to "line n"
+func numberedFile(t *testing.T, n int) string {
+	t.Helper()
+	var sb strings.Builder
+	for i := 1; i <= n; i++ {
+		fmt.Fprintf(&sb, "line %d\n", i)
+	}
+	path := filepath.Join(t.TempDir(), "lines.txt")
+	if err := os.WriteFile(path, []byte(sb.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadFileArguments(t *testing.T) {
+	path := numberedFile(t, 10)
+
+	tests := []struct {
+		name          string
+		offset, limit string
+		want          []string // lines that must appear
+		wantNot       []string // lines that must not
+		err           string
+	}{
+		{"whole file", "", "", []string{"line 1\n", "line 10\n", "Showing lines 1–10 of 10"}, nil, ""},
+		{"range", "3", "2", []string{"line 3\n", "line 4\n", "use offset=5 to continue"}, []string{"line 2\n", "line 5\n"}, ""},
+		{"limit past the end", "9", "50", []string{"line 9\n", "line 10\n", "Showing lines 9–10 of 10"}, nil, ""},
+		{"zero means the default", "0", "0", []string{"line 1\n", "line 10\n"}, nil, ""},
+		{"padded number", " 4 ", "1", []string{"line 4\n"}, []string{"line 5\n"}, ""},
+		{"offset past the end", "11", "", nil, nil, "offset 11 is past the end (10 lines)"},
+		{"offset not a number", "abc", "", nil, nil, `offset must be a whole number of 0 or more, got "abc"`},
+		{"fractional offset", "2.5", "", nil, nil, `offset must be a whole number`},
+		{"negative limit", "1", "-5", nil, nil, `limit must be a whole number of 0 or more, got "-5"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := map[string]string{"path": path, "offset": tt.offset, "limit": tt.limit}
+			result := ExecuteToolContext(context.Background(), ToolCall{Name: "read_file", Params: params})
+			if tt.err != "" {
+				if result.Success || !strings.Contains(result.Error, tt.err) {
+					t.Errorf("result = %+v, want error %q", result, tt.err)
+				}
+				return
+			}
+			if !result.Success {
+				t.Fatalf("read failed: %s", result.Error)
+			}
+			for _, s := range tt.want {
+				if !strings.Contains(result.Output, s) {
+					t.Errorf("output missing %q:\n%s", s, result.Output)
+				}
+			}
+			for _, s := range tt.wantNot {
+				if strings.Contains(result.Output, s) {
+					t.Errorf("output has %q:\n%s", s, result.Output)
+				}
+			}
+		})
+	}
+}
+
+func TestReadFileSummarisesBinary(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.bin")
+	if err := os.WriteFile(path, []byte{0x7f, 'E', 'L', 'F', 0, 0, 1, 2}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	result := ReadFileRange(path, 1, DefaultReadLimit)
+	if !result.Success || !strings.Contains(result.Output, "binary file") {
+		t.Errorf("result = %+v, want a binary summary", result)
+	}
+}
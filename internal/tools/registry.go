@@ -0,0 +1,165 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Tool is a capability offered to the model. Built-in tools, MCP tools and
+// custom tools all implement it and are dispatched through a Registry.
+type Tool interface {
+	// Name is the identifier the model calls the tool by
+	Name() string
+	// Schema describes the tool and its parameters for prompts and APIs
+	Schema() ToolDefinition
+	// Indicator is how the tool is shown in the chat while it runs
+	Indicator() ToolIndicator
+	// Execute runs the tool with string parameters
+	Execute(ctx context.Context, params map[string]string) ToolResult
+	// IsReadOnly reports whether the tool leaves files and repos untouched
+	IsReadOnly() bool
+}
+
+// ToolSource supplies tools that can change at runtime, such as the tools
+// of connected MCP servers
+type ToolSource func() []Tool
+
+// Registry holds the tools available to the model, in registration order
+type Registry struct {
+	mu      sync.RWMutex
+	tools   map[string]Tool
+	order   []string
+	sources []namedSource
+}
+
+// namedSource is a ToolSource registered under a name so it can be replaced
+type namedSource struct {
+	name string
+	fn   ToolSource
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{tools: make(map[string]Tool)}
+}
+
+// DefaultRegistry holds the built-in tools plus any registered at runtime
+var DefaultRegistry = NewRegistry()
+
+// Register adds a tool. Names must be unique.
+func (r *Registry) Register(t Tool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.tools[t.Name()]; exists {
+		return fmt.Errorf("tool %s is already registered", t.Name())
+	}
+	r.tools[t.Name()] = t
+	r.order = append(r.order, t.Name())
+	return nil
+}
+
+// Unregister removes a tool added with Register
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.tools[name]; !exists {
+		return
+	}
+	delete(r.tools, name)
+	for i, n := range r.order {
+		if n == name {
+			r.order = append(r.order[:i], r.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// SetSource adds or replaces a dynamic tool source. A nil fn removes it.
+// Source tools never shadow registered tools of the same name.
+func (r *Registry) SetSource(name string, fn ToolSource) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, s := range r.sources {
+		if s.name == name {
+			if fn == nil {
+				r.sources = append(r.sources[:i], r.sources[i+1:]...)
+			} else {
+				r.sources[i].fn = fn
+			}
+			return
+		}
+	}
+	if fn != nil {
+		r.sources = append(r.sources, namedSource{name: name, fn: fn})
+	}
+}
+
+// List returns registered tools followed by tools from sources
+func (r *Registry) List() []Tool {
+	r.mu.RLock()
+	result := make([]Tool, 0, len(r.order))
+	for _, name := range r.order {
+		result = append(result, r.tools[name])
+	}
+	sources := append([]namedSource(nil), r.sources...)
+	r.mu.RUnlock()
+
+	seen := make(map[string]bool, len(result))
+	for _, t := range result {
+		seen[t.Name()] = true
+	}
+	for _, s := range sources {
+		for _, t := range s.fn() {
+			if !seen[t.Name()] {
+				seen[t.Name()] = true
+				result = append(result, t)
+			}
+		}
+	}
+	return result
+}
+
+// Get looks a tool up by name
+func (r *Registry) Get(name string) (Tool, bool) {
+	r.mu.RLock()
+	t, ok := r.tools[name]
+	sources := append([]namedSource(nil), r.sources...)
+	r.mu.RUnlock()
+	if ok {
+		return t, true
+	}
+
+	for _, s := range sources {
+		for _, t := range s.fn() {
+			if t.Name() == name {
+				return t, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// Execute dispatches a call to the named tool
+func (r *Registry) Execute(ctx context.Context, call ToolCall) ToolResult {
+	t, ok := r.Get(call.Name)
+	if !ok {
+		return ToolResult{Success: false, Error: fmt.Sprintf("unknown tool: %s", call.Name)}
+	}
+	params := call.Params
+	if params == nil {
+		params = map[string]string{}
+	}
+	return t.Execute(ctx, params)
+}
+
+// Register adds a tool to the default registry
+func Register(t Tool) error {
+	return DefaultRegistry.Register(t)
+}
+
+// IsReadOnly reports whether the named tool is registered and read-only
+func IsReadOnly(name string) bool {
+	t, ok := DefaultRegistry.Get(name)
+	return ok && t.IsReadOnly()
+}
@@ -0,0 +1,416 @@
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Search defaults
+const (
+	// DefaultMaxResults caps the number of matching lines returned
+	DefaultMaxResults = 100
+	// maxMatchLineLength is the longest matched line shown before cutting
+	maxMatchLineLength = 300
+)
+
+// UseRipgrep enables delegating searches to rg when it is on PATH
+var UseRipgrep = true
+
+// GrepOptions configures a content search
+type GrepOptions struct {
+	Pattern     string   // RE2 regular expression
+	Literal     bool     // Treat Pattern as a plain string
+	IgnoreCase  bool     // Case-insensitive matching
+	FilePattern string   // Glob applied to file base names
+	Extensions  []string // Only search files with these extensions
+	Context     int      // Lines of context around each match
+	MaxResults  int      // Maximum matching lines (0 = DefaultMaxResults)
+}
+
+// grepLine is one formatted line of search output
+type grepLine struct {
+	text  string
+	match bool // False for context lines and hunk separators
+}
+
+// grepFileResult holds the formatted matches of a single file
+type grepFileResult struct {
+	rel     string
+	lines   []grepLine
+	matches int
+}
+
+// Grep searches file contents under root using the shared walker. It
+// delegates to ripgrep when available and falls back to a parallel Go scan.
+func Grep(ctx context.Context, root string, opts GrepOptions) ToolResult {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return ToolResult{Success: false, Error: fmt.Sprintf("invalid path: %v", err)}
+	}
+
+	if opts.MaxResults <= 0 {
+		opts.MaxResults = DefaultMaxResults
+	}
+	if opts.Context < 0 {
+		opts.Context = 0
+	}
+
+	expr := opts.Pattern
+	note := ""
+	if opts.Literal {
+		expr = regexp.QuoteMeta(expr)
+	}
+	if opts.IgnoreCase {
+		expr = "(?i)" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		// Models often send literal code fragments like "foo(" - search for them verbatim
+		opts.Literal = true
+		expr = regexp.QuoteMeta(opts.Pattern)
+		if opts.IgnoreCase {
+			expr = "(?i)" + expr
+		}
+		re = regexp.MustCompile(expr)
+		note = fmt.Sprintf("(invalid regex %q, searched as literal text)\n", opts.Pattern)
+	}
+
+	lines, ok := grepRipgrep(ctx, absRoot, opts)
+	if !ok {
+		lines, err = grepNative(ctx, absRoot, re, opts)
+		if err != nil {
+			return ToolResult{Success: false, Error: fmt.Sprintf("search failed: %v", err)}
+		}
+	}
+
+	lines, truncated := capGrepLines(lines, opts.MaxResults)
+	if len(lines) == 0 {
+		return ToolResult{Success: true, Output: note + "No matches found"}
+	}
+
+	text := make([]string, len(lines))
+	for i, l := range lines {
+		text[i] = l.text
+	}
+	output := note + strings.Join(text, "\n")
+	if truncated {
+		output += fmt.Sprintf("\n... (showing first %d matches; narrow the search or raise max_results)", opts.MaxResults)
+	}
+
+	return ToolResult{Success: true, Output: output}
+}
+
+// grepNative scans files in parallel and returns formatted lines in walk
+// order. Results are taken in that order too, so the scan stops at the same
+// files, and gives the same output, however the workers are scheduled.
+func grepNative(ctx context.Context, root string, re *regexp.Regexp, opts GrepOptions) ([]grepLine, error) {
+	scanCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type job struct {
+		seq  int
+		path string
+	}
+	type result struct {
+		seq int
+		grepFileResult
+	}
+	paths := make(chan job, 256)
+	results := make(chan result, 256)
+
+	var wg sync.WaitGroup
+	for i := 0; i < runtime.NumCPU(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range paths {
+				// Files without matches are reported too, so the ordered
+				// prefix below can move past them
+				r, _ := grepFile(root, j.path, re, opts.Context)
+				select {
+				case results <- result{j.seq, r}:
+				case <-scanCtx.Done():
+				}
+			}
+		}()
+	}
+
+	walkErr := make(chan error, 1)
+	go func() {
+		defer close(paths)
+		seq := 0
+		walkErr <- walkFiles(root, WalkOptions{SkipLarge: true}, func(path string, info fs.FileInfo) error {
+			if !matchFileFilter(info.Name(), opts) {
+				return nil
+			}
+			select {
+			case paths <- job{seq, path}:
+				seq++
+				return nil
+			case <-scanCtx.Done():
+				return filepath.SkipAll
+			}
+		})
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// Stop once the files before the first unfinished one hold more matches
+	// than will be shown
+	pending := make(map[int]grepFileResult)
+	var collected []grepFileResult
+	next, matches := 0, 0
+	for r := range results {
+		pending[r.seq] = r.grepFileResult
+		for {
+			fr, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+			if fr.matches > 0 && matches <= opts.MaxResults {
+				collected = append(collected, fr)
+				matches += fr.matches
+			}
+		}
+		if matches > opts.MaxResults {
+			cancel()
+		}
+	}
+
+	if err := <-walkErr; err != nil && err != filepath.SkipAll {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var lines []grepLine
+	for i, r := range collected {
+		if opts.Context > 0 && i > 0 {
+			lines = append(lines, grepLine{text: "--"})
+		}
+		lines = append(lines, r.lines...)
+	}
+
+	return lines, nil
+}
+
+// grepFile searches one file and formats matches as path:line:text, with
+// context lines as path-line-text and "--" between separate hunks
+func grepFile(root, path string, re *regexp.Regexp, context int) (grepFileResult, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil || looksBinary(data) {
+		return grepFileResult{}, false
+	}
+
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		rel = path
+	}
+	rel = filepath.ToSlash(rel)
+
+	var fileLines []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), maxScanSize)
+	for scanner.Scan() {
+		fileLines = append(fileLines, scanner.Text())
+	}
+
+	result := grepFileResult{rel: rel}
+	lastPrinted := -1
+	for i, line := range fileLines {
+		if !re.MatchString(line) {
+			continue
+		}
+		result.matches++
+
+		start := i - context
+		if start <= lastPrinted {
+			start = lastPrinted + 1
+		}
+		if start < 0 {
+			start = 0
+		}
+		if lastPrinted >= 0 && start > lastPrinted+1 && context > 0 {
+			result.lines = append(result.lines, grepLine{text: "--"})
+		}
+		for j := start; j < i; j++ {
+			result.lines = append(result.lines, formatGrepLine(rel, j+1, '-', fileLines[j]))
+		}
+		result.lines = append(result.lines, formatGrepLine(rel, i+1, ':', line))
+		lastPrinted = i
+
+		// Trailing context is emitted lazily so overlapping hunks merge
+		for j := i + 1; j <= i+context && j < len(fileLines); j++ {
+			if re.MatchString(fileLines[j]) {
+				break
+			}
+			result.lines = append(result.lines, formatGrepLine(rel, j+1, '-', fileLines[j]))
+			lastPrinted = j
+		}
+	}
+
+	return result, result.matches > 0
+}
+
+// formatGrepLine renders a single output line with a rune-safe length cap
+func formatGrepLine(rel string, lineNo int, sep byte, text string) grepLine {
+	text = strings.TrimRight(text, " \t\r")
+	if len(text) > maxMatchLineLength {
+		text = TruncateRunes(text, maxMatchLineLength) + "..."
+	}
+	return grepLine{
+		text:  fmt.Sprintf("%s%c%d%c%s", rel, sep, lineNo, sep, text),
+		match: sep == ':',
+	}
+}
+
+// capGrepLines keeps output up to maxResults match lines and reports whether
+// any were dropped
+func capGrepLines(lines []grepLine, maxResults int) ([]grepLine, bool) {
+	matches := 0
+	for i, line := range lines {
+		if line.match {
+			matches++
+			if matches > maxResults {
+				return lines[:i], true
+			}
+		}
+	}
+	return lines, false
+}
+
+// matchFileFilter applies the file pattern and extension filters
+func matchFileFilter(name string, opts GrepOptions) bool {
+	if opts.FilePattern != "" {
+		if matched, _ := filepath.Match(opts.FilePattern, name); !matched {
+			return false
+		}
+	}
+	if len(opts.Extensions) > 0 {
+		ext := filepath.Ext(name)
+		for _, e := range opts.Extensions {
+			if ext == e {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// grepRipgrep runs the search through rg. ok is false when rg is unavailable
+// or fails, in which case the caller falls back to the native scanner.
+func grepRipgrep(ctx context.Context, root string, opts GrepOptions) ([]grepLine, bool) {
+	if !UseRipgrep {
+		return nil, false
+	}
+	rg, err := exec.LookPath("rg")
+	if err != nil {
+		return nil, false
+	}
+
+	// JSON output keeps paths and line numbers apart however odd the path
+	args := []string{"--json", "--no-require-git", "--max-filesize", "10M", "--sort", "path"}
+	for _, dir := range sortedSkipDirs() {
+		args = append(args, "--glob", "!"+dir+"/")
+	}
+	if opts.IgnoreCase {
+		args = append(args, "--ignore-case")
+	}
+	if opts.Literal {
+		args = append(args, "--fixed-strings")
+	}
+	if opts.Context > 0 {
+		args = append(args, "--context", fmt.Sprint(opts.Context))
+	}
+	if opts.FilePattern != "" {
+		args = append(args, "--glob", opts.FilePattern)
+	}
+	for _, ext := range opts.Extensions {
+		args = append(args, "--glob", "*"+ext)
+	}
+	args = append(args, "--regexp", opts.Pattern, ".")
+
+	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, rg, args...)
+	cmd.Dir = root
+	out, err := cmd.Output()
+	if err != nil {
+		// Exit status 1 means no matches
+		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+			return nil, true
+		}
+		return nil, false
+	}
+
+	return parseRipgrepJSON(out, opts.Context), true
+}
+
+// rgText is a string in rg's JSON output; text that isn't UTF-8 comes as
+// base64 bytes instead
+type rgText struct {
+	Text  string `json:"text"`
+	Bytes []byte `json:"bytes"`
+}
+
+func (t rgText) String() string {
+	if t.Bytes != nil {
+		return string(t.Bytes)
+	}
+	return t.Text
+}
+
+// parseRipgrepJSON turns rg --json output into the lines grepFile produces,
+// with "--" between hunks when context was asked for
+func parseRipgrepJSON(out []byte, context int) []grepLine {
+	var lines []grepLine
+	lastFile, lastLine := "", 0
+	for _, raw := range bytes.Split(out, []byte("\n")) {
+		var msg struct {
+			Type string `json:"type"`
+			Data struct {
+				Path       rgText `json:"path"`
+				Lines      rgText `json:"lines"`
+				LineNumber int    `json:"line_number"`
+			} `json:"data"`
+		}
+		if json.Unmarshal(raw, &msg) != nil || (msg.Type != "match" && msg.Type != "context") {
+			continue
+		}
+
+		file := filepath.ToSlash(strings.TrimPrefix(msg.Data.Path.String(), "./"))
+		line := msg.Data.LineNumber
+		if context > 0 && lastFile != "" && (file != lastFile || line > lastLine+1) {
+			lines = append(lines, grepLine{text: "--"})
+		}
+		lastFile, lastLine = file, line
+
+		sep := byte('-')
+		if msg.Type == "match" {
+			sep = ':'
+		}
+		text := strings.TrimRight(msg.Data.Lines.String(), "\n")
+		lines = append(lines, formatGrepLine(file, line, sep, text))
+	}
+	return lines
+}
@@ -0,0 +1,99 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeTree creates files under a temporary directory
+func writeTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, data := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+// nativeGrep runs Grep without ripgrep
+func nativeGrep(t *testing.T, root string, opts GrepOptions) string {
+	t.Helper()
+	old := UseRipgrep
+	UseRipgrep = false
+	defer func() { UseRipgrep = old }()
+
+	result := Grep(context.Background(), root, opts)
+	if !result.Success {
+		t.Fatalf("Grep: %s", result.Error)
+	}
+	return result.Output
+}
+
+func TestGrepNativeCapIsDeterministic(t *testing.T) {
+	files := make(map[string]string)
+	for i := 0; i < 200; i++ {
+		files[fmt.Sprintf("d%d/f%03d.txt", i%7, i)] = "needle\nhay\nneedle\n"
+	}
+	root := writeTree(t, files)
+
+	want := nativeGrep(t, root, GrepOptions{Pattern: "needle", MaxResults: 25})
+	if !strings.HasPrefix(want, "d0/f000.txt:1:needle\n") {
+		t.Errorf("output starts %q, want the first file in walk order", want[:40])
+	}
+	for i := 0; i < 20; i++ {
+		if got := nativeGrep(t, root, GrepOptions{Pattern: "needle", MaxResults: 25}); got != want {
+			t.Fatalf("run %d differs:\n%s\nwant:\n%s", i, got, want)
+		}
+	}
+}
+
+func TestGrepSkipsVendor(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"main.go":             "needle\n",
+		"vendor/dep/dep.go":   "needle\n",
+		"node_modules/x/x.js": "needle\n",
+		"internal/vendor.go":  "needle\n",
+		"pkg/v-2-x/a.go":      "needle\n",
+	})
+	got := nativeGrep(t, root, GrepOptions{Pattern: "needle"})
+	want := "internal/vendor.go:1:needle\nmain.go:1:needle\npkg/v-2-x/a.go:1:needle"
+	if got != want {
+		t.Errorf("output:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestParseRipgrepJSON(t *testing.T) {
+	out := strings.Join([]string{
+		`{"type":"begin","data":{"path":{"text":"./pkg/v-2-x/a.go"}}}`,
+		`{"type":"context","data":{"path":{"text":"./pkg/v-2-x/a.go"},"lines":{"text":"before\n"},"line_number":9}}`,
+		`{"type":"match","data":{"path":{"text":"./pkg/v-2-x/a.go"},"lines":{"text":"x-1-y:2:\n"},"line_number":10}}`,
+		`{"type":"match","data":{"path":{"text":"./pkg/v-2-x/a.go"},"lines":{"text":"far\n"},"line_number":40}}`,
+		`{"type":"end","data":{"path":{"text":"./pkg/v-2-x/a.go"}}}`,
+		`{"type":"match","data":{"path":{"bytes":"YjpjLmdv"},"lines":{"text":"odd\n"},"line_number":1}}`,
+		`{"type":"summary","data":{}}`,
+	}, "\n")
+
+	got := parseRipgrepJSON([]byte(out), 1)
+	want := []grepLine{
+		{text: "pkg/v-2-x/a.go-9-before"},
+		{text: "pkg/v-2-x/a.go:10:x-1-y:2:", match: true},
+		{text: "--"},
+		{text: "pkg/v-2-x/a.go:40:far", match: true},
+		{text: "--"},
+		{text: "b:c.go:1:odd", match: true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseRipgrepJSON =\n%+v\nwant\n%+v", got, want)
+	}
+}
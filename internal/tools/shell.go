@@ -0,0 +1,304 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ShellSession is a long-lived shell that runs commands one at a time, so
+// cd, export and sourced environments carry over between commands
+type ShellSession struct {
+	mu       sync.Mutex // Held by Run for the whole command
+	procMu   sync.Mutex // Guards proc, so Close can reach a busy shell
+	proc     *exec.Cmd
+	cmd      *exec.Cmd
+	stdin    io.WriteCloser
+	stdout   *shellStream
+	stderr   *shellStream
+	done     chan struct{}
+	lastWD   string // Process working directory at the previous command
+	shellDir string // Shell working directory after the previous command
+}
+
+// shellStream accumulates output from one of the shell's pipes
+type shellStream struct {
+	mu     sync.Mutex
+	buf    bytes.Buffer
+	notify chan struct{}
+}
+
+func newShellStream() *shellStream {
+	return &shellStream{notify: make(chan struct{}, 1)}
+}
+
+// Write implements io.Writer
+func (s *shellStream) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	s.buf.Write(p)
+	s.mu.Unlock()
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+	return len(p), nil
+}
+
+// String returns the buffered output that hasn't been taken yet
+func (s *shellStream) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.buf.String()
+}
+
+// take returns the output before the sentinel line and consumes it. The
+// sentinel line itself is returned separately.
+func (s *shellStream) take(sentinel string) (string, string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data := s.buf.String()
+	idx := strings.Index(data, sentinel)
+	if idx < 0 {
+		return "", "", false
+	}
+	end := strings.IndexByte(data[idx:], '\n')
+	if end < 0 {
+		return "", "", false
+	}
+
+	output := strings.TrimSuffix(data[:idx], "\n")
+	marker := data[idx : idx+end]
+	s.buf.Reset()
+	s.buf.WriteString(data[idx+end+1:])
+	return output, marker, true
+}
+
+var (
+	shellMu sync.Mutex
+	shell   *ShellSession
+)
+
+// EnablePersistentShell makes ExecuteCommand run commands in one shared
+// shell session instead of a fresh `sh -c` per command
+func EnablePersistentShell() {
+	shellMu.Lock()
+	defer shellMu.Unlock()
+	if shell == nil {
+		shell = &ShellSession{}
+	}
+}
+
+// ResetShellSession stops the persistent shell, if enabled. The next command
+// starts a fresh one with a clean environment.
+func ResetShellSession() {
+	shellMu.Lock()
+	defer shellMu.Unlock()
+	if shell != nil {
+		shell.Close()
+	}
+}
+
+// persistentShell returns the shared session, or nil when disabled
+func persistentShell() *ShellSession {
+	shellMu.Lock()
+	defer shellMu.Unlock()
+	return shell
+}
+
+// start launches the underlying shell process
+func (s *ShellSession) start() error {
+	path, err := exec.LookPath("bash")
+	args := []string{"--noprofile", "--norc"}
+	if err != nil {
+		path, args = "sh", nil
+	}
+
+	cmd := exec.Command(path, args...)
+	setProcessGroup(cmd)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	s.stdout = newShellStream()
+	s.stderr = newShellStream()
+	cmd.Stdout = s.stdout
+	cmd.Stderr = s.stderr
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	s.setCmd(cmd)
+	s.stdin = stdin
+	s.done = make(chan struct{})
+	s.lastWD = ""
+	s.shellDir = ""
+	go func() {
+		cmd.Wait()
+		close(s.done)
+	}()
+
+	return nil
+}
+
+// alive reports whether the shell process is running
+func (s *ShellSession) alive() bool {
+	if s.cmd == nil {
+		return false
+	}
+	select {
+	case <-s.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// Close kills the shell process. A command still running holds s.mu, so
+// the shell is killed first; Run then sees it exit and lets go of the lock.
+func (s *ShellSession) Close() {
+	s.procMu.Lock()
+	proc := s.proc
+	s.procMu.Unlock()
+	if proc != nil {
+		killProcessGroup(proc)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.kill()
+}
+
+func (s *ShellSession) kill() {
+	if s.alive() {
+		s.stdin.Close()
+		killProcessGroup(s.cmd)
+		<-s.done
+	}
+	s.setCmd(nil)
+}
+
+// setCmd records the shell process for both Run and Close
+func (s *ShellSession) setCmd(cmd *exec.Cmd) {
+	s.cmd = cmd
+	s.procMu.Lock()
+	s.proc = cmd
+	s.procMu.Unlock()
+}
+
+// Run executes command in the session and waits for it to finish, time out
+// or for ctx to be cancelled. A command that doesn't finish kills the
+// session; the next command gets a new shell.
+func (s *ShellSession) Run(ctx context.Context, command string, timeout time.Duration) ToolResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.alive() {
+		if err := s.start(); err != nil {
+			return ToolResult{Success: false, Error: fmt.Sprintf("failed to start shell: %v", err)}
+		}
+	}
+
+	// Follow change_directory: if the process cwd moved, move the shell too
+	var script strings.Builder
+	if wd, err := os.Getwd(); err == nil && wd != s.lastWD {
+		script.WriteString("cd " + shellQuote(wd) + "\n")
+		s.lastWD = wd
+	}
+
+	// Commands are passed through a quoted heredoc and eval'd so that syntax
+	// errors can't leave the shell waiting for more input
+	id := strings.ReplaceAll(uuid.New().String(), "-", "")
+	sentinel := "__ZESBE_DONE_" + id
+	delim := "__ZESBE_EOF_" + id
+	script.WriteString(fmt.Sprintf("eval \"$(cat <<'%s'\n%s\n%s\n)\" </dev/null\n", delim, command, delim))
+	script.WriteString(fmt.Sprintf("__zesbe_ec=$?; printf '\\n%s %%d %%s\\n' \"$__zesbe_ec\" \"$PWD\"; printf '\\n%s\\n' >&2\n", sentinel, sentinel))
+
+	if _, err := io.WriteString(s.stdin, script.String()); err != nil {
+		s.kill()
+		return ToolResult{Success: false, Error: fmt.Sprintf("shell session died: %v", err)}
+	}
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	var stdout, stderr, marker string
+	gotOut, gotErr := false, false
+	for !gotOut || !gotErr {
+		if !gotOut {
+			stdout, marker, gotOut = s.stdout.take(sentinel)
+		}
+		if !gotErr {
+			stderr, _, gotErr = s.stderr.take(sentinel)
+		}
+		if gotOut && gotErr {
+			break
+		}
+
+		select {
+		case <-s.stdout.notify:
+		case <-s.stderr.notify:
+		case <-s.done:
+			output := joinOutput(s.stdout.String(), s.stderr.String())
+			s.setCmd(nil)
+			return ToolResult{Success: false, Output: output, Error: "shell exited (a fresh shell will be started for the next command)"}
+		case <-deadline.C:
+			output := joinOutput(s.stdout.String(), s.stderr.String())
+			s.kill()
+			return ToolResult{Success: false, Output: output,
+				Error: fmt.Sprintf("command timed out after %s; the shell session was restarted, so environment and directory changes were lost", timeout)}
+		case <-ctx.Done():
+			output := joinOutput(s.stdout.String(), s.stderr.String())
+			s.kill()
+			return ToolResult{Success: false, Output: output,
+				Error: "command cancelled; the shell session was restarted, so environment and directory changes were lost"}
+		}
+	}
+
+	// Marker is "<sentinel> <exit code> <pwd>"
+	fields := strings.SplitN(strings.TrimPrefix(marker, sentinel+" "), " ", 2)
+	exitCode, _ := strconv.Atoi(fields[0])
+	if len(fields) > 1 {
+		s.shellDir = fields[1]
+	}
+
+	output := joinOutput(stdout, stderr)
+	if wd, _ := os.Getwd(); s.shellDir != "" && s.shellDir != wd {
+		if output = strings.TrimRight(output, "\n"); output != "" {
+			output += "\n"
+		}
+		output += fmt.Sprintf("(shell cwd: %s)", s.shellDir)
+	}
+
+	if exitCode != 0 {
+		return ToolResult{Success: false, Output: output, Error: fmt.Sprintf("exit status %d", exitCode)}
+	}
+	return ToolResult{Success: true, Output: output}
+}
+
+// joinOutput combines stdout and stderr the way ExecuteCommand does
+func joinOutput(stdout, stderr string) string {
+	if stderr == "" {
+		return stdout
+	}
+	if stdout != "" {
+		return stdout + "\n" + stderr
+	}
+	return stderr
+}
+
+// shellQuote quotes s for safe use as a single POSIX shell word
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,63 @@
+package tools
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestShellSessionKeepsState(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a POSIX shell")
+	}
+	s := &ShellSession{}
+	defer s.Close()
+
+	ctx := context.Background()
+	if r := s.Run(ctx, "export ZESBE_TEST=kept", time.Minute); !r.Success {
+		t.Fatalf("export: %+v", r)
+	}
+	if r := s.Run(ctx, "echo $ZESBE_TEST", time.Minute); r.Output != "kept\n" {
+		t.Errorf("output = %q, want the exported value", r.Output)
+	}
+}
+
+func TestShellSessionCloseWhileRunning(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a POSIX shell")
+	}
+	s := &ShellSession{}
+	done := make(chan ToolResult, 1)
+	go func() { done <- s.Run(context.Background(), "sleep 30", time.Minute) }()
+
+	// Wait for the command to be running
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		s.procMu.Lock()
+		started := s.proc != nil
+		s.procMu.Unlock()
+		if started {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("shell never started")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	closed := make(chan struct{})
+	go func() {
+		s.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close waited for the running command")
+	}
+	if r := <-done; r.Success {
+		t.Error("interrupted command reported success")
+	}
+}
@@ -28,19 +28,9 @@ type FileInfo struct {
 	ModTime string `json:"mod_time"`
 }
 
-// ReadFile reads the content of a file
+// ReadFile reads the first DefaultReadLimit lines of a file
 func ReadFile(path string) ToolResult {
-	absPath, err := filepath.Abs(path)
-	if err != nil {
-		return ToolResult{Success: false, Error: fmt.Sprintf("invalid path: %v", err)}
-	}
-
-	content, err := os.ReadFile(absPath)
-	if err != nil {
-		return ToolResult{Success: false, Error: fmt.Sprintf("failed to read file: %v", err)}
-	}
-
-	return ToolResult{Success: true, Output: string(content)}
+	return ReadFileRange(path, 1, DefaultReadLimit)
 }
 
 // WriteFile writes content to a file
@@ -119,90 +109,44 @@ func ListDirectory(path string) ToolResult {
 	return ToolResult{Success: true, Output: output.String()}
 }
 
-// FindFiles searches for files matching a pattern
+// FindFiles searches for files matching a glob pattern, sorted by name
 func FindFiles(root, pattern string) ToolResult {
-	absRoot, err := filepath.Abs(root)
-	if err != nil {
-		return ToolResult{Success: false, Error: fmt.Sprintf("invalid path: %v", err)}
-	}
-
-	var matches []string
-	err = filepath.Walk(absRoot, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return nil // Skip errors
-		}
-		matched, _ := filepath.Match(pattern, info.Name())
-		if matched {
-			matches = append(matches, path)
-		}
-		return nil
-	})
-
-	if err != nil {
-		return ToolResult{Success: false, Error: fmt.Sprintf("failed to search: %v", err)}
-	}
-
-	return ToolResult{Success: true, Output: strings.Join(matches, "\n")}
+	return Glob(root, GlobOptions{Pattern: pattern, Sort: "name"})
 }
 
-// GrepFiles searches for content in files
+// GrepFiles searches for a regular expression in files under root
 func GrepFiles(root, pattern, filePattern string) ToolResult {
-	absRoot, err := filepath.Abs(root)
-	if err != nil {
-		return ToolResult{Success: false, Error: fmt.Sprintf("invalid path: %v", err)}
-	}
-
-	var results strings.Builder
-	err = filepath.Walk(absRoot, func(path string, info os.FileInfo, err error) error {
-		if err != nil || info.IsDir() {
-			return nil
-		}
-
-		if filePattern != "" {
-			matched, _ := filepath.Match(filePattern, info.Name())
-			if !matched {
-				return nil
-			}
-		}
-
-		content, err := os.ReadFile(path)
-		if err != nil {
-			return nil
-		}
-
-		lines := strings.Split(string(content), "\n")
-		for i, line := range lines {
-			if strings.Contains(line, pattern) {
-				results.WriteString(fmt.Sprintf("%s:%d: %s\n", path, i+1, strings.TrimSpace(line)))
-			}
-		}
-
-		return nil
-	})
-
-	if err != nil {
-		return ToolResult{Success: false, Error: fmt.Sprintf("failed to search: %v", err)}
-	}
-
-	output := results.String()
-	if output == "" {
-		return ToolResult{Success: true, Output: "No matches found"}
-	}
-
-	return ToolResult{Success: true, Output: output}
+	return Grep(context.Background(), root, GrepOptions{Pattern: pattern, FilePattern: filePattern})
 }
 
-// ExecuteCommand runs a shell command with timeout
-func ExecuteCommand(command string, timeout time.Duration) ToolResult {
+// ExecuteCommand runs a shell command with timeout, stopping it early if ctx
+// is cancelled. When the persistent shell is enabled the command runs in the
+// shared session.
+func ExecuteCommand(ctx context.Context, command string, timeout time.Duration) ToolResult {
 	if timeout == 0 {
 		timeout = 30 * time.Second
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	if s := persistentShell(); s != nil {
+		return s.Run(ctx, command, timeout)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
+	// Kill the whole group so children holding the output pipes go too
 	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	setProcessGroup(cmd)
+	cmd.Cancel = func() error {
+		killProcessGroup(cmd)
+		return nil
+	}
+	return runCmd(ctx, cmd, timeout)
+}
 
+// runCmd runs cmd, which must have been created with ctx, and combines its
+// stdout and stderr into a result
+func runCmd(ctx context.Context, cmd *exec.Cmd, timeout time.Duration) ToolResult {
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
@@ -218,75 +162,10 @@ func ExecuteCommand(command string, timeout time.Duration) ToolResult {
 	}
 
 	if ctx.Err() == context.DeadlineExceeded {
-		return ToolResult{Success: false, Output: output, Error: "command timed out"}
-	}
-
-	if err != nil {
-		return ToolResult{Success: false, Output: output, Error: err.Error()}
+		return ToolResult{Success: false, Output: output, Error: fmt.Sprintf("command timed out after %s", timeout)}
 	}
-
-	return ToolResult{Success: true, Output: output}
-}
-
-// GitStatus returns the git status of a repository
-func GitStatus(path string) ToolResult {
-	return executeGitCommand(path, "status", "--short")
-}
-
-// GitDiff returns the git diff
-func GitDiff(path string, staged bool) ToolResult {
-	if staged {
-		return executeGitCommand(path, "diff", "--cached")
-	}
-	return executeGitCommand(path, "diff")
-}
-
-// GitLog returns recent git commits
-func GitLog(path string, count int) ToolResult {
-	if count <= 0 {
-		count = 10
-	}
-	return executeGitCommand(path, "log", fmt.Sprintf("-%d", count), "--oneline")
-}
-
-// GitBranch returns the current branch and lists branches
-func GitBranch(path string) ToolResult {
-	return executeGitCommand(path, "branch", "-a")
-}
-
-// GitAdd stages files for commit
-func GitAdd(path string, files ...string) ToolResult {
-	args := append([]string{"add"}, files...)
-	return executeGitCommand(path, args...)
-}
-
-// GitCommit creates a commit
-func GitCommit(path, message string) ToolResult {
-	return executeGitCommand(path, "commit", "-m", message)
-}
-
-// executeGitCommand is a helper to run git commands
-func executeGitCommand(path string, args ...string) ToolResult {
-	absPath, err := filepath.Abs(path)
-	if err != nil {
-		return ToolResult{Success: false, Error: fmt.Sprintf("invalid path: %v", err)}
-	}
-
-	cmd := exec.Command("git", args...)
-	cmd.Dir = absPath
-
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
-	err = cmd.Run()
-
-	output := stdout.String()
-	if stderr.Len() > 0 {
-		if output != "" {
-			output += "\n"
-		}
-		output += stderr.String()
+	if ctx.Err() != nil {
+		return ToolResult{Success: false, Output: output, Error: "command cancelled"}
 	}
 
 	if err != nil {
@@ -477,80 +356,15 @@ func buildTree(sb *strings.Builder, path, prefix string, depth, maxDepth int) er
 	return nil
 }
 
-// CodeSearch performs semantic code search
-func CodeSearch(root, pattern, language string) ToolResult {
-	absRoot, err := filepath.Abs(root)
-	if err != nil {
-		return ToolResult{Success: false, Error: fmt.Sprintf("invalid path: %v", err)}
-	}
-
-	// Determine file extensions based on language
-	extensions := getLanguageExtensions(language)
-
-	var results strings.Builder
-	matchCount := 0
-	maxMatches := 50
-
-	err = filepath.Walk(absRoot, func(path string, info os.FileInfo, err error) error {
-		if err != nil || info.IsDir() {
-			return nil
-		}
-
-		// Skip hidden and vendor directories
-		if strings.Contains(path, "/.") || strings.Contains(path, "/node_modules/") ||
-			strings.Contains(path, "/vendor/") || strings.Contains(path, "/__pycache__/") {
-			return nil
-		}
-
-		// Check file extension
-		if len(extensions) > 0 {
-			ext := filepath.Ext(path)
-			matched := false
-			for _, e := range extensions {
-				if ext == e {
-					matched = true
-					break
-				}
-			}
-			if !matched {
-				return nil
-			}
-		}
-
-		content, err := os.ReadFile(path)
-		if err != nil {
-			return nil
-		}
-
-		lines := strings.Split(string(content), "\n")
-		for i, line := range lines {
-			if matchCount >= maxMatches {
-				return filepath.SkipAll
-			}
-			if strings.Contains(strings.ToLower(line), strings.ToLower(pattern)) {
-				relPath, _ := filepath.Rel(absRoot, path)
-				results.WriteString(fmt.Sprintf("%s:%d: %s\n", relPath, i+1, strings.TrimSpace(line)))
-				matchCount++
-			}
-		}
-
-		return nil
+// CodeSearch performs a case-insensitive text search limited to a language
+func CodeSearch(ctx context.Context, root, pattern, language string) ToolResult {
+	return Grep(ctx, root, GrepOptions{
+		Pattern:    pattern,
+		Literal:    true,
+		IgnoreCase: true,
+		Extensions: getLanguageExtensions(language),
+		MaxResults: 50,
 	})
-
-	if err != nil && err != filepath.SkipAll {
-		return ToolResult{Success: false, Error: fmt.Sprintf("search failed: %v", err)}
-	}
-
-	output := results.String()
-	if output == "" {
-		return ToolResult{Success: true, Output: "No matches found"}
-	}
-
-	if matchCount >= maxMatches {
-		output += fmt.Sprintf("\n... (showing first %d matches)", maxMatches)
-	}
-
-	return ToolResult{Success: true, Output: output}
 }
 
 func getLanguageExtensions(language string) []string {
@@ -741,14 +555,20 @@ func GetSystemInfo() ToolResult {
 }
 
 // WebSearch simulates web search (using DuckDuckGo CLI or curl)
-func WebSearch(query string) ToolResult {
+func WebSearch(ctx context.Context, query string, timeout time.Duration) ToolResult {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
 	// Use DuckDuckGo instant answer API
-	cmd := exec.Command("curl", "-s", fmt.Sprintf("https://api.duckduckgo.com/?q=%s&format=json&no_html=1", strings.ReplaceAll(query, " ", "+")))
+	cmd := exec.CommandContext(ctx, "curl", "-s", fmt.Sprintf("https://api.duckduckgo.com/?q=%s&format=json&no_html=1", strings.ReplaceAll(query, " ", "+")))
 
 	var stdout bytes.Buffer
 	cmd.Stdout = &stdout
 
 	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return ToolResult{Success: false, Error: fmt.Sprintf("web search timed out after %s", timeout)}
+		}
 		return ToolResult{Success: false, Error: fmt.Sprintf("web search failed: %v", err)}
 	}
 
@@ -761,8 +581,8 @@ func WebSearch(query string) ToolResult {
 }
 
 // FetchURL fetches content from a URL and extracts readable text
-func FetchURL(url string) ToolResult {
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+func FetchURL(ctx context.Context, url string, timeout time.Duration) ToolResult {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
 	cmd := exec.CommandContext(ctx, "curl", "-sL", "-A", "Mozilla/5.0 (compatible; Zesbe-Go/1.0)", url)
@@ -772,6 +592,9 @@ func FetchURL(url string) ToolResult {
 	cmd.Stderr = &stderr
 
 	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return ToolResult{Success: false, Error: fmt.Sprintf("fetch timed out after %s", timeout)}
+		}
 		return ToolResult{Success: false, Error: fmt.Sprintf("fetch failed: %v", err), Output: stderr.String()}
 	}
 
@@ -780,14 +603,9 @@ func FetchURL(url string) ToolResult {
 		return ToolResult{Success: false, Error: "empty response from URL"}
 	}
 
-	// Extract text content from HTML
+	// Extract text content from HTML; size is capped by the fetch_url policy
 	text := extractTextFromHTML(rawHTML)
 
-	// Limit output size
-	if len(text) > 15000 {
-		text = text[:15000] + "\n\n... (content truncated)"
-	}
-
 	return ToolResult{Success: true, Output: text}
 }
 
@@ -915,44 +733,6 @@ func removeTagWithContent(html, tagName string) string {
 	return result
 }
 
-// GitPush pushes to remote
-func GitPush(path, remote, branch string) ToolResult {
-	if remote == "" {
-		remote = "origin"
-	}
-	if branch == "" {
-		branch = "main"
-	}
-	return executeGitCommand(path, "push", remote, branch)
-}
-
-// GitPull pulls from remote
-func GitPull(path, remote, branch string) ToolResult {
-	if remote == "" {
-		remote = "origin"
-	}
-	args := []string{"pull", remote}
-	if branch != "" {
-		args = append(args, branch)
-	}
-	return executeGitCommand(path, args...)
-}
-
-// GitClone clones a repository
-func GitClone(url, dest string) ToolResult {
-	cmd := exec.Command("git", "clone", url, dest)
-
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
-	if err := cmd.Run(); err != nil {
-		return ToolResult{Success: false, Output: stderr.String(), Error: err.Error()}
-	}
-
-	return ToolResult{Success: true, Output: fmt.Sprintf("Cloned %s to %s", url, dest)}
-}
-
 // AnalyzeCode provides basic code analysis
 func AnalyzeCode(path string) ToolResult {
 	absPath, err := filepath.Abs(path)
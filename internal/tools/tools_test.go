@@ -0,0 +1,60 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestExecuteCommandCancelled(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a POSIX shell")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start := time.Now()
+	result := ExecuteCommand(ctx, "sleep 10", time.Minute)
+	if result.Success {
+		t.Error("cancelled command reported success")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("took %s, want the command stopped when ctx is cancelled", elapsed)
+	}
+}
+
+func TestReadOnlyAccess(t *testing.T) {
+	if err := SetToolAccess("read_only"); err != nil {
+		t.Fatal(err)
+	}
+	defer SetToolAccess("")
+
+	tests := []struct {
+		name    string
+		allowed bool
+	}{
+		{"read_file", true},
+		{"grep_files", true},
+		{"get_cwd", true},
+		{"change_directory", false},
+		{"write_file", false},
+		{"run_command", false},
+	}
+	for _, tt := range tests {
+		tool, ok := DefaultRegistry.Get(tt.name)
+		if !ok {
+			t.Fatalf("%s not registered", tt.name)
+		}
+		if got := allowed(tool); got != tt.allowed {
+			t.Errorf("%s allowed = %v, want %v", tt.name, got, tt.allowed)
+		}
+	}
+
+	before, _ := os.Getwd()
+	result := ExecuteToolContext(context.Background(), ToolCall{Name: "change_directory", Params: map[string]string{"path": t.TempDir()}})
+	if after, _ := os.Getwd(); result.Success || after != before {
+		t.Errorf("change_directory ran in a read-only session: %+v", result)
+	}
+}
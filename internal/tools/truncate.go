@@ -0,0 +1,149 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxDisplayBytes is the default size of a tool result shown in the chat
+const MaxDisplayBytes = 800
+
+// Truncation strategies for tool output
+const (
+	TruncateHead     = "head"      // Keep the beginning
+	TruncateTail     = "tail"      // Keep the end
+	TruncateHeadTail = "head_tail" // Keep both ends, drop the middle
+)
+
+// TruncateOutput limits s to maxBytes using the named strategy
+func TruncateOutput(s string, maxBytes int, strategy string) string {
+	if maxBytes <= 0 || len(s) <= maxBytes {
+		return s
+	}
+	switch strategy {
+	case TruncateTail:
+		return TruncateLinesTail(s, maxBytes)
+	case TruncateHeadTail:
+		return TruncateLinesHeadTail(s, maxBytes)
+	default:
+		return TruncateLines(s, maxBytes)
+	}
+}
+
+// TruncateRunes cuts s to at most maxBytes without splitting a UTF-8 rune
+func TruncateRunes(s string, maxBytes int) string {
+	if maxBytes <= 0 {
+		return ""
+	}
+	if len(s) <= maxBytes {
+		return s
+	}
+	cut := maxBytes
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut]
+}
+
+// TruncateLines keeps whole lines of s up to maxBytes and appends a note
+// saying how many lines were dropped. A single line longer than maxBytes is
+// cut at a rune boundary.
+func TruncateLines(s string, maxBytes int) string {
+	if len(s) <= maxBytes {
+		return s
+	}
+
+	lines := strings.SplitAfter(s, "\n")
+	var sb strings.Builder
+	kept := 0
+	for _, line := range lines {
+		if sb.Len()+len(line) > maxBytes {
+			break
+		}
+		sb.WriteString(line)
+		kept++
+	}
+
+	if kept == 0 {
+		sb.WriteString(TruncateRunes(lines[0], maxBytes))
+		kept = 1
+	}
+
+	out := strings.TrimRight(sb.String(), "\n")
+	if dropped := countLines(s) - kept; dropped > 0 {
+		return out + fmt.Sprintf("\n... (%d more lines truncated)", dropped)
+	}
+	return out + "\n... (truncated)"
+}
+
+// TruncateLinesTail keeps the last whole lines of s up to maxBytes
+func TruncateLinesTail(s string, maxBytes int) string {
+	if len(s) <= maxBytes {
+		return s
+	}
+
+	lines := strings.SplitAfter(strings.TrimRight(s, "\n"), "\n")
+	size, first := 0, len(lines)
+	for i := len(lines) - 1; i >= 0; i-- {
+		if size+len(lines[i]) > maxBytes {
+			break
+		}
+		size += len(lines[i])
+		first = i
+	}
+
+	if first == len(lines) {
+		// The last line alone is too long: keep its end
+		last := lines[len(lines)-1]
+		cut := len(last) - maxBytes
+		for cut < len(last) && !utf8.RuneStart(last[cut]) {
+			cut++
+		}
+		return fmt.Sprintf("... (%d earlier lines truncated)\n%s", len(lines)-1, last[cut:])
+	}
+
+	return fmt.Sprintf("... (%d earlier lines truncated)\n%s", first, strings.Join(lines[first:], ""))
+}
+
+// TruncateLinesHeadTail keeps whole lines from both ends of s, splitting
+// maxBytes between them, and notes how many lines were dropped in between
+func TruncateLinesHeadTail(s string, maxBytes int) string {
+	if len(s) <= maxBytes {
+		return s
+	}
+
+	lines := strings.SplitAfter(strings.TrimRight(s, "\n"), "\n")
+	budget := maxBytes / 2
+
+	head, size := 0, 0
+	for head < len(lines) && size+len(lines[head]) <= budget {
+		size += len(lines[head])
+		head++
+	}
+
+	tail, size := len(lines), 0
+	for tail > head && size+len(lines[tail-1]) <= budget {
+		size += len(lines[tail-1])
+		tail--
+	}
+
+	if head == 0 && tail == len(lines) {
+		return TruncateLines(s, maxBytes)
+	}
+
+	return fmt.Sprintf("%s... (%d lines omitted)\n%s",
+		strings.Join(lines[:head], ""), tail-head, strings.Join(lines[tail:], ""))
+}
+
+// countLines returns the number of lines in s, ignoring a trailing newline
+func countLines(s string) int {
+	if s == "" {
+		return 0
+	}
+	n := strings.Count(s, "\n")
+	if !strings.HasSuffix(s, "\n") {
+		n++
+	}
+	return n
+}
@@ -0,0 +1,105 @@
+package tools
+
+import (
+	"bytes"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// maxScanSize is the largest file the search tools will open
+const maxScanSize = 10 * 1024 * 1024
+
+// alwaysSkipDirs are never descended into, regardless of .gitignore
+var alwaysSkipDirs = map[string]bool{
+	".git":         true,
+	".hg":          true,
+	".svn":         true,
+	"node_modules": true,
+	"vendor":       true,
+	"__pycache__":  true,
+}
+
+// sortedSkipDirs returns alwaysSkipDirs in a fixed order
+func sortedSkipDirs() []string {
+	dirs := make([]string, 0, len(alwaysSkipDirs))
+	for dir := range alwaysSkipDirs {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	return dirs
+}
+
+// WalkOptions controls which entries walkFiles reports
+type WalkOptions struct {
+	Hidden    bool // Include dotfiles and dot-directories
+	NoIgnore  bool // Don't honour .gitignore
+	SkipLarge bool // Skip files larger than maxScanSize
+}
+
+// walkFiles calls fn for every regular file under root that survives the
+// ignore rules. It is the shared walker behind the search tools.
+func walkFiles(root string, opts WalkOptions, fn func(path string, info fs.FileInfo) error) error {
+	var ignore *ignoreMatcher
+	if !opts.NoIgnore {
+		ignore = newIgnoreMatcher(root)
+	}
+
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if d != nil && d.IsDir() && path != root {
+				return filepath.SkipDir
+			}
+			return nil // Skip unreadable entries
+		}
+
+		name := d.Name()
+		if path != root {
+			if d.IsDir() && alwaysSkipDirs[name] {
+				return filepath.SkipDir
+			}
+			if !opts.Hidden && strings.HasPrefix(name, ".") {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if ignore != nil && ignore.Ignored(path, d.IsDir()) {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+		}
+
+		if d.IsDir() {
+			if ignore != nil {
+				ignore.loadDir(path)
+			}
+			return nil
+		}
+
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		if opts.SkipLarge && info.Size() > maxScanSize {
+			return nil
+		}
+
+		return fn(path, info)
+	})
+}
+
+// looksBinary reports whether the head of data contains a NUL byte
+func looksBinary(data []byte) bool {
+	if len(data) > sniffSize {
+		data = data[:sniffSize]
+	}
+	return bytes.IndexByte(data, 0) >= 0
+}
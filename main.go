@@ -1,14 +1,27 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"flag"
 	"fmt"
 	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
 
+	"github.com/zesbe/zesbe-go/internal/ai"
 	"github.com/zesbe/zesbe-go/internal/app"
 	"github.com/zesbe/zesbe-go/internal/config"
+	"github.com/zesbe/zesbe-go/internal/credentials"
+	"github.com/zesbe/zesbe-go/internal/hooks"
 	"github.com/zesbe/zesbe-go/internal/logger"
+	"github.com/zesbe/zesbe-go/internal/review"
+	"github.com/zesbe/zesbe-go/internal/tools"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"golang.org/x/term"
 )
 
 // Version information
@@ -17,12 +30,120 @@ var (
 	BuildTime = "unknown"
 )
 
+// usage introduces the commands; the flag list follows it
+const usage = `Usage: zesbe-go [flags] [command]
+
+Without a command, zesbe-go starts the interactive chat.
+
+Commands:
+  sessions list|show|export|delete  Manage saved chat sessions
+  config get|set|validate|schema    Read, change or check settings
+  auth login|logout|list            Store API keys in the keyring
+  providers                         List providers and their API key status
+  doctor                            Check the installation and configuration
+  review [range]                    Review a diff and print the findings
+  mcp serve                         Serve the built-in tools over MCP stdio
+
+Flags:
+`
+
+// logLevels are the values --log-level accepts
+var logLevels = []string{"debug", "info", "warn", "error"}
+
+// options are the global flags, given before the command
+type options struct {
+	provider   string
+	model      string
+	cwd        string
+	logLevel   string
+	configPath string
+	version    bool
+	noTools    bool
+	readOnly   bool
+}
+
+// parseFlags reads the global flags and returns the command line after them
+func parseFlags(args []string) (options, []string, error) {
+	var o options
+	fs := flag.NewFlagSet("zesbe-go", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&o.provider, "provider", "", "AI provider to use, e.g. openai")
+	fs.StringVar(&o.model, "model", "", "model to use instead of the provider's default")
+	fs.StringVar(&o.cwd, "cwd", "", "directory to work in")
+	fs.StringVar(&o.logLevel, "log-level", "info", "log level: "+strings.Join(logLevels, ", "))
+	fs.StringVar(&o.configPath, "config", "", "user config file to use instead of ~/.zesbe-go/config.json")
+	fs.BoolVar(&o.version, "version", false, "print the version and exit")
+	fs.BoolVar(&o.noTools, "no-tools", false, "don't let the model use any tools")
+	fs.BoolVar(&o.readOnly, "read-only", false, "only let the model use tools that change nothing")
+	if err := fs.Parse(args); err != nil {
+		return o, nil, err
+	}
+
+	valid := false
+	for _, l := range logLevels {
+		valid = valid || o.logLevel == l
+	}
+	if !valid {
+		fmt.Fprintf(os.Stderr, "invalid value %q for flag -log-level: want one of %s\n", o.logLevel, strings.Join(logLevels, ", "))
+		fs.Usage()
+		return o, nil, fmt.Errorf("invalid log level %q", o.logLevel)
+	}
+	return o, fs.Args(), nil
+}
+
+// overrides turns the flags that change settings into the config's flag
+// layer
+func (o options) overrides() []config.Override {
+	var list []config.Override
+	if o.provider != "" {
+		list = append(list, config.Override{Flag: "--provider", Key: "provider", Value: o.provider})
+	}
+	if o.model != "" {
+		list = append(list, config.Override{Flag: "--model", Key: "model", Value: o.model})
+	}
+	switch {
+	case o.noTools:
+		list = append(list, config.Override{Flag: "--no-tools", Key: "tool_access", Value: config.ToolAccessNone})
+	case o.readOnly:
+		list = append(list, config.Override{Flag: "--read-only", Key: "tool_access", Value: config.ToolAccessReadOnly})
+	}
+	return list
+}
+
 func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+// run parses the command line, runs the command and returns the exit status
+func run(args []string) int {
+	opts, args, err := parseFlags(args)
+	if err == flag.ErrHelp {
+		return 0
+	} else if err != nil {
+		return 2
+	}
+	if opts.version {
+		fmt.Printf("zesbe-go %s (built %s)\n", Version, BuildTime)
+		return 0
+	}
+	if opts.cwd != "" {
+		if err := os.Chdir(opts.cwd); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+	}
+	if opts.configPath != "" {
+		config.SetConfigPath(opts.configPath)
+	}
+
 	// Initialize logger
 	logCfg := logger.DefaultConfig()
-	logCfg.Level = "info"
+	logCfg.Level = opts.logLevel
 	if err := logger.Init(logCfg); err != nil {
-		fmt.Printf("Warning: Failed to initialize logger: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Warning: Failed to initialize logger: %v\n", err)
 	}
 	defer logger.Close()
 
@@ -30,17 +151,145 @@ func main() {
 	logger.Infof("Version: %s", Version)
 
 	// Load configuration
-	cfg := config.Load()
+	cfg := config.LoadWithFlags(opts.overrides())
+	for _, w := range cfg.Problems() {
+		logger.Warnf("Config: %s", w)
+	}
+
+	command := ""
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
+	switch command {
+	case "":
+		cfg = askProjectTrust(cfg, opts)
+		if !checkConfig(cfg) {
+			return 1
+		}
+		// Only commands that call a provider or manage keys may ask for
+		// the credentials file's passphrase
+		credentials.SetPrompt(promptPassphrase)
+		cfg.LoadAPIKey()
+		if !checkProvider(cfg) {
+			return 1
+		}
+		return runChat(cfg)
+	case "review":
+		if !checkConfig(cfg) {
+			return 1
+		}
+		credentials.SetPrompt(promptPassphrase)
+		cfg.LoadAPIKey()
+		if !checkProvider(cfg) {
+			return 1
+		}
+		return runReview(cfg, args)
+	case "mcp":
+		if !checkConfig(cfg) {
+			return 1
+		}
+		return runMCP(cfg, args)
+	case "sessions":
+		return runSessions(args)
+	case "config":
+		return runConfig(cfg, args)
+	case "auth":
+		// auth manages the credentials file, so it may ask for the passphrase
+		credentials.SetPrompt(promptPassphrase)
+		return runAuth(cfg, args)
+	case "providers":
+		return runProviders(cfg)
+	case "doctor":
+		return runDoctor(cfg, args)
+	case "help":
+		parseFlags([]string{"-help"}) // Prints the usage
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command: %s\nRun 'zesbe-go -help' for usage.\n", command)
+		return 2
+	}
+}
+
+// checkConfig refuses to go on with config files that are broken or break
+// the schema, rather than quietly running without the settings they hold
+func checkConfig(cfg *config.Config) bool {
+	errs := cfg.Errors()
+	if len(errs) == 0 {
+		return true
+	}
+	fmt.Fprintln(os.Stderr, "Error: invalid configuration")
+	for _, e := range errs {
+		fmt.Fprintf(os.Stderr, "  %s\n", e)
+	}
+	fmt.Fprintln(os.Stderr, "\nFix these, then run 'zesbe-go config validate' to check again.")
+	return false
+}
+
+// askProjectTrust asks, once per project, whether the project config may
+// run commands, and reloads the config when the user agrees. Without a
+// terminal to ask on, the project stays untrusted.
+func askProjectTrust(cfg *config.Config, opts options) *config.Config {
+	if len(cfg.Untrusted) == 0 || !term.IsTerminal(int(os.Stdin.Fd())) {
+		return cfg
+	}
+	if _, decided := cfg.ProjectTrust(); decided {
+		return cfg
+	}
+
+	root := config.ProjectRoot(cfg.ProjectFile)
+	fmt.Fprintf(os.Stderr, "%s sets %s, which run commands or change the git guards.\n",
+		cfg.ProjectFile, strings.Join(cfg.Untrusted, ", "))
+	fmt.Fprintf(os.Stderr, "Trust %s? [y/N] ", root)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	trusted := answer == "y" || answer == "yes"
+
+	if err := config.SetProjectTrust(root, trusted); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	if !trusted {
+		fmt.Fprintln(os.Stderr, "Leaving those settings off; run 'zesbe-go config trust' to change your mind.")
+		return cfg
+	}
+	reloaded := config.LoadWithFlags(opts.overrides())
+	for _, w := range reloaded.Problems() {
+		logger.Warnf("Config: %s", w)
+	}
+	return reloaded
+}
+
+// checkProvider makes sure the provider is known and has an API key
+func checkProvider(cfg *config.Config) bool {
+	if _, ok := cfg.Providers[cfg.Provider]; !ok {
+		providers := cfg.ListProviders()
+		sort.Strings(providers)
+		fmt.Printf("Error: Unknown provider '%s'\n", cfg.Provider)
+		fmt.Printf("\nSupported providers: %s\n", strings.Join(providers, ", "))
+		return false
+	}
 
 	// Validate API key
 	if cfg.APIKey == "" {
 		fmt.Printf("Error: No API key found for provider '%s'\n", cfg.Provider)
 		fmt.Println("\nPlease set your API key using one of these methods:")
-		fmt.Printf("  1. Environment variable: export %s_API_KEY=your-key\n", cfg.Provider)
-		fmt.Printf("  2. Key file: echo 'your-key' > ~/.%s_api_key\n", cfg.Provider)
+		fmt.Printf("  1. Credential store: zesbe-go auth login %s\n", cfg.Provider)
+		fmt.Printf("  2. Environment variable: export %s=your-key\n", config.GetAPIKeyEnvVar(cfg.Provider))
 		fmt.Println("\nSupported providers: minimax, openai, anthropic, google, groq, deepseek, openrouter, ollama")
-		os.Exit(1)
+		return false
 	}
+	return true
+}
+
+// runChat runs the interactive TUI
+func runChat(cfg *config.Config) int {
+	// The TUI can't prompt, so unlock the credentials file now for
+	// /provider to use
+	if store, ok := config.Credentials().(*credentials.FileStore); ok && store.Exists() {
+		if err := store.Unlock(); err != nil {
+			logger.Warnf("Credentials: %v", err)
+		}
+	}
+	credentials.SetPrompt(nil)
 
 	// Create the app model
 	model := app.New(cfg)
@@ -58,8 +307,128 @@ func main() {
 	if _, err := p.Run(); err != nil {
 		logger.Fatal("Error running app", err)
 		fmt.Printf("Error running app: %v\n", err)
-		os.Exit(1)
+		return 1
 	}
 
 	logger.Info("Zesbe Go exited normally")
+	return 0
+}
+
+// runMCP handles `zesbe-go mcp serve`, which exposes the built-in tools to
+// other MCP clients over stdio, rooted at the current directory
+func runMCP(cfg *config.Config, args []string) int {
+	if len(args) == 0 || args[0] != "serve" {
+		fmt.Fprintln(os.Stderr, "Usage: zesbe-go mcp serve")
+		return 2
+	}
+
+	// Same tool settings as the chat, minus the MCP client side
+	if err := tools.Configure(cfg); err != nil {
+		logger.Warnf("Invalid tool settings: %v", err)
+	}
+	defer tools.Shutdown()
+
+	// The same hooks as the chat, so no client can get around them
+	runner, err := hooks.New(cfg.AllHooks())
+	if err != nil {
+		logger.Warnf("Invalid hooks: %v", err)
+	}
+	execute := func(ctx context.Context, call tools.ToolCall) tools.ToolResult {
+		_, result := runner.ExecuteTool(ctx, call)
+		return result
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	logger.Info("Serving tools over MCP stdio")
+	if err := tools.NewMCPServer(Version, execute).ServeStdio(ctx, os.Stdin, os.Stdout); err != nil {
+		logger.Error("MCP server stopped", err)
+		return 1
+	}
+	return 0
+}
+
+// runReview handles `zesbe-go review [range]`, which reviews a diff without
+// the TUI and prints or saves the findings for CI
+func runReview(cfg *config.Config, args []string) int {
+	fs := flag.NewFlagSet("review", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: zesbe-go review [range] [--format text|json|sarif] [--output file] [--fail-on error|warning|info]")
+		fs.PrintDefaults()
+	}
+	format := fs.String("format", review.FormatText, "output format: text, json or sarif")
+	output := fs.String("output", "", "write the report to this file instead of stdout")
+	failOn := fs.String("fail-on", "", "exit with status 1 when a finding is at least this severe")
+
+	// Allow the range before or after the flags
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 2
+	}
+	if len(positional) > 1 {
+		fs.Usage()
+		return 2
+	}
+
+	// Check the flags before spending any API calls
+	if err := review.CheckFormat(*format); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	threshold := ""
+	if *failOn != "" {
+		if threshold, err = review.ParseSeverity(*failOn); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: --fail-on: %v\n", err)
+			return 2
+		}
+	}
+
+	opts := review.Options{}
+	if len(positional) == 1 {
+		opts.Range = positional[0]
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	report, err := review.Run(ctx, ai.NewClient(cfg), opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	data, err := report.Export(*format, Version)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	if *output == "" {
+		os.Stdout.Write(data)
+		if len(data) > 0 && data[len(data)-1] != '\n' {
+			fmt.Println()
+		}
+	} else {
+		if err := os.WriteFile(*output, data, 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		for _, e := range report.Errors {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", e)
+		}
+		fmt.Fprintln(os.Stderr, report.Summary())
+	}
+
+	if threshold != "" {
+		// Part of the diff went unreviewed, so the gate can't pass
+		if len(report.Errors) > 0 {
+			return 1
+		}
+		for _, f := range report.Findings {
+			if review.AtLeast(f.Severity, threshold) {
+				return 1
+			}
+		}
+	}
+	return 0
 }